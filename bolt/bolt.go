@@ -0,0 +1,533 @@
+//Package bolt is where we place all funcs related to the embedded bolt storage operations
+package bolt
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Bolt main struct. A bolt transaction can not be interrupted, so the context of each operation is only checked before the transaction starts.
+type Bolt struct {
+	DB *bbolt.DB
+}
+
+//setsBucket is the root bucket name where every set is stored as a nested bucket (one nested bucket for each key)
+var setsBucket = []byte("sets")
+
+//zsetsBucket is the root bucket name where every sorted set is stored as a nested bucket (one nested bucket for each key).
+//The nested bucket of a sorted set has a members bucket (member to score) and a scores bucket (score followed by member, ordered by score)
+var zsetsBucket = []byte("zsets")
+
+var (
+	zsetMembersBucket = []byte("members")
+	zsetScoresBucket  = []byte("scores")
+)
+
+//stringsBucket is the root bucket name where every string value is stored (key to value)
+var stringsBucket = []byte("strings")
+
+//KeyTypeSet is the key type returned by Type for a set key (same as redis TYPE reply)
+const KeyTypeSet string = "set"
+
+//KeyTypeZSet is the key type returned by Type for a sorted set key (same as redis TYPE reply)
+const KeyTypeZSet string = "zset"
+
+//KeyTypeString is the key type returned by Type for a string key (same as redis TYPE reply)
+const KeyTypeString string = "string"
+
+//KeyTypeNone is the key type returned by Type for a non existing key (same as redis TYPE reply)
+const KeyTypeNone string = "none"
+
+//InitBolt is a func to open (or create) the embedded bolt database that we are going to use
+func InitBolt(storageConfig config.StorageConfigWrap) (*Bolt, error) {
+	path := strings.Trim(storageConfig.BoltElasthink.Path, " ")
+	if len(path) == 0 {
+		return nil, errors.New("Bolt database path must be defined!")
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Duration(storageConfig.BoltElasthink.Timeout) * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(setsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(zsetsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(stringsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Bolt{DB: db}, nil
+}
+
+// SAdd add an item into a set
+func (b *Bolt) SAdd(ctx context.Context, key string, args []interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var added int64
+	err := b.DB.Update(func(tx *bbolt.Tx) error {
+		set, err := tx.Bucket(setsBucket).CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+		for _, arg := range args {
+			member := []byte(fmt.Sprint(arg))
+			if set.Get(member) != nil {
+				continue
+			}
+			if err := set.Put(member, []byte{}); err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// SMembers get members of a set
+func (b *Bolt) SMembers(ctx context.Context, key string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	err := b.DB.View(func(tx *bbolt.Tx) error {
+		set := tx.Bucket(setsBucket).Bucket([]byte(key))
+		if set == nil {
+			return nil
+		}
+		return set.ForEach(func(k, v []byte) error {
+			result = append(result, string(k))
+			return nil
+		})
+	})
+	return result, err
+}
+
+// SRem remove an item from a set
+func (b *Bolt) SRem(ctx context.Context, key string, members []interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err := b.DB.Update(func(tx *bbolt.Tx) error {
+		sets := tx.Bucket(setsBucket)
+		set := sets.Bucket([]byte(key))
+		if set == nil {
+			return nil
+		}
+		for _, m := range members {
+			member := []byte(fmt.Sprint(m))
+			if set.Get(member) == nil {
+				continue
+			}
+			if err := set.Delete(member); err != nil {
+				return err
+			}
+			removed++
+		}
+		// an empty set does not exist, same as in redis
+		if k, _ := set.Cursor().First(); k == nil {
+			return sets.DeleteBucket([]byte(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// ZAdd adds a member with its score into a sorted set (the score of an existing member is updated)
+func (b *Bolt) ZAdd(ctx context.Context, key string, score float64, member string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var added int64
+	err := b.DB.Update(func(tx *bbolt.Tx) error {
+		zset, err := tx.Bucket(zsetsBucket).CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+		members, err := zset.CreateBucketIfNotExists(zsetMembersBucket)
+		if err != nil {
+			return err
+		}
+		scores, err := zset.CreateBucketIfNotExists(zsetScoresBucket)
+		if err != nil {
+			return err
+		}
+
+		if oldScore := members.Get([]byte(member)); oldScore != nil {
+			if err := scores.Delete(scoreMemberKey(oldScore, member)); err != nil {
+				return err
+			}
+		} else {
+			added++
+		}
+
+		encodedScore := encodeScore(score)
+		if err := members.Put([]byte(member), encodedScore); err != nil {
+			return err
+		}
+		return scores.Put(scoreMemberKey(encodedScore, member), []byte{})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// ZRem removes members from a sorted set
+func (b *Bolt) ZRem(ctx context.Context, key string, members []interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err := b.DB.Update(func(tx *bbolt.Tx) error {
+		zsets := tx.Bucket(zsetsBucket)
+		zset := zsets.Bucket([]byte(key))
+		if zset == nil {
+			return nil
+		}
+		zsetMembers := zset.Bucket(zsetMembersBucket)
+		zsetScores := zset.Bucket(zsetScoresBucket)
+		for _, m := range members {
+			member := fmt.Sprint(m)
+			encodedScore := zsetMembers.Get([]byte(member))
+			if encodedScore == nil {
+				continue
+			}
+			if err := zsetScores.Delete(scoreMemberKey(encodedScore, member)); err != nil {
+				return err
+			}
+			if err := zsetMembers.Delete([]byte(member)); err != nil {
+				return err
+			}
+			removed++
+		}
+		// an empty sorted set does not exist, same as in redis
+		if k, _ := zsetMembers.Cursor().First(); k == nil {
+			return zsets.DeleteBucket([]byte(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// ZRangeByScore gets the members of a sorted set with a score between min and max (inclusive, or exclusive when prefixed by "(", and "-inf" or "+inf" for no bound)
+func (b *Bolt) ZRangeByScore(ctx context.Context, key, min, max string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	minScore, isMinExclusive, err := parseScoreBound(min)
+	if err != nil {
+		return nil, err
+	}
+	maxScore, isMaxExclusive, err := parseScoreBound(max)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	err = b.DB.View(func(tx *bbolt.Tx) error {
+		zset := tx.Bucket(zsetsBucket).Bucket([]byte(key))
+		if zset == nil {
+			return nil
+		}
+		c := zset.Bucket(zsetScoresBucket).Cursor()
+		for k, _ := c.Seek(encodeScore(minScore)); k != nil; k, _ = c.Next() {
+			score := decodeScore(k[:8])
+			if score > maxScore || (isMaxExclusive && score == maxScore) {
+				break
+			}
+			if isMinExclusive && score == minScore {
+				continue
+			}
+			result = append(result, string(k[8:]))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ZMembersWithScores gets every member of a sorted set with its score
+func (b *Bolt) ZMembersWithScores(ctx context.Context, key string) (map[string]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64)
+	err := b.DB.View(func(tx *bbolt.Tx) error {
+		zset := tx.Bucket(zsetsBucket).Bucket([]byte(key))
+		if zset == nil {
+			return nil
+		}
+		return zset.Bucket(zsetMembersBucket).ForEach(func(k, v []byte) error {
+			result[string(k)] = decodeScore(v)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// ZScores gets the scores of the given members that are in a sorted set
+func (b *Bolt) ZScores(ctx context.Context, key string, members []string) (map[string]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64)
+	err := b.DB.View(func(tx *bbolt.Tx) error {
+		zset := tx.Bucket(zsetsBucket).Bucket([]byte(key))
+		if zset == nil {
+			return nil
+		}
+		zsetMembers := zset.Bucket(zsetMembersBucket)
+		for _, member := range members {
+			if v := zsetMembers.Get([]byte(member)); v != nil {
+				result[member] = decodeScore(v)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Set sets the string value of a key
+func (b *Bolt) Set(ctx context.Context, key, value string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.DB.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(stringsBucket).Put([]byte(key), []byte(value))
+	})
+}
+
+// Get gets the string value of a key, the value is empty when the key does not exist
+func (b *Bolt) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	value := ""
+	err := b.DB.View(func(tx *bbolt.Tx) error {
+		value = string(tx.Bucket(stringsBucket).Get([]byte(key)))
+		return nil
+	})
+	return value, err
+}
+
+// MGet gets the string values of several keys in one read transaction, in the order of the keys. A value is empty when its key does not exist
+func (b *Bolt) MGet(ctx context.Context, keys []string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(keys))
+	err := b.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(stringsBucket)
+		for i, key := range keys {
+			result[i] = string(bucket.Get([]byte(key)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Del removes a key (of any type)
+func (b *Bolt) Del(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err := b.DB.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{setsBucket, zsetsBucket} {
+			if tx.Bucket(bucket).Bucket([]byte(key)) != nil {
+				removed = 1
+				return tx.Bucket(bucket).DeleteBucket([]byte(key))
+			}
+		}
+		if tx.Bucket(stringsBucket).Get([]byte(key)) != nil {
+			removed = 1
+			return tx.Bucket(stringsBucket).Delete([]byte(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+//encodeScore encodes a score into 8 bytes whose byte order is the order of the scores
+func encodeScore(score float64) []byte {
+	bits := math.Float64bits(score)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	encoded := make([]byte, 8)
+	binary.BigEndian.PutUint64(encoded, bits)
+	return encoded
+}
+
+//decodeScore decodes a score encoded by encodeScore
+func decodeScore(encoded []byte) float64 {
+	bits := binary.BigEndian.Uint64(encoded)
+	if bits&(1<<63) != 0 {
+		bits &^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return math.Float64frombits(bits)
+}
+
+//scoreMemberKey is the key of a member in the scores bucket of a sorted set. Format --> encoded score followed by member
+func scoreMemberKey(encodedScore []byte, member string) []byte {
+	return bytes.Join([][]byte{encodedScore, []byte(member)}, nil)
+}
+
+//parseScoreBound parses a score bound of ZRangeByScore (same as redis ZRANGEBYSCORE bounds)
+func parseScoreBound(bound string) (float64, bool, error) {
+	bound = strings.Trim(bound, " ")
+	isExclusive := strings.HasPrefix(bound, "(")
+	score, err := strconv.ParseFloat(strings.TrimPrefix(bound, "("), 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false, fmt.Errorf("Invalid score bound %s", bound)
+	}
+	return score, isExclusive, nil
+}
+
+// KeysPrefix get keys by a defined prefix
+func (b *Bolt) KeysPrefix(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return make([]string, 0), err
+	}
+
+	prefix = strings.Trim(prefix, " ")
+	if len(prefix) == 0 {
+		return make([]string, 0), errors.New("Prefix must be defined!")
+	}
+
+	result := make([]string, 0)
+	err := b.DB.View(func(tx *bbolt.Tx) error {
+		rawPrefix := []byte(prefix)
+		for _, bucket := range [][]byte{setsBucket, zsetsBucket, stringsBucket} {
+			c := tx.Bucket(bucket).Cursor()
+			for k, _ := c.Seek(rawPrefix); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+				result = append(result, string(k))
+			}
+		}
+		return nil
+	})
+	sort.Strings(result)
+	return result, err
+}
+
+// Type get the type of the value stored in a key
+func (b *Bolt) Type(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	keyType := KeyTypeNone
+	err := b.DB.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket(setsBucket).Bucket([]byte(key)) != nil {
+			keyType = KeyTypeSet
+		} else if tx.Bucket(zsetsBucket).Bucket([]byte(key)) != nil {
+			keyType = KeyTypeZSet
+		} else if tx.Bucket(stringsBucket).Get([]byte(key)) != nil {
+			keyType = KeyTypeString
+		}
+		return nil
+	})
+	return keyType, err
+}
+
+// Ping checks that the bolt database file is open and readable
+func (b *Bolt) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.DB.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket(setsBucket) == nil {
+			return errors.New("Sets bucket does not exist")
+		}
+		if tx.Bucket(zsetsBucket) == nil {
+			return errors.New("Sorted sets bucket does not exist")
+		}
+		if tx.Bucket(stringsBucket) == nil {
+			return errors.New("Strings bucket does not exist")
+		}
+		return nil
+	})
+}
+
+// Close closes the bolt database file
+func (b *Bolt) Close() error {
+	return b.DB.Close()
+}
@@ -0,0 +1,291 @@
+package bolt
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func initTestBolt(t *testing.T) *Bolt {
+	storageConfig := config.StorageConfigWrap{
+		Storage: config.StorageConfig{Backend: "bolt"},
+		BoltElasthink: config.BoltConfig{
+			Path:    filepath.Join(t.TempDir(), "data", "elasthink.db"),
+			Timeout: 1,
+		},
+	}
+	boltObject, err := InitBolt(storageConfig)
+	if err != nil {
+		t.Fatal("Expected : ok, but found error! err:", err.Error())
+	}
+	t.Cleanup(func() {
+		boltObject.Close()
+	})
+	return boltObject
+}
+
+func TestInitBolt(t *testing.T) {
+	boltObject := initTestBolt(t)
+	assert.NotNil(t, boltObject.DB)
+
+	_, err := InitBolt(config.StorageConfigWrap{})
+	assert.Equal(t, errors.New("Bolt database path must be defined!"), err)
+}
+
+func TestSAdd(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	added, err := boltObject.SAdd(context.Background(), "campaign:ganteng", []interface{}{666, "777"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), added)
+
+	added, err = boltObject.SAdd(context.Background(), "campaign:ganteng", []interface{}{"666", 888})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), added)
+}
+
+func TestSMembers(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	members, err := boltObject.SMembers(context.Background(), "campaign:bangun")
+	assert.Nil(t, err)
+	assert.Equal(t, make([]string, 0), members)
+
+	boltObject.SAdd(context.Background(), "campaign:bangun", []interface{}{"123", "234", "345", "456"})
+	members, err = boltObject.SMembers(context.Background(), "campaign:bangun")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"123", "234", "345", "456"}, members)
+}
+
+func TestSRem(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	boltObject.SAdd(context.Background(), "campaign:ganteng", []interface{}{666, 777})
+	removed, err := boltObject.SRem(context.Background(), "campaign:ganteng", []interface{}{666, 999})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	keyType, _ := boltObject.Type(context.Background(), "campaign:ganteng")
+	assert.Equal(t, KeyTypeSet, keyType)
+
+	//removing the last member removes the key
+	removed, err = boltObject.SRem(context.Background(), "campaign:ganteng", []interface{}{777})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	keyType, _ = boltObject.Type(context.Background(), "campaign:ganteng")
+	assert.Equal(t, KeyTypeNone, keyType)
+}
+
+func TestZAdd(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	added, err := boltObject.ZAdd(context.Background(), "range:campaign:price", 15000, "666")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), added)
+
+	//an existing member only gets its score updated
+	added, err = boltObject.ZAdd(context.Background(), "range:campaign:price", 20000, "666")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), added)
+
+	members, err := boltObject.ZMembersWithScores(context.Background(), "range:campaign:price")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{"666": 20000}, members)
+
+	//a member that is not in the sorted set has no score
+	scores, err := boltObject.ZScores(context.Background(), "range:campaign:price", []string{"666", "777"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{"666": 20000}, scores)
+	scores, err = boltObject.ZScores(context.Background(), "range:campaign:discount", []string{"666"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(scores))
+
+	keyType, _ := boltObject.Type(context.Background(), "range:campaign:price")
+	assert.Equal(t, KeyTypeZSet, keyType)
+}
+
+func TestZRem(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	boltObject.ZAdd(context.Background(), "range:campaign:price", 15000, "666")
+	boltObject.ZAdd(context.Background(), "range:campaign:price", 20000, "777")
+
+	removed, err := boltObject.ZRem(context.Background(), "range:campaign:price", []interface{}{666, "888"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	members, _ := boltObject.ZRangeByScore(context.Background(), "range:campaign:price", "-inf", "+inf")
+	assert.Equal(t, []string{"777"}, members)
+
+	//removing the last member removes the key
+	removed, err = boltObject.ZRem(context.Background(), "range:campaign:price", []interface{}{"777"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	keyType, _ := boltObject.Type(context.Background(), "range:campaign:price")
+	assert.Equal(t, KeyTypeNone, keyType)
+}
+
+func TestZRangeByScore(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	boltObject.ZAdd(context.Background(), "range:campaign:price", -500, "111")
+	boltObject.ZAdd(context.Background(), "range:campaign:price", 0, "222")
+	boltObject.ZAdd(context.Background(), "range:campaign:price", 15000, "333")
+	boltObject.ZAdd(context.Background(), "range:campaign:price", 15000, "444")
+	boltObject.ZAdd(context.Background(), "range:campaign:price", 20000.5, "555")
+
+	type tcase struct {
+		min            string
+		max            string
+		expectedResult []string
+		expectedError  error
+	}
+
+	testCases := make(map[string]tcase)
+
+	testCases["all members"] = tcase{
+		min:            "-inf",
+		max:            "+inf",
+		expectedResult: []string{"111", "222", "333", "444", "555"},
+	}
+
+	testCases["inclusive bounds"] = tcase{
+		min:            "0",
+		max:            "15000",
+		expectedResult: []string{"222", "333", "444"},
+	}
+
+	testCases["exclusive bounds"] = tcase{
+		min:            "(0",
+		max:            "(20000.5",
+		expectedResult: []string{"333", "444"},
+	}
+
+	testCases["negative bound"] = tcase{
+		min:            "-1000",
+		max:            "(0",
+		expectedResult: []string{"111"},
+	}
+
+	testCases["empty range"] = tcase{
+		min:            "20001",
+		max:            "+inf",
+		expectedResult: []string{},
+	}
+
+	testCases["invalid bound"] = tcase{
+		min:           "murah",
+		max:           "+inf",
+		expectedError: errors.New("Invalid score bound murah"),
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on ZRangeByScore with test case:", ktc)
+		actualResult, actualError := boltObject.ZRangeByScore(context.Background(), "range:campaign:price", vtc.min, vtc.max)
+		assert.Equal(t, vtc.expectedError, actualError)
+		if vtc.expectedError == nil {
+			assert.Equal(t, vtc.expectedResult, actualResult)
+		}
+	}
+
+	members, err := boltObject.ZRangeByScore(context.Background(), "range:campaign:stock", "-inf", "+inf")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, members)
+}
+
+func TestSetGetDel(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	err := boltObject.Set(context.Background(), "normal:campaign:666", "ganteng")
+	assert.Nil(t, err)
+
+	value, err := boltObject.Get(context.Background(), "normal:campaign:666")
+	assert.Nil(t, err)
+	assert.Equal(t, "ganteng", value)
+
+	keyType, _ := boltObject.Type(context.Background(), "normal:campaign:666")
+	assert.Equal(t, KeyTypeString, keyType)
+
+	removed, err := boltObject.Del(context.Background(), "normal:campaign:666")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	//a missing key has an empty value
+	value, err = boltObject.Get(context.Background(), "normal:campaign:666")
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+
+	//MGet keeps the order of the keys
+	boltObject.Set(context.Background(), "normal:campaign:777", "banget")
+	values, err := boltObject.MGet(context.Background(), []string{"normal:campaign:777", "normal:campaign:666"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"banget", ""}, values)
+	boltObject.Del(context.Background(), "normal:campaign:777")
+
+	//Del removes a key of any type
+	boltObject.SAdd(context.Background(), "campaign:ganteng", []interface{}{666})
+	boltObject.ZAdd(context.Background(), "range:campaign:price", 1, "666")
+	removed, _ = boltObject.Del(context.Background(), "campaign:ganteng")
+	assert.Equal(t, int64(1), removed)
+	removed, _ = boltObject.Del(context.Background(), "range:campaign:price")
+	assert.Equal(t, int64(1), removed)
+	removed, _ = boltObject.Del(context.Background(), "range:campaign:price")
+	assert.Equal(t, int64(0), removed)
+	keys, _ := boltObject.KeysPrefix(context.Background(), "campaign:")
+	assert.Equal(t, 0, len(keys))
+}
+
+func TestKeysPrefix(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	boltObject.SAdd(context.Background(), "campaign:bangun", []interface{}{1})
+	boltObject.SAdd(context.Background(), "campaign:tidur", []interface{}{1})
+	boltObject.SAdd(context.Background(), "advcampaign:jalan", []interface{}{1})
+	boltObject.ZAdd(context.Background(), "campaign:duduk", 1, "1")
+
+	//test case 1 : normal
+	keys, err := boltObject.KeysPrefix(context.Background(), "campaign:")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"campaign:bangun", "campaign:duduk", "campaign:tidur"}, keys)
+
+	//test case 2 : expect error
+	keys, err = boltObject.KeysPrefix(context.Background(), "             ")
+	assert.Equal(t, errors.New("Prefix must be defined!"), err)
+	assert.Equal(t, make([]string, 0), keys)
+}
+
+func TestDoneContext(t *testing.T) {
+	boltObject := initTestBolt(t)
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := boltObject.SAdd(cancelledCtx, "campaign:ganteng", []interface{}{666})
+	assert.Equal(t, context.Canceled, err)
+
+	keyType, _ := boltObject.Type(context.Background(), "campaign:ganteng")
+	assert.Equal(t, KeyTypeNone, keyType)
+}
@@ -0,0 +1,93 @@
+//Package main is the migration tool that copies an existing elasthink index from redis into the embedded bolt storage
+package main
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/bolt"
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/redis"
+	"github.com/SurgicalSteel/elasthink/storage"
+	"github.com/SurgicalSteel/elasthink/util"
+)
+
+const configPath string = "files/config"
+
+func main() {
+	log.SetOutput(os.Stdout)
+	environmentFlag := flag.String("env", config.EnvOrDefault(config.EnvEnvironment, "development"), "specify the environment whose redis and storage config are used (development / staging / production or any environment that has its config files), can be set with "+config.EnvEnvironment)
+	configPathFlag := flag.String("config", config.EnvOrDefault(config.EnvConfigPath, configPath), "specify the config directory, can be set with "+config.EnvConfigPath)
+	boltPathFlag := flag.String("bolt", "", "path of the destination bolt database file (default is the path in the storage config)")
+	prefixFlag := flag.String("prefix", "", "only keys with this prefix are migrated (default is the namespace in the storage config followed by a colon)")
+
+	flag.Parse()
+
+	environment, err := util.GetEnv(*environmentFlag)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	log.Println("Environment for elasthink migration:", environment)
+
+	err = config.InitConfig(*configPathFlag, environment)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	//KEYS only reaches one node of a redis cluster, and the keys there are hash tagged while bolt keys are not
+	if redis.GetMode(config.GetRedisConfig().RedisElasthink.Mode) == redis.ModeCluster {
+		log.Fatalln("Migration from a redis cluster is not supported, migrate from a standalone or sentinel redis instead")
+		return
+	}
+
+	storageConfig := *config.GetStorageConfig()
+	if len(strings.Trim(*boltPathFlag, " ")) > 0 {
+		storageConfig.BoltElasthink.Path = *boltPathFlag
+	}
+
+	prefix := *prefixFlag
+	if len(strings.Trim(prefix, " ")) == 0 {
+		prefix = storage.NewKeyBuilder(storageConfig.Storage.Namespace, false).NamespacePrefix()
+	}
+
+	redisObject := redis.InitRedis(*config.GetRedisConfig())
+	defer redisObject.Close()
+
+	boltObject, err := bolt.InitBolt(storageConfig)
+	if err != nil {
+		log.Fatalln("Failed to open bolt database. Reason :", err.Error())
+		return
+	}
+	defer boltObject.Close()
+
+	log.Println("Migrating keys with prefix", prefix, "from redis into", storageConfig.BoltElasthink.Path)
+	migrated, err := storage.Migrate(context.Background(), redisObject, boltObject, prefix)
+	if err != nil {
+		log.Println("Migration stopped after", migrated, "keys. Reason :", err.Error())
+		os.Exit(1)
+	}
+
+	log.Println("Migration finished,", migrated, "keys migrated")
+}
@@ -0,0 +1,66 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/gcfg.v1"
+)
+
+var authConfig *AuthConfigWrap
+
+//AuthConfigWrap is A wrapper for reading the authentication of the internal endpoints
+type AuthConfigWrap struct {
+	InternalAuth InternalAuthConfig
+}
+
+//InternalAuthConfig is the authentication configuration of the internal endpoints.
+//Method (token / hmac / mtls) can be defined multiple times. A request must pass one of token or hmac method (when configured), and mtls method when it is configured.
+//BearerToken and HMACSecret can be defined multiple times (for rotation). HMACMaxSkew is the maximum age of a signed request (in second).
+//ClientCAFile is the CA certificate file (PEM) to verify client certificates, AllowedClientName is an allowed common name of a client certificate (all verified clients are allowed if it is not defined).
+type InternalAuthConfig struct {
+	Method            []string
+	BearerToken       []string
+	HMACSecret        []string
+	HMACMaxSkew       int
+	ClientCAFile      string
+	AllowedClientName []string
+}
+
+func readAuthConfig(path, env string) error {
+	authConfig = &AuthConfigWrap{}
+	fileName := fmt.Sprintf("%s/auth/%s.ini", path, env)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		log.Println(configTag, "Auth config file not found, internal endpoints are not authenticated")
+	} else {
+		err = gcfg.ReadFileInto(authConfig, fileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return applyWrapEnvOverrides(authConfig)
+}
+
+//GetAuthConfig gets the auth config that has been initializad
+func GetAuthConfig() *AuthConfigWrap {
+	return authConfig
+}
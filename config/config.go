@@ -19,13 +19,27 @@ package config
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 const configTag string = "[CONFIG]"
 
-//InitConfig initializes all configuration (database, mq, api calls, redis, etc.)
+//requiredConfigDirectories are the config directories that must have a config file for the environment, the others are optional
+var requiredConfigDirectories = []string{"redis"}
+
+//InitConfig initializes all configuration (database, mq, api calls, redis, etc.).
+//Every config value can be overridden by its ELASTHINK_* environment variable (see EnvName)
 func InitConfig(path, env string) error {
+	err := validateEnvironment(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on validating environment. Detail :", err.Error())
+		return err
+	}
 
 	// err := readDatabaseConfig(path, env)
 	// if err != nil {
@@ -39,11 +53,97 @@ func InitConfig(path, env string) error {
 	// 	return err
 	// }
 
-	err := readRedisConfig(path, env)
+	err = readRedisConfig(path, env)
 	if err != nil {
 		log.Println(configTag, "Error on reading Redis cofig. Detail :", err.Error())
 		return err
 	}
 
+	err = readStorageConfig(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on reading Storage config. Detail :", err.Error())
+		return err
+	}
+
+	err = readTenantConfig(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on reading Tenant config. Detail :", err.Error())
+		return err
+	}
+
+	err = readDocumentConfig(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on reading Document config. Detail :", err.Error())
+		return err
+	}
+
+	err = readAuthConfig(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on reading Auth config. Detail :", err.Error())
+		return err
+	}
+
+	err = readRateLimitConfig(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on reading Rate Limit config. Detail :", err.Error())
+		return err
+	}
+
+	err = readServerConfig(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on reading Server config. Detail :", err.Error())
+		return err
+	}
+
+	err = readExpiryConfig(path, env)
+	if err != nil {
+		log.Println(configTag, "Error on reading Expiry config. Detail :", err.Error())
+		return err
+	}
+
 	return nil
 }
+
+//validateEnvironment checks that the config path is a directory, the environment has at least one config file and every required config file exists
+func validateEnvironment(path, env string) error {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("Config path %s is not a directory", path)
+	}
+
+	environments := listEnvironments(path)
+	isKnown := false
+	for _, environment := range environments {
+		if environment == env {
+			isKnown = true
+			break
+		}
+	}
+	if !isKnown {
+		return fmt.Errorf("Unknown environment %s, no config file found in %s (available environments : %s)", env, path, strings.Join(environments, ", "))
+	}
+
+	for _, directory := range requiredConfigDirectories {
+		fileName := fmt.Sprintf("%s/%s/%s.ini", path, directory, env)
+		if _, err := os.Stat(fileName); err != nil {
+			return fmt.Errorf("Missing config file %s", fileName)
+		}
+	}
+	return nil
+}
+
+//listEnvironments lists the environments (sorted) that have a config file in any config directory
+func listEnvironments(path string) []string {
+	fileNames, _ := filepath.Glob(filepath.Join(path, "*", "*.ini"))
+	environmentMap := make(map[string]int)
+	for _, fileName := range fileNames {
+		environmentMap[strings.TrimSuffix(filepath.Base(fileName), ".ini")] = 1
+	}
+
+	environments := make([]string, 0, len(environmentMap))
+	for environment := range environmentMap {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+	return environments
+}
@@ -0,0 +1,76 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/gcfg.v1"
+)
+
+var documentConfig *DocumentConfigWrap
+
+//DocumentConfigWrap is A wrapper for reading the fields of every document type (one subsection for each document type, e.g. [DocumentType "campaign"])
+type DocumentConfigWrap struct {
+	DocumentType map[string]*DocumentTypeConfig
+}
+
+//DocumentTypeConfig is the configuration of the fields of a document type. Field can be defined multiple times, its format is name:boost (e.g. title:3), the boost is 1 when it is not defined.
+//IDType is the type of the document ids, int64 (default) or string
+type DocumentTypeConfig struct {
+	Field  []string
+	IDType string
+}
+
+func readDocumentConfig(path, env string) error {
+	loadedDocumentConfig, err := LoadDocumentConfig(path, env)
+	if err != nil {
+		return err
+	}
+	documentConfig = loadedDocumentConfig
+	return nil
+}
+
+//LoadDocumentConfig reads the document config (with its environment variable overrides) without replacing the initialized one, so the document fields can be reloaded
+func LoadDocumentConfig(path, env string) (*DocumentConfigWrap, error) {
+	loadedDocumentConfig := &DocumentConfigWrap{
+		DocumentType: make(map[string]*DocumentTypeConfig),
+	}
+	fileName := fmt.Sprintf("%s/document/%s.ini", path, env)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		log.Println(configTag, "Document config file not found, every document type has a single name field")
+	} else {
+		err = gcfg.ReadFileInto(loadedDocumentConfig, fileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := applyWrapEnvOverrides(loadedDocumentConfig)
+	if err != nil {
+		return nil, err
+	}
+	return loadedDocumentConfig, nil
+}
+
+//GetDocumentConfig gets the document config that has been initializad
+func GetDocumentConfig() *DocumentConfigWrap {
+	return documentConfig
+}
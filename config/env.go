@@ -0,0 +1,197 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+//envPrefix is the prefix of every environment variable that overrides a config value
+const envPrefix string = "ELASTHINK"
+
+//EnvEnvironment and EnvConfigPath are the environment variables of the environment name and the config directory, used when their flags are not given
+const (
+	EnvEnvironment string = "ELASTHINK_ENV"
+	EnvConfigPath  string = "ELASTHINK_CONFIG_PATH"
+)
+
+//EnvStopwordsRemoval, EnvLogLevel and EnvShutdownDelay are the environment variables of the -swr, -loglevel and -shutdowndelay flags, used when their flags are not given
+const (
+	EnvStopwordsRemoval string = "ELASTHINK_STOPWORDS_REMOVAL"
+	EnvLogLevel         string = "ELASTHINK_LOG_LEVEL"
+	EnvShutdownDelay    string = "ELASTHINK_SHUTDOWN_DELAY"
+)
+
+//EnvOrDefault gets the value of an environment variable, or the default value if it is not set
+func EnvOrDefault(name, defaultValue string) string {
+	if value, ok := os.LookupEnv(name); ok && len(strings.Trim(value, " ")) > 0 {
+		return strings.Trim(value, " ")
+	}
+	return defaultValue
+}
+
+//EnvBoolOrDefault gets the boolean value of an environment variable (e.g. true or 1), or the default value if it is not set
+func EnvBoolOrDefault(name string, defaultValue bool) (bool, error) {
+	rawValue := EnvOrDefault(name, "")
+	if len(rawValue) == 0 {
+		return defaultValue, nil
+	}
+	value, err := strconv.ParseBool(rawValue)
+	if err != nil {
+		return defaultValue, fmt.Errorf("Invalid boolean value of %s", name)
+	}
+	return value, nil
+}
+
+//EnvDurationOrDefault gets the duration value of an environment variable (e.g. 5s), or the default value if it is not set
+func EnvDurationOrDefault(name string, defaultValue time.Duration) (time.Duration, error) {
+	rawValue := EnvOrDefault(name, "")
+	if len(rawValue) == 0 {
+		return defaultValue, nil
+	}
+	value, err := time.ParseDuration(rawValue)
+	if err != nil {
+		return defaultValue, fmt.Errorf("Invalid duration value of %s", name)
+	}
+	return value, nil
+}
+
+//EnvName is the environment variable that overrides a config field of a section. Format --> ELASTHINK_SECTION_FIELD (e.g. ELASTHINK_SERVER_READ_TIMEOUT, ELASTHINK_INTERNAL_AUTH_HMAC_SECRET)
+func EnvName(section, field string) string {
+	return fmt.Sprintf("%s_%s_%s", envPrefix, toScreamingSnakeCase(section), toScreamingSnakeCase(field))
+}
+
+//applyWrapEnvOverrides overrides every section of a config wrapper (a pointer to struct) from the environment variables.
+//A section is a struct field, or a subsection of a map field that is named after the field and the subsection (e.g. ELASTHINK_RATE_LIMIT_SEARCH_CLIENT_RATE for [RateLimit "search"]).
+//Only subsections that are defined in the config file can be overridden.
+func applyWrapEnvOverrides(wrap interface{}) error {
+	value := reflect.ValueOf(wrap).Elem()
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		fieldValue := value.Field(i)
+		switch fieldValue.Kind() {
+		case reflect.Struct:
+			err := applyEnvOverrides(field.Name, fieldValue.Addr().Interface())
+			if err != nil {
+				return err
+			}
+		case reflect.Map:
+			for _, key := range fieldValue.MapKeys() {
+				subsection := fieldValue.MapIndex(key)
+				if subsection.Kind() != reflect.Ptr || subsection.IsNil() {
+					continue
+				}
+				section := fmt.Sprintf("%s_%s", toScreamingSnakeCase(field.Name), toEnvPart(key.String()))
+				err := applyEnvOverrides(section, subsection.Interface())
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//applyEnvOverrides overrides every field of a config section (a pointer to struct) from its environment variable, if it is set.
+//Multi-valued fields ([]string) are comma separated.
+func applyEnvOverrides(section string, target interface{}) error {
+	value := reflect.ValueOf(target).Elem()
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		envName := EnvName(section, field.Name)
+		rawValue, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		switch fieldValue.Kind() {
+		case reflect.String:
+			fieldValue.SetString(rawValue)
+		case reflect.Int:
+			parsed, err := strconv.Atoi(strings.Trim(rawValue, " "))
+			if err != nil {
+				return fmt.Errorf("Invalid integer value of %s", envName)
+			}
+			fieldValue.SetInt(int64(parsed))
+		case reflect.Float64:
+			parsed, err := strconv.ParseFloat(strings.Trim(rawValue, " "), 64)
+			if err != nil {
+				return fmt.Errorf("Invalid number value of %s", envName)
+			}
+			fieldValue.SetFloat(parsed)
+		case reflect.Bool:
+			parsed, err := strconv.ParseBool(strings.Trim(rawValue, " "))
+			if err != nil {
+				return fmt.Errorf("Invalid boolean value of %s", envName)
+			}
+			fieldValue.SetBool(parsed)
+		case reflect.Slice:
+			if fieldValue.Type().Elem().Kind() != reflect.String {
+				return fmt.Errorf("Unsupported type of %s", envName)
+			}
+			values := make([]string, 0)
+			for _, v := range strings.Split(rawValue, ",") {
+				if v = strings.Trim(v, " "); len(v) > 0 {
+					values = append(values, v)
+				}
+			}
+			fieldValue.Set(reflect.ValueOf(values))
+		default:
+			return fmt.Errorf("Unsupported type of %s", envName)
+		}
+	}
+	return nil
+}
+
+//toEnvPart converts a subsection name into an environment variable part, e.g. payment-v2 --> PAYMENT_V2
+func toEnvPart(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+//toScreamingSnakeCase converts a field name into an environment variable part, e.g. ReadTimeout --> READ_TIMEOUT, TLSCertFile --> TLS_CERT_FILE
+func toScreamingSnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			isAfterLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			isBeforeLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if isAfterLower || (isBeforeLower && unicode.IsUpper(runes[i-1])) {
+				builder.WriteRune('_')
+			}
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+	return builder.String()
+}
@@ -0,0 +1,140 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvName(t *testing.T) {
+	type tcase struct {
+		section  string
+		field    string
+		expected string
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["simple field"] = tcase{section: "Server", field: "Address", expected: "ELASTHINK_SERVER_ADDRESS"}
+	testCases["camel case field"] = tcase{section: "Server", field: "ReadTimeout", expected: "ELASTHINK_SERVER_READ_TIMEOUT"}
+	testCases["acronym field"] = tcase{section: "Server", field: "TLSCertFile", expected: "ELASTHINK_SERVER_TLS_CERT_FILE"}
+	testCases["trailing acronym field"] = tcase{section: "InternalAuth", field: "HMACSecret", expected: "ELASTHINK_INTERNAL_AUTH_HMAC_SECRET"}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on EnvName with test case:", ktc)
+		assert.Equal(t, vtc.expected, EnvName(vtc.section, vtc.field))
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	type sample struct {
+		Address     string
+		ReadTimeout int
+		Rate        float64
+		UseTLS      bool
+		Replica     []string
+		Untouched   string
+	}
+	target := sample{Address: "0.0.0.0:9000", Untouched: "keep"}
+
+	os.Setenv("ELASTHINK_SAMPLE_ADDRESS", "127.0.0.1:9100")
+	os.Setenv("ELASTHINK_SAMPLE_READ_TIMEOUT", "30")
+	os.Setenv("ELASTHINK_SAMPLE_RATE", "2.5")
+	os.Setenv("ELASTHINK_SAMPLE_USE_TLS", "true")
+	os.Setenv("ELASTHINK_SAMPLE_REPLICA", "replica-1:6379, replica-2:6379")
+	defer func() {
+		for _, name := range []string{"ADDRESS", "READ_TIMEOUT", "RATE", "USE_TLS", "REPLICA"} {
+			os.Unsetenv("ELASTHINK_SAMPLE_" + name)
+		}
+	}()
+
+	err := applyEnvOverrides("SAMPLE", &target)
+	assert.Nil(t, err)
+	assert.Equal(t, sample{
+		Address:     "127.0.0.1:9100",
+		ReadTimeout: 30,
+		Rate:        2.5,
+		UseTLS:      true,
+		Replica:     []string{"replica-1:6379", "replica-2:6379"},
+		Untouched:   "keep",
+	}, target)
+
+	os.Setenv("ELASTHINK_SAMPLE_READ_TIMEOUT", "thirty")
+	err = applyEnvOverrides("SAMPLE", &target)
+	assert.NotNil(t, err)
+}
+
+func TestApplyWrapEnvOverrides(t *testing.T) {
+	target := &RateLimitConfigWrap{
+		RateLimiter: RateLimiterConfig{Store: "memory"},
+		RateLimit: map[string]*RateLimitConfig{
+			"search":  {ClientRate: 10, ClientBurst: 20},
+			"suggest": {ClientRate: 5, ClientBurst: 10},
+		},
+	}
+
+	os.Setenv("ELASTHINK_RATE_LIMITER_STORE", "redis")
+	os.Setenv("ELASTHINK_RATE_LIMIT_SEARCH_CLIENT_RATE", "50")
+	defer os.Unsetenv("ELASTHINK_RATE_LIMITER_STORE")
+	defer os.Unsetenv("ELASTHINK_RATE_LIMIT_SEARCH_CLIENT_RATE")
+
+	err := applyWrapEnvOverrides(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "redis", target.RateLimiter.Store)
+	assert.Equal(t, RateLimitConfig{ClientRate: 50, ClientBurst: 20}, *target.RateLimit["search"])
+	assert.Equal(t, RateLimitConfig{ClientRate: 5, ClientBurst: 10}, *target.RateLimit["suggest"])
+}
+
+func TestEnvFlagDefaults(t *testing.T) {
+	defer func() {
+		for _, name := range []string{EnvStopwordsRemoval, EnvShutdownDelay, EnvLogLevel} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	//the defaults are used when the environment variables are not set
+	isUsingStopwordsRemoval, err := EnvBoolOrDefault(EnvStopwordsRemoval, false)
+	assert.Nil(t, err)
+	assert.False(t, isUsingStopwordsRemoval)
+	shutdownDelay, err := EnvDurationOrDefault(EnvShutdownDelay, 5*time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, shutdownDelay)
+	assert.Equal(t, "info", EnvOrDefault(EnvLogLevel, "info"))
+
+	os.Setenv(EnvStopwordsRemoval, "true")
+	os.Setenv(EnvShutdownDelay, "10s")
+	os.Setenv(EnvLogLevel, " debug ")
+	isUsingStopwordsRemoval, err = EnvBoolOrDefault(EnvStopwordsRemoval, false)
+	assert.Nil(t, err)
+	assert.True(t, isUsingStopwordsRemoval)
+	shutdownDelay, err = EnvDurationOrDefault(EnvShutdownDelay, 5*time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, shutdownDelay)
+	assert.Equal(t, "debug", EnvOrDefault(EnvLogLevel, "info"))
+
+	os.Setenv(EnvStopwordsRemoval, "yes please")
+	os.Setenv(EnvShutdownDelay, "10")
+	_, err = EnvBoolOrDefault(EnvStopwordsRemoval, false)
+	assert.NotNil(t, err)
+	_, err = EnvDurationOrDefault(EnvShutdownDelay, 5*time.Second)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,84 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/gcfg.v1"
+)
+
+var expiryConfig *ExpiryConfigWrap
+
+//ExpiryConfigWrap is A wrapper for reading the document expiry configuration
+type ExpiryConfigWrap struct {
+	Expiry ExpiryConfig
+}
+
+//ExpiryConfig is the configuration of the sweeper of expired documents.
+//SweepInterval and SweepTimeout are in second, the sweeper does not run when SweepInterval is 0 (expired documents are still not found by search)
+type ExpiryConfig struct {
+	SweepInterval int
+	SweepTimeout  int
+}
+
+//defaultExpiryConfig is the expiry configuration used when no expiry config file is provided
+var defaultExpiryConfig = ExpiryConfig{
+	SweepInterval: 60,
+	SweepTimeout:  30,
+}
+
+func readExpiryConfig(path, env string) error {
+	expiryConfig = &ExpiryConfigWrap{
+		Expiry: defaultExpiryConfig,
+	}
+	fileName := fmt.Sprintf("%s/expiry/%s.ini", path, env)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		log.Println(configTag, "Expiry config file not found, using default expiry config")
+	} else {
+		err = gcfg.ReadFileInto(expiryConfig, fileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := applyWrapEnvOverrides(expiryConfig)
+	if err != nil {
+		return err
+	}
+	return validateExpiryConfig(expiryConfig.Expiry)
+}
+
+//validateExpiryConfig rejects a negative sweep interval, and a sweep timeout that would cancel every sweep when the sweeper runs
+func validateExpiryConfig(ec ExpiryConfig) error {
+	if ec.SweepInterval < 0 {
+		return errors.New("Expiry SweepInterval must not be negative")
+	}
+	if ec.SweepInterval > 0 && ec.SweepTimeout <= 0 {
+		return errors.New("Expiry SweepTimeout must be positive when SweepInterval is positive")
+	}
+	return nil
+}
+
+//GetExpiryConfig gets the expiry config that has been initializad
+func GetExpiryConfig() *ExpiryConfigWrap {
+	return expiryConfig
+}
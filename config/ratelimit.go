@@ -0,0 +1,73 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/gcfg.v1"
+)
+
+var rateLimitConfig *RateLimitConfigWrap
+
+//RateLimitConfigWrap is A wrapper for reading the rate limiter and the limits of each route (one subsection for each route, e.g. [RateLimit "search"])
+type RateLimitConfigWrap struct {
+	RateLimiter RateLimiterConfig
+	RateLimit   map[string]*RateLimitConfig
+}
+
+//RateLimiterConfig is the configuration of the rate limiter.
+//Store is memory (limits are per instance) or redis (limits are shared across instances).
+//TrustForwardedFor takes the client IP from the X-Forwarded-For header, only enable it behind a trusted proxy.
+type RateLimiterConfig struct {
+	Store             string
+	TrustForwardedFor bool
+}
+
+//RateLimitConfig is the token bucket limits of a route, per client key (API key) and per IP.
+//Rate is the number of requests per second, Burst is the bucket size. A zero rate means unlimited.
+type RateLimitConfig struct {
+	ClientRate  float64
+	ClientBurst int
+	IPRate      float64
+	IPBurst     int
+}
+
+func readRateLimitConfig(path, env string) error {
+	rateLimitConfig = &RateLimitConfigWrap{
+		RateLimit: make(map[string]*RateLimitConfig),
+	}
+	fileName := fmt.Sprintf("%s/ratelimit/%s.ini", path, env)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		log.Println(configTag, "Rate limit config file not found, running without rate limit")
+	} else {
+		err = gcfg.ReadFileInto(rateLimitConfig, fileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return applyWrapEnvOverrides(rateLimitConfig)
+}
+
+//GetRateLimitConfig gets the rate limit config that has been initializad
+func GetRateLimitConfig() *RateLimitConfigWrap {
+	return rateLimitConfig
+}
@@ -30,19 +30,48 @@ type RedisConfigWrap struct {
 	RedisElasthink RedisConfig
 }
 
-//RedisConfig is the basic configuration for a redis connection
+//RedisConfig is the basic configuration for a redis connection.
+//Username (redis ACL user) and Password are used to authenticate, Database is the selected database index.
+//ConnectTimeout, ReadTimeout and WriteTimeout are in second, zero means using the redigo default.
+//Mode is standalone (default), sentinel or cluster. SentinelAddress, ClusterAddress and ReplicaAddress can be defined multiple times.
+//ReplicaAddress is the address of a read replica (standalone and sentinel mode only), ReplicaHealthCheckInterval is in second.
+//OperationTimeout is the deadline of each redis command in millisecond (on top of the request deadline), zero means no deadline.
 type RedisConfig struct {
-	Address   string
-	MaxActive int
-	MaxIdle   int
-	Timeout   int
+	Address        string
+	MaxActive      int
+	MaxIdle        int
+	Timeout        int
+	Username       string
+	Password       string
+	Database       int
+	UseTLS         bool
+	TLSSkipVerify  bool
+	TLSServerName  string
+	TLSCACertFile  string
+	ConnectTimeout int
+	ReadTimeout    int
+	WriteTimeout   int
+
+	OperationTimeout int
+
+	Mode               string
+	SentinelAddress    []string
+	SentinelMasterName string
+	SentinelPassword   string
+	ClusterAddress     []string
+
+	ReplicaAddress             []string
+	ReplicaHealthCheckInterval int
 }
 
 func readRedisConfig(path, env string) error {
 	redisConfig = &RedisConfigWrap{}
 	fileName := fmt.Sprintf("%s/redis/%s.ini", path, env)
 	err := gcfg.ReadFileInto(redisConfig, fileName)
-	return err
+	if err != nil {
+		return err
+	}
+	return applyWrapEnvOverrides(redisConfig)
 }
 
 //GetRedisConfig gets the redis config that has been initializad
@@ -0,0 +1,99 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/gcfg.v1"
+)
+
+var serverConfig *ServerConfigWrap
+
+//ServerConfigWrap is A wrapper for reading the HTTP server configuration
+type ServerConfigWrap struct {
+	Server ServerConfig
+}
+
+//ServerConfig is the configuration of the HTTP server.
+//InternalAddress is an optional separate listen address for the internal endpoints (e.g. a private interface), they are served on Address when it is empty.
+//ReadTimeout, WriteTimeout, IdleTimeout and ShutdownTimeout are in second, ShutdownTimeout must be positive. TLS is served when both TLSCertFile and TLSKeyFile are defined, defining only one of them is an error.
+type ServerConfig struct {
+	Address         string
+	InternalAddress string
+	ReadTimeout     int
+	WriteTimeout    int
+	IdleTimeout     int
+	ShutdownTimeout int
+	TLSCertFile     string
+	TLSKeyFile      string
+}
+
+//defaultServerConfig is the server configuration used when no server config file is provided
+var defaultServerConfig = ServerConfig{
+	Address:         "0.0.0.0:9000",
+	ReadTimeout:     15,
+	WriteTimeout:    15,
+	IdleTimeout:     60,
+	ShutdownTimeout: 5,
+}
+
+//IsUsingTLS tells whether the server serves TLS
+func (sc ServerConfig) IsUsingTLS() bool {
+	return len(sc.TLSCertFile) > 0 && len(sc.TLSKeyFile) > 0
+}
+
+func readServerConfig(path, env string) error {
+	serverConfig = &ServerConfigWrap{
+		Server: defaultServerConfig,
+	}
+	fileName := fmt.Sprintf("%s/server/%s.ini", path, env)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		log.Println(configTag, "Server config file not found, using default server config")
+	} else {
+		err = gcfg.ReadFileInto(serverConfig, fileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := applyWrapEnvOverrides(serverConfig)
+	if err != nil {
+		return err
+	}
+	return validateServerConfig(serverConfig.Server)
+}
+
+//validateServerConfig rejects a TLS certificate without its key (or a key without its certificate), so a typo does not silently serve plaintext, and a shutdown timeout that would cancel the shutdown immediately
+func validateServerConfig(sc ServerConfig) error {
+	if (len(sc.TLSCertFile) > 0) != (len(sc.TLSKeyFile) > 0) {
+		return errors.New("Server TLSCertFile and TLSKeyFile must be defined together")
+	}
+	if sc.ShutdownTimeout <= 0 {
+		return errors.New("Server ShutdownTimeout must be positive")
+	}
+	return nil
+}
+
+//GetServerConfig gets the server config that has been initializad
+func GetServerConfig() *ServerConfigWrap {
+	return serverConfig
+}
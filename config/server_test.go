@@ -0,0 +1,49 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateServerConfig(t *testing.T) {
+	type tcase struct {
+		serverConfig ServerConfig
+		isError      bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["default config"] = tcase{serverConfig: defaultServerConfig}
+	testCases["tls config"] = tcase{serverConfig: ServerConfig{ShutdownTimeout: 5, TLSCertFile: "server.crt", TLSKeyFile: "server.key"}}
+	testCases["tls certificate without key"] = tcase{serverConfig: ServerConfig{ShutdownTimeout: 5, TLSCertFile: "server.crt"}, isError: true}
+	testCases["tls key without certificate"] = tcase{serverConfig: ServerConfig{ShutdownTimeout: 5, TLSKeyFile: "server.key"}, isError: true}
+	testCases["zero shutdown timeout"] = tcase{serverConfig: ServerConfig{ShutdownTimeout: 0}, isError: true}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on validateServerConfig with test case:", ktc)
+		err := validateServerConfig(vtc.serverConfig)
+		if vtc.isError {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/gcfg.v1"
+)
+
+var storageConfig *StorageConfigWrap
+
+//StorageConfigWrap is A wrapper for reading the storage backend selection and the embedded storage configuration
+type StorageConfigWrap struct {
+	Storage       StorageConfig
+	BoltElasthink BoltConfig
+}
+
+//StorageConfig is the configuration to select which storage backend holds the indexes (redis / bolt).
+//Namespace is the prefix of every key elasthink reads or writes (default is elasthink)
+type StorageConfig struct {
+	Backend   string
+	Namespace string
+}
+
+//BoltConfig is the basic configuration for an embedded bolt database file
+type BoltConfig struct {
+	Path    string
+	Timeout int
+}
+
+//DefaultStorageBackend is the storage backend used when no storage config file is provided
+const DefaultStorageBackend string = "redis"
+
+func readStorageConfig(path, env string) error {
+	storageConfig = &StorageConfigWrap{
+		Storage: StorageConfig{Backend: DefaultStorageBackend},
+	}
+	fileName := fmt.Sprintf("%s/storage/%s.ini", path, env)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		log.Println(configTag, "Storage config file not found, using default storage backend :", DefaultStorageBackend)
+	} else {
+		err = gcfg.ReadFileInto(storageConfig, fileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return applyWrapEnvOverrides(storageConfig)
+}
+
+//GetStorageConfig gets the storage config that has been initializad
+func GetStorageConfig() *StorageConfigWrap {
+	return storageConfig
+}
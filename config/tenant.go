@@ -0,0 +1,77 @@
+package config
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/gcfg.v1"
+)
+
+var tenantConfig *TenantConfigWrap
+
+//TenantConfigWrap is A wrapper for reading all tenants (one subsection for each tenant, e.g. [Tenant "payment"])
+type TenantConfigWrap struct {
+	Tenant map[string]*TenantConfig
+}
+
+//TenantConfig is the configuration of a tenant. APIKey and DocumentType can be defined multiple times.
+//Namespace is the key namespace of the tenant, default is the storage namespace followed by the tenant name
+type TenantConfig struct {
+	APIKey       []string
+	DocumentType []string
+	Namespace    string
+}
+
+func readTenantConfig(path, env string) error {
+	loadedTenantConfig, err := LoadTenantConfig(path, env)
+	if err != nil {
+		return err
+	}
+	tenantConfig = loadedTenantConfig
+	return nil
+}
+
+//LoadTenantConfig reads the tenant config (with its environment variable overrides) without replacing the initialized one, so the tenants can be reloaded
+func LoadTenantConfig(path, env string) (*TenantConfigWrap, error) {
+	loadedTenantConfig := &TenantConfigWrap{
+		Tenant: make(map[string]*TenantConfig),
+	}
+	fileName := fmt.Sprintf("%s/tenant/%s.ini", path, env)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		log.Println(configTag, "Tenant config file not found, running without tenants")
+	} else {
+		err = gcfg.ReadFileInto(loadedTenantConfig, fileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := applyWrapEnvOverrides(loadedTenantConfig)
+	if err != nil {
+		return nil, err
+	}
+	return loadedTenantConfig, nil
+}
+
+//GetTenantConfig gets the tenant config that has been initializad
+func GetTenantConfig() *TenantConfigWrap {
+	return tenantConfig
+}
@@ -34,6 +34,30 @@ const (
 	CampaignDocument DocumentType = "campaign"
 )
 
+//DefaultDocumentField is the field of a document type that has no configured fields, it holds the document name
+const DefaultDocumentField string = "name"
+
+//DefaultDocumentFieldBoost is the boost of a field when it is not configured
+const DefaultDocumentFieldBoost float64 = 1
+
+//AllDocumentField is the pseudo field of a word that is only found in the word set of the document type (a document indexed before its document type had fields)
+const AllDocumentField string = "_all"
+
+//DocumentField is a named field of a document type. A match on the field is weighted by its boost in ranking
+type DocumentField struct {
+	Name  string
+	Boost float64
+}
+
+//StoredDocument is a document kept in the normal index: the values of its fields, the keys it is indexed in (sets in Keys, sorted sets in RangeKeys) and its expiry time in unix seconds (0 when it does not expire).
+//The keys are kept so an expired document can be removed from every posting without its content
+type StoredDocument struct {
+	Fields    map[string]string `json:"fields"`
+	Keys      []string          `json:"keys"`
+	RangeKeys []string          `json:"rangeKeys,omitempty"`
+	ExpiresAt int64             `json:"expiresAt,omitempty"`
+}
+
 /*
 //IsValid checks if the document type is a valid (registered) document type in entity const
 func (dt DocumentType) IsValid() error {
@@ -0,0 +1,124 @@
+package entity
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//DocumentIDType is the type of the ids of a document type
+type DocumentIDType string
+
+const (
+	//DocumentIDTypeInt64 is the type of positive int64 ids (default), they are written in JSON as numbers
+	DocumentIDTypeInt64 DocumentIDType = "int64"
+	//DocumentIDTypeString is the type of opaque string ids (e.g. UUID or slug), they are written in JSON as strings
+	DocumentIDTypeString DocumentIDType = "string"
+)
+
+//MaxStringDocumentIDLength is the maximum length of a string document id
+const MaxStringDocumentIDLength int = 128
+
+//stringDocumentIDRegex is the format of a string document id, which must not contain whitespaces nor key pattern characters (*, ?, [, ])
+var stringDocumentIDRegex = regexp.MustCompile(`^[^\s*?\[\]]+$`)
+
+//DocumentID is the id of a document as it is kept in the index (the decimal form of an int64 id) with the id type of its document type
+type DocumentID struct {
+	Value  string
+	IDType DocumentIDType
+}
+
+//Int64DocumentID is the document id of an int64 id
+func Int64DocumentID(id int64) DocumentID {
+	return DocumentID{Value: strconv.FormatInt(id, 10), IDType: DocumentIDTypeInt64}
+}
+
+//StringDocumentID is the document id of a string id
+func StringDocumentID(id string) DocumentID {
+	return DocumentID{Value: id, IDType: DocumentIDTypeString}
+}
+
+//GetDocumentIDType normalizes the given id type name, an empty name means the default (int64) id type
+func GetDocumentIDType(idType string) (DocumentIDType, error) {
+	switch DocumentIDType(strings.ToLower(strings.Trim(idType, " "))) {
+	case "", DocumentIDTypeInt64:
+		return DocumentIDTypeInt64, nil
+	case DocumentIDTypeString:
+		return DocumentIDTypeString, nil
+	}
+	return "", errors.New("Invalid Document ID Type")
+}
+
+//ParseDocumentID validates a raw document id of an id type. An int64 id must be a positive number, a string id must not be empty nor too long, and must not contain whitespaces nor key pattern characters
+func ParseDocumentID(raw string, idType DocumentIDType) (DocumentID, error) {
+	if idType == DocumentIDTypeString {
+		if len(raw) > MaxStringDocumentIDLength || !stringDocumentIDRegex.MatchString(raw) {
+			return DocumentID{}, errors.New("Invalid Document ID")
+		}
+		return StringDocumentID(raw), nil
+	}
+
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return DocumentID{}, errors.New("Invalid Document ID")
+	}
+	return Int64DocumentID(id), nil
+}
+
+//String gets the document id as it is kept in the index
+func (id DocumentID) String() string {
+	return id.Value
+}
+
+//Less tells whether the document id is ordered before the other one (numerically for int64 ids)
+func (id DocumentID) Less(other DocumentID) bool {
+	if id.IDType == DocumentIDTypeInt64 && other.IDType == DocumentIDTypeInt64 {
+		value, _ := strconv.ParseInt(id.Value, 10, 64)
+		otherValue, _ := strconv.ParseInt(other.Value, 10, 64)
+		return value < otherValue
+	}
+	return id.Value < other.Value
+}
+
+//MarshalJSON writes an int64 id as a number and a string id as a string
+func (id DocumentID) MarshalJSON() ([]byte, error) {
+	if id.IDType == DocumentIDTypeInt64 {
+		return []byte(id.Value), nil
+	}
+	return json.Marshal(id.Value)
+}
+
+//UnmarshalJSON reads a number as an int64 id and a string as a string id
+func (id *DocumentID) UnmarshalJSON(data []byte) error {
+	var number int64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*id = Int64DocumentID(number)
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("Document ID must be a number or a string")
+	}
+	*id = StringDocumentID(value)
+	return nil
+}
@@ -0,0 +1,102 @@
+package entity
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocumentID(t *testing.T) {
+	type tcase struct {
+		raw           string
+		idType        DocumentIDType
+		expected      DocumentID
+		expectedError error
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["int64 id"] = tcase{
+		raw:      "42",
+		idType:   DocumentIDTypeInt64,
+		expected: Int64DocumentID(42),
+	}
+	testCases["non numeric int64 id"] = tcase{
+		raw:           "promo-42",
+		idType:        DocumentIDTypeInt64,
+		expectedError: errors.New("Invalid Document ID"),
+	}
+	testCases["zero int64 id"] = tcase{
+		raw:           "0",
+		idType:        DocumentIDTypeInt64,
+		expectedError: errors.New("Invalid Document ID"),
+	}
+	testCases["uuid string id"] = tcase{
+		raw:      "3f2b8c1e-6a4d-4e8f-9b2a-1c0d5e7f9a3b",
+		idType:   DocumentIDTypeString,
+		expected: StringDocumentID("3f2b8c1e-6a4d-4e8f-9b2a-1c0d5e7f9a3b"),
+	}
+	testCases["empty string id"] = tcase{
+		raw:           "",
+		idType:        DocumentIDTypeString,
+		expectedError: errors.New("Invalid Document ID"),
+	}
+	testCases["string id with whitespace"] = tcase{
+		raw:           "promo murah",
+		idType:        DocumentIDTypeString,
+		expectedError: errors.New("Invalid Document ID"),
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on ParseDocumentID with test case:", ktc)
+		actual, actualError := ParseDocumentID(vtc.raw, vtc.idType)
+		assert.Equal(t, vtc.expectedError, actualError)
+		assert.Equal(t, vtc.expected, actual)
+	}
+}
+
+func TestDocumentIDJSON(t *testing.T) {
+	rawJSON, err := json.Marshal([]DocumentID{Int64DocumentID(42), StringDocumentID("promo-42")})
+	assert.Nil(t, err)
+	assert.Equal(t, `[42,"promo-42"]`, string(rawJSON))
+
+	var ids []DocumentID
+	err = json.Unmarshal(rawJSON, &ids)
+	assert.Nil(t, err)
+	assert.Equal(t, []DocumentID{Int64DocumentID(42), StringDocumentID("promo-42")}, ids)
+
+	assert.True(t, Int64DocumentID(9).Less(Int64DocumentID(10)))
+	assert.True(t, StringDocumentID("10").Less(StringDocumentID("9")))
+}
+
+func TestGetDocumentIDType(t *testing.T) {
+	idType, err := GetDocumentIDType("")
+	assert.Nil(t, err)
+	assert.Equal(t, DocumentIDTypeInt64, idType)
+
+	idType, err = GetDocumentIDType(" String ")
+	assert.Nil(t, err)
+	assert.Equal(t, DocumentIDTypeString, idType)
+
+	_, err = GetDocumentIDType("uuid")
+	assert.NotNil(t, err)
+}
@@ -19,7 +19,12 @@ package entity
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
+import (
+	"sync"
+)
+
 type entityData struct {
+	mutex         sync.RWMutex
 	documentTypes map[DocumentType]int
 	stopwordData  StopwordData
 }
@@ -27,6 +32,8 @@ type entityData struct {
 var Entity entityData
 
 func (e *entityData) Initialize(stopwordData StopwordData) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 	e.documentTypes = map[DocumentType]int{
 		CampaignDocument:              1,
 		AdvertisementCampaignDocument: 1,
@@ -35,9 +42,20 @@ func (e *entityData) Initialize(stopwordData StopwordData) {
 }
 
 func (e *entityData) GetDocumentTypes() map[DocumentType]int {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
 	return e.documentTypes
 }
 
 func (e *entityData) GetStopwordData() StopwordData {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
 	return e.stopwordData
 }
+
+//SetStopwordData replaces the stopword data (on reload)
+func (e *entityData) SetStopwordData(stopwordData StopwordData) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.stopwordData = stopwordData
+}
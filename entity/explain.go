@@ -0,0 +1,45 @@
+package entity
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//FieldMatch is a field of a document where a search word is found, with the boost it adds to the score of the document
+type FieldMatch struct {
+	Field string  `json:"field"`
+	Boost float64 `json:"boost"`
+}
+
+//TermExplanation is how a search word matches a document. DocumentFrequency is the number of matched documents (that pass the filters) where the word is found, Score is the sum of the boosts of Fields
+type TermExplanation struct {
+	Term              string       `json:"term"`
+	DocumentFrequency int          `json:"documentFrequency"`
+	Fields            []FieldMatch `json:"fields"`
+	Score             float64      `json:"score"`
+}
+
+//SearchResultExplanation is why a document matches a search and how it is scored and ranked. Score is the sum of the scores of MatchedTerms and ShowCount is their number,
+//the documents are ranked by score (descending), then show count (descending), then id (ascending). TotalMatched is the number of ranked documents, Description is the breakdown in words
+type SearchResultExplanation struct {
+	MatchedTerms   []TermExplanation `json:"matchedTerms"`
+	UnmatchedTerms []string          `json:"unmatchedTerms"`
+	Score          float64           `json:"score"`
+	ShowCount      int               `json:"showCount"`
+	Rank           int               `json:"rank"`
+	TotalMatched   int               `json:"totalMatched"`
+	Description    string            `json:"description"`
+}
@@ -18,9 +18,16 @@ package entity
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
-//SearchResultRankData is the core struct that represent search result rank item
+//SearchResultRankData is the core struct that represent search result rank item.
+//ShowCount is the number of search words found in the document, Score is the sum of the boosts of the fields where each search word is found.
+//Document is the stored values of the fields of the document, Highlights is the searched fields where a search word is found, with every found word wrapped in tags (the text is HTML escaped, the tags are not),
+//and Explanation is how the document is matched, scored and ranked (they are only returned when they are requested)
 type SearchResultRankData struct {
-	ID        int64 `json:"id"`
-	ShowCount int   `json:"showCount"`
-	Rank      int   `json:"rank"`
+	ID          DocumentID               `json:"id"`
+	ShowCount   int                      `json:"showCount"`
+	Score       float64                  `json:"score"`
+	Rank        int                      `json:"rank"`
+	Document    map[string]string        `json:"document,omitempty"`
+	Highlights  map[string]string        `json:"highlights,omitempty"`
+	Explanation *SearchResultExplanation `json:"explanation,omitempty"`
 }
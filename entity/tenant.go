@@ -1,4 +1,4 @@
-package module
+package entity
 
 // Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
 // Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
@@ -18,5 +18,9 @@ package module
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
-const elasthinkInvertedIndexPrefix string = "elasthink:inverted:"
-const elasthinkNormalIndexPrefix string = "elasthink:normal:"
+//Tenant is a business unit sharing an elasthink deployment. A tenant can only access its allowed document types, under its own key namespace
+type Tenant struct {
+	Name          string
+	Namespace     string
+	DocumentTypes map[DocumentType]int
+}
@@ -0,0 +1,192 @@
+package logger
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Level is the severity of a log line
+type Level int
+
+const (
+	//LevelDebug is for detailed lines that are only useful while debugging
+	LevelDebug Level = iota
+	//LevelInfo is for lines about the normal operation (default)
+	LevelInfo
+	//LevelWarn is for lines about unexpected conditions that do not fail a request
+	LevelWarn
+	//LevelError is for lines about failures
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+//ParseLevel parses a level name (debug, info, warn or error)
+func ParseLevel(level string) (Level, error) {
+	level = strings.ToLower(strings.Trim(level, " "))
+	for kl, vl := range levelNames {
+		if vl == level {
+			return kl, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("Invalid log level %s", level)
+}
+
+//Fields is the additional fields of a log line
+type Fields map[string]interface{}
+
+type contextKey string
+
+const requestIDContextKey contextKey = "requestID"
+
+var (
+	mutex    sync.Mutex
+	output   io.Writer = os.Stdout
+	minLevel           = LevelInfo
+)
+
+//SetOutput sets the output of every logger
+func SetOutput(w io.Writer) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	output = w
+}
+
+//SetLevel sets the minimum level of the written log lines
+func SetLevel(level Level) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	minLevel = level
+}
+
+//WithRequestID returns a copy of ctx that carries the request ID, which is written in every log line with this context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+//RequestID gets the request ID carried by ctx, empty if there is none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+//Logger writes JSON log lines (one object per line) of a component
+type Logger struct {
+	component string
+}
+
+//New creates a logger of a component (e.g. service, module, redis)
+func New(component string) *Logger {
+	return &Logger{component: component}
+}
+
+//Debug writes a debug line
+func (l *Logger) Debug(ctx context.Context, message string, fields Fields) {
+	l.write(ctx, LevelDebug, message, fields)
+}
+
+//Info writes an info line
+func (l *Logger) Info(ctx context.Context, message string, fields Fields) {
+	l.write(ctx, LevelInfo, message, fields)
+}
+
+//Warn writes a warning line
+func (l *Logger) Warn(ctx context.Context, message string, fields Fields) {
+	l.write(ctx, LevelWarn, message, fields)
+}
+
+//Error writes an error line
+func (l *Logger) Error(ctx context.Context, message string, fields Fields) {
+	l.write(ctx, LevelError, message, fields)
+}
+
+//write writes a line with the time, level, component, message and request ID (if any), followed by the additional fields
+func (l *Logger) write(ctx context.Context, level Level, message string, fields Fields) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if level < minLevel {
+		return
+	}
+
+	line := make(map[string]interface{}, len(fields)+5)
+	for kf, vf := range fields {
+		// errors are marshalled as an empty object, so the message is written instead
+		if err, ok := vf.(error); ok && err != nil {
+			vf = err.Error()
+		}
+		line[kf] = vf
+	}
+	line["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level.String()
+	line["component"] = l.component
+	line["msg"] = message
+	if requestID := RequestID(ctx); len(requestID) > 0 {
+		line["request_id"] = requestID
+	}
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		encoded, _ = json.Marshal(map[string]interface{}{
+			"time":      line["time"],
+			"level":     LevelError.String(),
+			"component": l.component,
+			"msg":       "Failed to marshal log line",
+			"error":     err.Error(),
+		})
+	}
+	output.Write(append(encoded, '\n'))
+}
+
+//stdWriter writes every line of the standard logger as an info line
+type stdWriter struct {
+	logger *Logger
+}
+
+//NewStdWriter creates a writer to plug the standard logger (log.SetOutput) into the JSON logger of a component
+func NewStdWriter(component string) io.Writer {
+	return &stdWriter{logger: New(component)}
+}
+
+func (sw *stdWriter) Write(p []byte) (int, error) {
+	sw.logger.Info(context.Background(), string(bytes.TrimRight(p, "\n")), nil)
+	return len(p), nil
+}
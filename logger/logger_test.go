@@ -0,0 +1,86 @@
+package logger
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	type tcase struct {
+		level         string
+		expected      Level
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["debug"] = tcase{level: "debug", expected: LevelDebug}
+	testCases["upper case warn"] = tcase{level: " WARN ", expected: LevelWarn}
+	testCases["invalid"] = tcase{level: "verbose", expected: LevelInfo, expectedError: true}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on ParseLevel with test case:", ktc)
+		actual, err := ParseLevel(vtc.level)
+		assert.Equal(t, vtc.expected, actual)
+		assert.Equal(t, vtc.expectedError, err != nil)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	SetOutput(buffer)
+	SetLevel(LevelInfo)
+	defer SetOutput(os.Stdout)
+
+	moduleLogger := New("module")
+	ctx := WithRequestID(context.Background(), "req-123")
+
+	moduleLogger.Debug(ctx, "skipped", nil)
+	assert.Equal(t, 0, buffer.Len())
+
+	moduleLogger.Error(ctx, "Failed to add index", Fields{"key": "elasthink:inverted:campaign:promo", "error": errors.New("connection refused")})
+	line := make(map[string]interface{})
+	err := json.Unmarshal(buffer.Bytes(), &line)
+	assert.Nil(t, err)
+	assert.Equal(t, "error", line["level"])
+	assert.Equal(t, "module", line["component"])
+	assert.Equal(t, "Failed to add index", line["msg"])
+	assert.Equal(t, "req-123", line["request_id"])
+	assert.Equal(t, "connection refused", line["error"])
+	assert.Equal(t, "elasthink:inverted:campaign:promo", line["key"])
+
+	buffer.Reset()
+	stdLogger := log.New(NewStdWriter("main"), "", 0)
+	stdLogger.Println("Server Started")
+	line = make(map[string]interface{})
+	err = json.Unmarshal(buffer.Bytes(), &line)
+	assert.Nil(t, err)
+	assert.Equal(t, "info", line["level"])
+	assert.Equal(t, "Server Started", line["msg"])
+	_, isRequestIDExist := line["request_id"]
+	assert.False(t, isRequestIDExist)
+}
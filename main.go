@@ -21,13 +21,20 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"github.com/SurgicalSteel/elasthink/config"
 	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/metrics"
 	"github.com/SurgicalSteel/elasthink/module"
 	"github.com/SurgicalSteel/elasthink/redis"
 	"github.com/SurgicalSteel/elasthink/router"
+	"github.com/SurgicalSteel/elasthink/service"
+	"github.com/SurgicalSteel/elasthink/storage"
 	"github.com/SurgicalSteel/elasthink/util"
 	"io/ioutil"
 	"log"
@@ -41,81 +48,286 @@ import (
 const stopwordsFileName string = "files/data/stopwords_id.json"
 const configPath string = "files/config"
 
+//envStopwordsFile is the environment variable of the stopwords file, used when its flag is not given
+const envStopwordsFile string = "ELASTHINK_STOPWORDS_FILE"
+
 func main() {
-	log.SetOutput(os.Stdout)
-	environmentFlag := flag.String("env", "development", "specify your environment for running elasthink (development / staging / production)")
-	stopwordsRemovalUsageFlag := flag.Bool("swr", false, "option to use stopwords removal during create index & update index & searching (default false)")
+	stopwordsRemovalUsage, err := config.EnvBoolOrDefault(config.EnvStopwordsRemoval, false)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	shutdownDelay, err := config.EnvDurationOrDefault(config.EnvShutdownDelay, 5*time.Second)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	environmentFlag := flag.String("env", config.EnvOrDefault(config.EnvEnvironment, "development"), "specify your environment for running elasthink (development / staging / production or any environment that has its config files), can be set with "+config.EnvEnvironment)
+	configPathFlag := flag.String("config", config.EnvOrDefault(config.EnvConfigPath, configPath), "specify the config directory, can be set with "+config.EnvConfigPath)
+	stopwordsFileFlag := flag.String("stopwords", config.EnvOrDefault(envStopwordsFile, stopwordsFileName), "specify the stopwords file, can be set with "+envStopwordsFile)
+	stopwordsRemovalUsageFlag := flag.Bool("swr", stopwordsRemovalUsage, "option to use stopwords removal during create index & update index & searching, can be set with "+config.EnvStopwordsRemoval)
+	shutdownDelayFlag := flag.Duration("shutdowndelay", shutdownDelay, "specify how long elasthink reports not ready before shutting down the server, so the load balancer stops routing requests, can be set with "+config.EnvShutdownDelay)
+	logLevelFlag := flag.String("loglevel", config.EnvOrDefault(config.EnvLogLevel, "info"), "specify the minimum level of log lines (debug / info / warn / error), can be set with "+config.EnvLogLevel)
 
 	flag.Parse()
 
-	environment := util.GetEnv(*environmentFlag)
+	//every log line is a JSON object, lines of the standard logger are written as info lines
+	logLevel, err := logger.ParseLevel(*logLevelFlag)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	logger.SetLevel(logLevel)
+	log.SetFlags(0)
+	log.SetOutput(logger.NewStdWriter("main"))
+
+	environment, err := util.GetEnv(*environmentFlag)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
 	log.Println("Environment for elasthink:", environment)
+	log.Println("Config path for elasthink:", *configPathFlag)
 
 	isUsingStopwordsRemoval := *stopwordsRemovalUsageFlag
 
 	//read stop words file
-	stopwordData, err := readStopwordsFile(stopwordsFileName)
+	stopwordData, err := readStopwordsFile(*stopwordsFileFlag)
 	if err != nil {
 		log.Fatalln(err)
 		return
 	}
 
 	//init config
-	err = config.InitConfig(configPath, environment)
+	err = config.InitConfig(*configPathFlag, environment)
 	if err != nil {
 		log.Fatalln(err)
 		return
 	}
 
-	//init redis
-	redisObject := redis.InitRedis(*config.GetRedisConfig())
+	err = storage.ValidateNamespace(config.GetStorageConfig().Storage.Namespace)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	//init storage (redis or embedded bolt)
+	storageObject, err := storage.InitStorage(*config.GetStorageConfig(), *config.GetRedisConfig())
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	log.Println("Storage backend for elasthink:", config.GetStorageConfig().Storage.Backend)
+	if poolStatsSource, ok := storageObject.(metrics.PoolStatsSource); ok {
+		metrics.SetPoolStatsSource(poolStatsSource)
+	}
 
 	//init entity data
 	entity.Entity.Initialize(stopwordData)
 
-	//init module
-	module.InitModule(entity.Entity.GetStopwordData(), redisObject, isUsingStopwordsRemoval)
+	//init module (keys are under the configured namespace and need hash tags on a redis cluster)
+	isUsingHashTag := redis.GetMode(config.GetRedisConfig().RedisElasthink.Mode) == redis.ModeCluster
+	keyBuilder := storage.NewKeyBuilder(config.GetStorageConfig().Storage.Namespace, isUsingHashTag)
+	log.Println("Key namespace for elasthink:", keyBuilder.Namespace())
+	module.InitModule(entity.Entity.GetStopwordData(), storageObject, isUsingStopwordsRemoval, keyBuilder)
+
+	//init tenants (requests are scoped to the tenant of their API key)
+	err = module.InitTenants(*config.GetTenantConfig())
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	log.Println("Using tenants:", module.IsUsingTenant(context.Background()))
+
+	//init fields of every document type
+	err = module.InitDocumentFields(*config.GetDocumentConfig())
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	//init authentication of internal endpoints
+	err = service.InitInternalAuth(*config.GetAuthConfig())
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	log.Println("Using internal endpoints authentication:", service.IsUsingInternalAuth())
+
+	//init rate limit of public endpoints
+	err = service.InitRateLimit(*config.GetRateLimitConfig(), storageObject, keyBuilder)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	service.InitHealth(storageObject)
+
+	//init reload of stopwords, tenants and document fields (on SIGHUP or the reload endpoint)
+	service.InitReload(func() error {
+		return reloadSettings(*stopwordsFileFlag, *configPathFlag, environment)
+	})
+	reloadSignal := make(chan os.Signal, 1)
+	signal.Notify(reloadSignal, syscall.SIGHUP)
+	go func() {
+		for range reloadSignal {
+			//the result is logged by the reload
+			service.Reload(context.Background())
+		}
+	}()
+
+	//sweep expired documents in the background
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	expiryConfig := config.GetExpiryConfig().Expiry
+	if expiryConfig.SweepInterval > 0 {
+		go service.RunExpirySweeper(sweeperCtx, time.Duration(expiryConfig.SweepInterval)*time.Second, time.Duration(expiryConfig.SweepTimeout)*time.Second)
+	}
+	log.Println("Using expiry sweeper:", expiryConfig.SweepInterval > 0)
+
+	//init servers (internal endpoints get their own listener when an internal address is configured)
+	serverConfig := config.GetServerConfig().Server
+	internalTLSConfig, err := buildInternalTLSConfig(config.GetAuthConfig().InternalAuth)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
 
 	routing := router.InitializeRoute()
 	routing.RegisterHandler()
 	routing.RegisterAppHandler()
-	routing.RegisterInternalHandler()
-	server := &http.Server{
-		Addr: "0.0.0.0:9000",
-		// Good practice to set timeouts to avoid Slowloris attacks.
-		WriteTimeout: time.Second * 15,
-		ReadTimeout:  time.Second * 15,
-		IdleTimeout:  time.Second * 60,
-		Handler:      routing.Router,
+	servers := make([]*http.Server, 0, 2)
+	if len(serverConfig.InternalAddress) == 0 {
+		routing.RegisterInternalHandler()
+		servers = append(servers, newServer(serverConfig, serverConfig.Address, routing.Router, internalTLSConfig))
+	} else {
+		internalRouting := router.InitializeRoute()
+		internalRouting.RegisterHandler()
+		internalRouting.RegisterInternalHandler()
+		servers = append(servers,
+			newServer(serverConfig, serverConfig.Address, routing.Router, nil),
+			newServer(serverConfig, serverConfig.InternalAddress, internalRouting.Router, internalTLSConfig))
 	}
-	log.Println("Starting elasthink in port 9000...")
+
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalln("Failed to start service! Reason :", err.Error())
-		}
-	}()
+	for _, server := range servers {
+		log.Println("Starting elasthink in", server.Addr, "using TLS:", serverConfig.IsUsingTLS())
+		go serve(server, serverConfig)
+	}
 	log.Println("Server Started")
 
 	<-done
 	log.Println("Server Stopped")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	//report not ready first, so no new request is routed here while the server shuts down
+	service.SetShuttingDown()
+	time.Sleep(*shutdownDelayFlag)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(serverConfig.ShutdownTimeout)*time.Second)
 
 	defer func() {
 		// extra handling here
 		cancel()
 	}()
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server Shutdown Failed:%+v\n", err)
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Fatalf("Server Shutdown Failed:%+v\n", err)
+		}
+	}
+
+	stopSweeper()
+	if err := storageObject.Close(); err != nil {
+		log.Println("Failed to close storage. Reason :", err.Error())
 	}
 
 	log.Println("Server Exited Properly")
 	log.Println("👋")
 }
 
+//newServer creates an HTTP server listening on address with the configured timeouts.
+//tlsConfig is only used when the server serves TLS
+func newServer(serverConfig config.ServerConfig, address string, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	server := &http.Server{
+		Addr: address,
+		// Good practice to set timeouts to avoid Slowloris attacks.
+		WriteTimeout: time.Duration(serverConfig.WriteTimeout) * time.Second,
+		ReadTimeout:  time.Duration(serverConfig.ReadTimeout) * time.Second,
+		IdleTimeout:  time.Duration(serverConfig.IdleTimeout) * time.Second,
+		Handler:      handler,
+	}
+	if serverConfig.IsUsingTLS() {
+		server.TLSConfig = tlsConfig
+	}
+	return server
+}
+
+//serve starts serving the server, with TLS when the cert and key files are configured
+func serve(server *http.Server, serverConfig config.ServerConfig) {
+	var err error
+	if serverConfig.IsUsingTLS() {
+		err = server.ListenAndServeTLS(serverConfig.TLSCertFile, serverConfig.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalln("Failed to start service! Reason :", err.Error())
+	}
+}
+
+//buildInternalTLSConfig builds the TLS config of the server of internal endpoints.
+//When a client CA file is configured, client certificates signed by it are verified so the mtls authentication can check them
+func buildInternalTLSConfig(authConfig config.InternalAuthConfig) (*tls.Config, error) {
+	if len(authConfig.ClientCAFile) == 0 {
+		return nil, nil
+	}
+
+	caCert, err := ioutil.ReadFile(authConfig.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no valid certificate found in client CA file %s", authConfig.ClientCAFile)
+	}
+
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  clientCAs,
+	}, nil
+}
+
+//reloadSettings reads the stopwords file, the tenant config and the document config again, then swaps them in.
+//Storage, server, auth and rate limit settings are not reloaded, they need a restart
+func reloadSettings(stopwordsFileName, configPath, environment string) error {
+	stopwordData, err := readStopwordsFile(stopwordsFileName)
+	if err != nil {
+		return err
+	}
+	if len(stopwordData.Words) == 0 {
+		return fmt.Errorf("Stopwords file %s has no word", stopwordsFileName)
+	}
+
+	tenantConfig, err := config.LoadTenantConfig(configPath, environment)
+	if err != nil {
+		return err
+	}
+
+	documentConfig, err := config.LoadDocumentConfig(configPath, environment)
+	if err != nil {
+		return err
+	}
+
+	err = module.ReloadSettings(stopwordData, *tenantConfig, *documentConfig)
+	if err != nil {
+		return err
+	}
+	entity.Entity.SetStopwordData(stopwordData)
+	return nil
+}
+
 func readStopwordsFile(fileName string) (entity.StopwordData, error) {
 	var stopwordData entity.StopwordData
 
@@ -0,0 +1,179 @@
+package metrics
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace string = "elasthink"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	requestCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	redisCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_duration_seconds",
+		Help:      "Latency of redis commands by command and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+	}, []string{"command", "result"})
+
+	searchResultSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "search",
+		Name:      "result_size",
+		Help:      "Number of documents in the result of a search by document type.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"document_type"})
+
+	searchZeroResultCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "search",
+		Name:      "zero_results_total",
+		Help:      "Number of searches without any result by document type.",
+	}, []string{"document_type"})
+
+	indexingErrorCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexing",
+		Name:      "errors_total",
+		Help:      "Number of failed indexing operations by document type and operation.",
+	}, []string{"document_type", "operation"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestCount,
+		requestDuration,
+		redisCommandDuration,
+		searchResultSize,
+		searchZeroResultCount,
+		indexingErrorCount,
+		poolStatsCollectorObj,
+	)
+}
+
+//Handler is the handler of the metrics endpoint (prometheus text format)
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+//ObserveRequest records an HTTP request of a route (path template)
+func ObserveRequest(route, method string, statusCode int, duration time.Duration) {
+	status := strconv.Itoa(statusCode)
+	requestCount.WithLabelValues(route, method, status).Inc()
+	requestDuration.WithLabelValues(route, method, status).Observe(duration.Seconds())
+}
+
+//ObserveRedisCommand records the latency of a redis command since start
+func ObserveRedisCommand(command string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	redisCommandDuration.WithLabelValues(command, result).Observe(time.Since(start).Seconds())
+}
+
+//ObserveSearchResult records the result size of a search, and counts it as a zero result search when it is empty
+func ObserveSearchResult(documentType string, size int) {
+	searchResultSize.WithLabelValues(documentType).Observe(float64(size))
+	if size == 0 {
+		searchZeroResultCount.WithLabelValues(documentType).Inc()
+	}
+}
+
+//IncIndexingError counts a failed indexing operation (create / update) of a document type
+func IncIndexingError(documentType, operation string) {
+	indexingErrorCount.WithLabelValues(documentType, operation).Inc()
+}
+
+//PoolStats is the number of active and idle connections of a connection pool
+type PoolStats struct {
+	ActiveCount int
+	IdleCount   int
+}
+
+//PoolStatsSource gives the stats of every connection pool, by pool name
+type PoolStatsSource interface {
+	PoolStats() map[string]PoolStats
+}
+
+//poolStatsCollector collects the connection pool stats from its source on every scrape
+type poolStatsCollector struct {
+	mutex  sync.RWMutex
+	source PoolStatsSource
+
+	activeDesc *prometheus.Desc
+	idleDesc   *prometheus.Desc
+}
+
+var poolStatsCollectorObj = &poolStatsCollector{
+	activeDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis", "pool_active_connections"), "Number of active connections of a redis pool.", []string{"pool"}, nil),
+	idleDesc:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis", "pool_idle_connections"), "Number of idle connections of a redis pool.", []string{"pool"}, nil),
+}
+
+//SetPoolStatsSource sets the source of the connection pool stats
+func SetPoolStatsSource(source PoolStatsSource) {
+	poolStatsCollectorObj.mutex.Lock()
+	defer poolStatsCollectorObj.mutex.Unlock()
+	poolStatsCollectorObj.source = source
+}
+
+func (psc *poolStatsCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- psc.activeDesc
+	descs <- psc.idleDesc
+}
+
+func (psc *poolStatsCollector) Collect(metrics chan<- prometheus.Metric) {
+	psc.mutex.RLock()
+	source := psc.source
+	psc.mutex.RUnlock()
+	if source == nil {
+		return
+	}
+
+	for pool, stats := range source.PoolStats() {
+		metrics <- prometheus.MustNewConstMetric(psc.activeDesc, prometheus.GaugeValue, float64(stats.ActiveCount), pool)
+		metrics <- prometheus.MustNewConstMetric(psc.idleDesc, prometheus.GaugeValue, float64(stats.IdleCount), pool)
+	}
+}
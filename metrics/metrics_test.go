@@ -0,0 +1,55 @@
+package metrics
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockPoolStatsSource struct{}
+
+func (mpss mockPoolStatsSource) PoolStats() map[string]PoolStats {
+	return map[string]PoolStats{"primary": {ActiveCount: 3, IdleCount: 2}}
+}
+
+func TestHandler(t *testing.T) {
+	ObserveRequest("/v1/{document_type}/_search", http.MethodPost, http.StatusOK, 10*time.Millisecond)
+	ObserveRedisCommand("SMEMBERS", time.Now(), errors.New("connection refused"))
+	ObserveSearchResult("campaign", 0)
+	IncIndexingError("campaign", "create")
+	SetPoolStatsSource(mockPoolStatsSource{})
+	defer SetPoolStatsSource(nil)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `elasthink_http_requests_total{method="POST",route="/v1/{document_type}/_search",status="200"} 1`)
+	assert.Contains(t, body, `elasthink_redis_command_duration_seconds_count{command="SMEMBERS",result="error"} 1`)
+	assert.Contains(t, body, `elasthink_search_zero_results_total{document_type="campaign"} 1`)
+	assert.Contains(t, body, `elasthink_indexing_errors_total{document_type="campaign",operation="create"} 1`)
+	assert.Contains(t, body, `elasthink_redis_pool_active_connections{pool="primary"} 3`)
+	assert.Contains(t, body, `elasthink_redis_pool_idle_connections{pool="primary"} 2`)
+}
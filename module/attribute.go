@@ -0,0 +1,291 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+)
+
+const (
+	//FilterTypeTerm is the filter clause that keeps documents whose attribute has the value
+	FilterTypeTerm string = "term"
+	//FilterTypeTerms is the filter clause that keeps documents whose attribute has one of the values
+	FilterTypeTerms string = "terms"
+	//FilterTypeNot is the filter clause that removes documents whose attribute has the value (or one of the values)
+	FilterTypeNot string = "not"
+	//FilterTypeRange is the filter clause that keeps documents whose numeric attribute is within the bounds (gte or gt, lte or lt)
+	FilterTypeRange string = "range"
+)
+
+//attributeNameRegex is the format of an attribute name
+var attributeNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+//attributeValueRegex is the format of an attribute value, which must not contain whitespaces nor key pattern characters (*, ?, [, ])
+var attributeValueRegex = regexp.MustCompile(`^[^\s*?\[\]]+$`)
+
+//AttributeValues are the keyword values of a document attribute, they can be sent as a single string or an array of strings
+type AttributeValues []string
+
+//UnmarshalJSON reads the attribute values from a string or an array of strings
+func (av *AttributeValues) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*av = AttributeValues{value}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return errors.New("Attribute values must be a string or an array of strings")
+	}
+	*av = AttributeValues(values)
+	return nil
+}
+
+//FilterClause is a filter clause of a search on a document attribute.
+//Type is term (Value), terms (one of Values), not (Value or one of Values) or range (Gte or Gt, Lte or Lt on a numeric attribute)
+type FilterClause struct {
+	Type      string        `json:"type"`
+	Attribute string        `json:"attribute"`
+	Value     string        `json:"value,omitempty"`
+	Values    []string      `json:"values,omitempty"`
+	Gte       *NumericValue `json:"gte,omitempty"`
+	Gt        *NumericValue `json:"gt,omitempty"`
+	Lte       *NumericValue `json:"lte,omitempty"`
+	Lt        *NumericValue `json:"lt,omitempty"`
+}
+
+//normalizeAttributeValue lowercases and trims an attribute value
+func normalizeAttributeValue(value string) string {
+	return strings.ToLower(strings.Trim(value, " "))
+}
+
+//normalizeAttributes validates the attributes of a document and gets the values of every attribute (lowercased, without duplicates)
+func normalizeAttributes(attributes map[string]AttributeValues) (map[string]map[string]int, error) {
+	result := make(map[string]map[string]int)
+	for name, values := range attributes {
+		name = strings.ToLower(strings.Trim(name, " "))
+		if !attributeNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("Invalid attribute %s", name)
+		}
+
+		valueSet := make(map[string]int)
+		for _, value := range values {
+			value = normalizeAttributeValue(value)
+			if !attributeValueRegex.MatchString(value) {
+				return nil, fmt.Errorf("Invalid value of attribute %s", name)
+			}
+			valueSet[value] = 1
+		}
+		if len(valueSet) > 0 {
+			result[name] = valueSet
+		}
+	}
+	return result, nil
+}
+
+//documentAttributeKeys gets the attribute value keys of a document
+func documentAttributeKeys(ctx context.Context, documentType entity.DocumentType, attributes map[string]map[string]int) []string {
+	keys := make([]string, 0)
+	for name, valueSet := range attributes {
+		for value := range valueSet {
+			keys = append(keys, attributeKey(ctx, documentType, name, value))
+		}
+	}
+	return keys
+}
+
+//clauseValues gets the values of a filter clause (lowercased)
+func (fc FilterClause) clauseValues() []string {
+	values := make([]string, 0, len(fc.Values)+1)
+	if len(strings.Trim(fc.Value, " ")) > 0 {
+		values = append(values, normalizeAttributeValue(fc.Value))
+	}
+	for _, value := range fc.Values {
+		values = append(values, normalizeAttributeValue(value))
+	}
+	return values
+}
+
+//validateFilterClauses validates the filter clauses of a search
+func validateFilterClauses(filters []FilterClause) error {
+	for _, filter := range filters {
+		attribute := strings.ToLower(strings.Trim(filter.Attribute, " "))
+		if !attributeNameRegex.MatchString(attribute) {
+			return fmt.Errorf("Invalid filter attribute %s", filter.Attribute)
+		}
+
+		values := filter.clauseValues()
+		if strings.ToLower(filter.Type) == FilterTypeRange {
+			if err := validateRangeClause(filter, attribute); err != nil {
+				return err
+			}
+			continue
+		}
+		if filter.hasRangeBound() {
+			return fmt.Errorf("Filter %s on attribute %s does not take range bounds", strings.ToLower(filter.Type), attribute)
+		}
+
+		switch strings.ToLower(filter.Type) {
+		case FilterTypeTerm:
+			if len(strings.Trim(filter.Value, " ")) == 0 || len(filter.Values) > 0 {
+				return fmt.Errorf("Filter %s on attribute %s requires a single value", FilterTypeTerm, attribute)
+			}
+		case FilterTypeTerms, FilterTypeNot:
+			if len(values) == 0 {
+				return fmt.Errorf("Filter %s on attribute %s requires values", strings.ToLower(filter.Type), attribute)
+			}
+		default:
+			return fmt.Errorf("Invalid filter type %s", filter.Type)
+		}
+
+		for _, value := range values {
+			if !attributeValueRegex.MatchString(value) {
+				return fmt.Errorf("Invalid value of filter attribute %s", attribute)
+			}
+		}
+	}
+	return nil
+}
+
+//documentFilter is the result of the filter clauses of a search: the documents kept by every term, terms and range clause (when there is one) and the documents removed by not clauses or by their expiry
+type documentFilter struct {
+	isRestricted bool
+	keptIDs      map[string]int
+	removedIDs   map[string]int
+}
+
+//fetchDocumentFilter fetches the document ids of every filter clause value.
+//Unlike the words of a search, a value that fails to be fetched fails the search
+func fetchDocumentFilter(ctx context.Context, documentType entity.DocumentType, filters []FilterClause) (documentFilter, error) {
+	result := documentFilter{removedIDs: make(map[string]int)}
+
+	for _, filter := range filters {
+		attribute := strings.ToLower(strings.Trim(filter.Attribute, " "))
+
+		clauseIDs, err := fetchClauseDocumentIDs(ctx, documentType, attribute, filter)
+		if err != nil {
+			return result, err
+		}
+
+		if strings.ToLower(filter.Type) == FilterTypeNot {
+			for documentID := range clauseIDs {
+				result.removedIDs[documentID] = 1
+			}
+			continue
+		}
+
+		//intersection of the document ids of every term, terms and range clause
+		if !result.isRestricted {
+			result.isRestricted = true
+			result.keptIDs = clauseIDs
+			continue
+		}
+		for documentID := range result.keptIDs {
+			if _, ok := clauseIDs[documentID]; !ok {
+				delete(result.keptIDs, documentID)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+//fetchClauseDocumentIDs fetches the document ids of a filter clause: the documents within the range of a range clause, or the union of the documents of the values of other clauses
+func fetchClauseDocumentIDs(ctx context.Context, documentType entity.DocumentType, attribute string, filter FilterClause) (map[string]int, error) {
+	clauseIDs := make(map[string]int)
+	if strings.ToLower(filter.Type) == FilterTypeRange {
+		members, err := fetchRangeDocumentIDs(ctx, documentType, attribute, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, documentID := range members {
+			clauseIDs[documentID] = 1
+		}
+		return clauseIDs, nil
+	}
+
+	for _, value := range filter.clauseValues() {
+		members, err := readStorage(ctx).SMembers(ctx, attributeKey(ctx, documentType, attribute, value))
+		if err != nil {
+			return nil, err
+		}
+		for _, documentID := range members {
+			clauseIDs[documentID] = 1
+		}
+	}
+	return clauseIDs, nil
+}
+
+//isKept tells whether a document passes the filter
+func (df documentFilter) isKept(documentID string) bool {
+	if _, ok := df.removedIDs[documentID]; ok {
+		return false
+	}
+	if !df.isRestricted {
+		return true
+	}
+	_, ok := df.keptIDs[documentID]
+	return ok
+}
+
+//removeExpiredDocuments removes the matched documents that have expired at the given time (even before they are swept).
+//Only the expiry of the matched documents that pass the filter is fetched, so the cost does not grow with the documents waiting for the sweeper
+func (df documentFilter) removeExpiredDocuments(ctx context.Context, documentType entity.DocumentType, wordMatches map[string]map[string][]entity.FieldMatch, now time.Time) error {
+	candidateSet := make(map[string]int)
+	candidateIDs := make([]string, 0)
+	for _, matches := range wordMatches {
+		for documentID := range matches {
+			if _, ok := candidateSet[documentID]; ok || !df.isKept(documentID) {
+				continue
+			}
+			candidateSet[documentID] = 1
+			candidateIDs = append(candidateIDs, documentID)
+		}
+	}
+
+	expiresAtByID, err := fetchDocumentExpiries(ctx, documentType, candidateIDs)
+	if err != nil {
+		return err
+	}
+	for documentID, expiresAt := range expiresAtByID {
+		if expiresAt <= float64(now.Unix()) {
+			df.removedIDs[documentID] = 1
+		}
+	}
+	return nil
+}
+
+//apply removes the documents that do not pass the filter from the word scores
+func (df documentFilter) apply(wordScores map[string]map[string]float64) {
+	for _, scores := range wordScores {
+		for documentID := range scores {
+			if !df.isKept(documentID) {
+				delete(scores, documentID)
+			}
+		}
+	}
+}
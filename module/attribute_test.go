@@ -0,0 +1,152 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeValuesUnmarshalJSON(t *testing.T) {
+	var payload CreateIndexRequestPayload
+	err := json.Unmarshal([]byte(`{"documentName": "Diskon Kopi", "attributes": {"status": "active", "city": ["jakarta", "bandung"]}}`), &payload)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]AttributeValues{
+		"status": {"active"},
+		"city":   {"jakarta", "bandung"},
+	}, payload.Attributes)
+
+	err = json.Unmarshal([]byte(`{"attributes": {"status": 1}}`), &payload)
+	assert.NotNil(t, err)
+}
+
+func TestValidateFilterClauses(t *testing.T) {
+	type tcase struct {
+		filters       []FilterClause
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["valid filters"] = tcase{
+		filters: []FilterClause{
+			{Type: "term", Attribute: "status", Value: "active"},
+			{Type: "terms", Attribute: "city", Values: []string{"jakarta", "bandung"}},
+			{Type: "not", Attribute: "category", Value: "food"},
+		},
+	}
+	testCases["term without value"] = tcase{
+		filters:       []FilterClause{{Type: "term", Attribute: "status"}},
+		expectedError: true,
+	}
+	testCases["terms without values"] = tcase{
+		filters:       []FilterClause{{Type: "terms", Attribute: "city"}},
+		expectedError: true,
+	}
+	testCases["invalid type"] = tcase{
+		filters:       []FilterClause{{Type: "between", Attribute: "status", Value: "active"}},
+		expectedError: true,
+	}
+	testCases["invalid value"] = tcase{
+		filters:       []FilterClause{{Type: "term", Attribute: "status", Value: "act*"}},
+		expectedError: true,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on validateFilterClauses with test case:", ktc)
+		err := validateFilterClauses(vtc.filters)
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestSearchWithFilters(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	documents := map[string]map[string]AttributeValues{
+		"1": {"status": {"active"}, "city": {"jakarta"}, "category": {"food"}},
+		"2": {"status": {"active"}, "city": {"bandung"}},
+		"3": {"status": {"inactive"}, "city": {"jakarta"}},
+	}
+	for documentID, attributes := range documents {
+		response := CreateIndex(ctx, documentID, "campaign", CreateIndexRequestPayload{DocumentName: "Diskon Kopi", Attributes: attributes})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+
+	type tcase struct {
+		filters     []FilterClause
+		expectedIDs []string
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["no filter"] = tcase{expectedIDs: []string{"1", "2", "3"}}
+	testCases["term filter"] = tcase{
+		filters:     []FilterClause{{Type: "term", Attribute: "status", Value: "Active"}},
+		expectedIDs: []string{"1", "2"},
+	}
+	testCases["term and terms filters"] = tcase{
+		filters: []FilterClause{
+			{Type: "term", Attribute: "status", Value: "active"},
+			{Type: "terms", Attribute: "city", Values: []string{"jakarta", "surabaya"}},
+		},
+		expectedIDs: []string{"1"},
+	}
+	testCases["not filter only"] = tcase{
+		filters:     []FilterClause{{Type: "not", Attribute: "category", Value: "food"}},
+		expectedIDs: []string{"2", "3"},
+	}
+	testCases["no document passes"] = tcase{
+		filters:     []FilterClause{{Type: "term", Attribute: "city", Value: "surabaya"}},
+		expectedIDs: []string{},
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on Search with filters with test case:", ktc)
+		response := Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi", Filters: vtc.filters})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		actualIDs := make([]string, 0)
+		for _, result := range response.Data.(SearchResponsePayload).RankedResultList {
+			actualIDs = append(actualIDs, result.ID.String())
+		}
+		assert.Equal(t, vtc.expectedIDs, actualIDs)
+	}
+
+	//the document is moved to the sets of its new attribute values
+	response := UpdateIndex(ctx, "3", "campaign", UpdateIndexRequestPayload{
+		OldDocumentName: "Diskon Kopi",
+		NewDocumentName: "Diskon Kopi",
+		OldAttributes:   map[string]AttributeValues{"status": {"inactive"}, "city": {"jakarta"}},
+		NewAttributes:   map[string]AttributeValues{"status": {"active"}, "city": {"jakarta"}},
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi", Filters: []FilterClause{{Type: "term", Attribute: "status", Value: "active"}}})
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(1), ShowCount: 1, Score: 1, Rank: 1},
+		{ID: entity.Int64DocumentID(2), ShowCount: 1, Score: 1, Rank: 2},
+		{ID: entity.Int64DocumentID(3), ShowCount: 1, Score: 1, Rank: 3},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+}
@@ -0,0 +1,46 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+
+	"github.com/SurgicalSteel/elasthink/storage"
+)
+
+type contextKey string
+
+const primaryReadContextKey contextKey = "primaryRead"
+
+//WithPrimaryRead returns a copy of ctx which forces every read of the request to the primary storage (instead of the read replicas)
+func WithPrimaryRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadContextKey, true)
+}
+
+func isForcingPrimaryRead(ctx context.Context) bool {
+	isForcing, ok := ctx.Value(primaryReadContextKey).(bool)
+	return ok && isForcing
+}
+
+//readStorage is the storage used for reads of a request, the read replicas are used unless the request forces reading from the primary
+func readStorage(ctx context.Context) storage.Storage {
+	if isForcingPrimaryRead(ctx) {
+		return storage.Primary(moduleObj.Storage)
+	}
+	return moduleObj.Storage
+}
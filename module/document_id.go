@@ -0,0 +1,63 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+)
+
+//buildDocumentIDTypes builds the id type of every document type that has a configured id type
+func buildDocumentIDTypes(documentConfig config.DocumentConfigWrap) (map[entity.DocumentType]entity.DocumentIDType, error) {
+	idTypesByDocumentType := make(map[entity.DocumentType]entity.DocumentIDType)
+
+	for name, vd := range documentConfig.DocumentType {
+		if vd == nil || vd.IDType == "" {
+			continue
+		}
+		documentType := entity.DocumentType(strings.ToLower(strings.Trim(name, " ")))
+		if err := documentType.IsValidFromCustomDocumentType(entity.Entity.GetDocumentTypes()); err != nil {
+			return nil, fmt.Errorf("ID type of an invalid document type %s", name)
+		}
+
+		idType, err := entity.GetDocumentIDType(vd.IDType)
+		if err != nil {
+			return nil, fmt.Errorf("Document type %s has an invalid id type %s", name, vd.IDType)
+		}
+		idTypesByDocumentType[documentType] = idType
+	}
+
+	return idTypesByDocumentType, nil
+}
+
+//documentIDType gets the id type of a document type, which is int64 when the document type has no configured id type
+func documentIDType(ctx context.Context, documentType entity.DocumentType) entity.DocumentIDType {
+	if idType, ok := currentSettings(ctx).DocumentIDTypes[documentType]; ok {
+		return idType
+	}
+	return entity.DocumentIDTypeInt64
+}
+
+//parseDocumentID validates a raw document id with the id type of its document type
+func parseDocumentID(ctx context.Context, documentType entity.DocumentType, rawDocumentID string) (entity.DocumentID, error) {
+	return entity.ParseDocumentID(strings.Trim(rawDocumentID, " "), documentIDType(ctx, documentType))
+}
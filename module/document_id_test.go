@@ -0,0 +1,115 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDocumentIDTypes(t *testing.T) {
+	entity.Entity.Initialize(entity.StopwordData{})
+
+	type tcase struct {
+		idType        string
+		expected      map[entity.DocumentType]entity.DocumentIDType
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["string id type"] = tcase{idType: "String", expected: map[entity.DocumentType]entity.DocumentIDType{"campaign": entity.DocumentIDTypeString}}
+	testCases["default id type"] = tcase{idType: "", expected: map[entity.DocumentType]entity.DocumentIDType{}}
+	testCases["invalid id type"] = tcase{idType: "uuid", expectedError: true}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on buildDocumentIDTypes with test case:", ktc)
+		actual, err := buildDocumentIDTypes(config.DocumentConfigWrap{
+			DocumentType: map[string]*config.DocumentTypeConfig{
+				"campaign": {IDType: vtc.idType},
+			},
+		})
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, vtc.expected, actual)
+	}
+}
+
+func TestIndexWithDocumentIDTypes(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	err := InitDocumentFields(config.DocumentConfigWrap{
+		DocumentType: map[string]*config.DocumentTypeConfig{
+			"advcampaign": {IDType: "string"},
+		},
+	})
+	assert.Nil(t, err)
+
+	type tcase struct {
+		documentType       string
+		documentID         string
+		expectedStatusCode int
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["int64 id"] = tcase{documentType: "campaign", documentID: "12", expectedStatusCode: http.StatusOK}
+	testCases["non numeric int64 id"] = tcase{documentType: "campaign", documentID: "promo-kopi", expectedStatusCode: http.StatusBadRequest}
+	testCases["zero int64 id"] = tcase{documentType: "campaign", documentID: "0", expectedStatusCode: http.StatusBadRequest}
+	testCases["uuid string id"] = tcase{documentType: "advcampaign", documentID: "8f14e45f-ceea-4e1f-9b3a-2b0c5d1f6a7e", expectedStatusCode: http.StatusOK}
+	testCases["slug string id"] = tcase{documentType: "advcampaign", documentID: "promo-kopi", expectedStatusCode: http.StatusOK}
+	testCases["string id with whitespace"] = tcase{documentType: "advcampaign", documentID: "promo kopi", expectedStatusCode: http.StatusBadRequest}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on CreateIndex with test case:", ktc)
+		response := CreateIndex(ctx, vtc.documentID, vtc.documentType, CreateIndexRequestPayload{DocumentName: "Diskon Kopi"})
+		assert.Equal(t, vtc.expectedStatusCode, response.StatusCode)
+	}
+
+	//a corrupted member is skipped instead of being returned as a zero id
+	_, err = moduleObj.Storage.SAdd(ctx, invertedIndexKey(ctx, "campaign", "kopi"), []interface{}{"corrupted"})
+	assert.Nil(t, err)
+
+	response := Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(12), ShowCount: 1, Score: 1, Rank: 1},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+
+	response = Search(ctx, "advcampaign", SearchRequestPayload{SearchTerm: "kopi"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	rankedResultList := response.Data.(SearchResponsePayload).RankedResultList
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.StringDocumentID("8f14e45f-ceea-4e1f-9b3a-2b0c5d1f6a7e"), ShowCount: 1, Score: 1, Rank: 1},
+		{ID: entity.StringDocumentID("promo-kopi"), ShowCount: 1, Score: 1, Rank: 2},
+	}, rankedResultList)
+
+	//string ids are returned as strings
+	resultJSON, err := json.Marshal(rankedResultList[1])
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":"promo-kopi","showCount":1,"score":1,"rank":2}`, string(resultJSON))
+}
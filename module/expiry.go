@@ -0,0 +1,192 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/storage"
+)
+
+//validateExpiresAt validates the expiry time of a document and gets it in unix seconds (0 when the document does not expire)
+func validateExpiresAt(expiresAt *NumericValue) (int64, error) {
+	if expiresAt == nil {
+		return 0, nil
+	}
+	if *expiresAt <= 0 {
+		return 0, errors.New("Invalid Expiry Time")
+	}
+	return int64(*expiresAt), nil
+}
+
+//storeDocument keeps the document in the normal index and schedules its expiry (or cancels it when the document does not expire anymore). Returns the key that fails to be written
+func storeDocument(ctx context.Context, documentType entity.DocumentType, documentID entity.DocumentID, document entity.StoredDocument) (string, error) {
+	key := normalIndexKey(ctx, documentType, documentID.String())
+	value, err := json.Marshal(document)
+	if err != nil {
+		return key, err
+	}
+	err = moduleObj.Storage.Set(ctx, key, string(value))
+	if err != nil {
+		return key, err
+	}
+
+	key = expiryKey(ctx, documentType)
+	member := documentID.String()
+	if document.ExpiresAt > 0 {
+		_, err = moduleObj.Storage.ZAdd(ctx, key, float64(document.ExpiresAt), member)
+	} else {
+		_, err = moduleObj.Storage.ZRem(ctx, key, []interface{}{member})
+	}
+	if err != nil {
+		return key, err
+	}
+	return "", nil
+}
+
+//fetchExpiredDocumentIDs fetches the ids (as they are kept in the index) of the documents of a document type that have expired at the given time
+func fetchExpiredDocumentIDs(ctx context.Context, documentType entity.DocumentType, now time.Time) ([]string, error) {
+	return readStorage(ctx).ZRangeByScore(ctx, expiryKey(ctx, documentType), "-inf", strconv.FormatInt(now.Unix(), 10))
+}
+
+//fetchDocumentExpiries fetches the expiry time (in unix seconds) of the given documents that expire
+func fetchDocumentExpiries(ctx context.Context, documentType entity.DocumentType, documentIDs []string) (map[string]float64, error) {
+	return readStorage(ctx).ZScores(ctx, expiryKey(ctx, documentType), documentIDs)
+}
+
+//sweepDocument removes an expired document from every key it is indexed in, then removes the stored document and its expiry.
+//A document whose expiry has been moved after the given time is kept, the stored document is read from the primary storage so a replica lag cannot hide the move. Returns whether the document is removed
+func sweepDocument(ctx context.Context, documentType entity.DocumentType, documentID string, now time.Time) (bool, error) {
+	documentKey := normalIndexKey(ctx, documentType, documentID)
+	value, err := storage.Primary(moduleObj.Storage).Get(ctx, documentKey)
+	if err != nil {
+		return false, err
+	}
+
+	member := []interface{}{documentID}
+	if len(value) > 0 {
+		var document entity.StoredDocument
+		if err := json.Unmarshal([]byte(value), &document); err != nil {
+			return false, fmt.Errorf("Invalid stored document %s", documentKey)
+		}
+		if document.ExpiresAt == 0 || document.ExpiresAt > now.Unix() {
+			return false, nil
+		}
+
+		removedAttributes := make(map[string]map[string]int)
+		for _, key := range document.Keys {
+			if _, err := moduleObj.Storage.SRem(ctx, key, member); err != nil {
+				return false, err
+			}
+			if attribute, value, ok := parseAttributeKey(ctx, documentType, key); ok {
+				if _, ok := removedAttributes[attribute]; !ok {
+					removedAttributes[attribute] = make(map[string]int)
+				}
+				removedAttributes[attribute][value] = 1
+			}
+		}
+		if _, err := pruneAttributeValues(ctx, documentType, removedAttributes); err != nil {
+			return false, err
+		}
+		for _, key := range document.RangeKeys {
+			if _, err := moduleObj.Storage.ZRem(ctx, key, member); err != nil {
+				return false, err
+			}
+		}
+		if _, err := moduleObj.Storage.Del(ctx, documentKey); err != nil {
+			return false, err
+		}
+	}
+
+	//the expiry is removed last, so a document that fails to be removed is swept again
+	if _, err := moduleObj.Storage.ZRem(ctx, expiryKey(ctx, documentType), member); err != nil {
+		return false, err
+	}
+	return len(value) > 0, nil
+}
+
+//sweepContexts gets a context for each tenant when tenants are used (the documents of every tenant are swept), or the given context otherwise
+func sweepContexts(ctx context.Context) []context.Context {
+	tenantsByAPIKey := currentSettings(ctx).TenantsByAPIKey
+	if len(tenantsByAPIKey) == 0 {
+		return []context.Context{ctx}
+	}
+
+	//several API keys can belong to the same tenant
+	tenantsByName := make(map[string]entity.Tenant)
+	for _, tenant := range tenantsByAPIKey {
+		tenantsByName[tenant.Name] = tenant
+	}
+	names := make([]string, 0, len(tenantsByName))
+	for name := range tenantsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]context.Context, len(names))
+	for i, name := range names {
+		result[i] = WithTenant(ctx, tenantsByName[name])
+	}
+	return result
+}
+
+//SweepExpiredDocuments removes every document that has expired at the given time from every posting and from the normal index, for every document type (of every tenant when tenants are used).
+//A document that fails to be removed is logged and swept again on the next run. Returns the number of removed documents and the last error
+func SweepExpiredDocuments(ctx context.Context, now time.Time) (int, error) {
+	removed := 0
+	var lastErr error
+
+	for _, sweepCtx := range sweepContexts(WithPrimaryRead(ctx)) {
+		for documentType := range documentTypes(sweepCtx) {
+			documentIDs, err := fetchExpiredDocumentIDs(sweepCtx, documentType, now)
+			if err != nil {
+				if isContextError(err) {
+					return removed, err
+				}
+				moduleLogger.Error(sweepCtx, "Failed to fetch expired documents", logger.Fields{"document_type": documentType, "error": err})
+				lastErr = err
+				continue
+			}
+
+			for _, documentID := range documentIDs {
+				isRemoved, err := sweepDocument(sweepCtx, documentType, documentID, now)
+				if err != nil {
+					if isContextError(err) {
+						return removed, err
+					}
+					moduleLogger.Error(sweepCtx, "Failed to remove expired document", logger.Fields{"document_type": documentType, "document_id": documentID, "error": err})
+					lastErr = err
+					continue
+				}
+				if isRemoved {
+					removed++
+				}
+			}
+		}
+	}
+
+	return removed, lastErr
+}
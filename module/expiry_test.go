@@ -0,0 +1,147 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExpiresAt(t *testing.T) {
+	expiresAt, err := validateExpiresAt(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), expiresAt)
+
+	var payload CreateIndexRequestPayload
+	err = json.Unmarshal([]byte(`{"documentName": "Diskon Kopi", "expiresAt": "2020-01-02"}`), &payload)
+	assert.Nil(t, err)
+	expiresAt, err = validateExpiresAt(payload.ExpiresAt)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1577923200), expiresAt)
+
+	invalid := NumericValue(-1)
+	_, err = validateExpiresAt(&invalid)
+	assert.NotNil(t, err)
+}
+
+func TestDocumentExpiry(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	expired := NumericValue(now.Add(-time.Minute).Unix())
+	expiring := NumericValue(now.Add(time.Hour).Unix())
+
+	response := CreateIndex(ctx, "1", "campaign", CreateIndexRequestPayload{
+		DocumentName:      "Diskon Kopi",
+		Attributes:        map[string]AttributeValues{"city": {"jakarta"}},
+		NumericAttributes: map[string]NumericValue{"price": 50000},
+		ExpiresAt:         &expired,
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = CreateIndex(ctx, "2", "campaign", CreateIndexRequestPayload{DocumentName: "Diskon Kopi Susu", ExpiresAt: &expiring})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = CreateIndex(ctx, "3", "campaign", CreateIndexRequestPayload{DocumentName: "Kopi Gratis"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	value, _ := moduleObj.Storage.Get(ctx, normalIndexKey(ctx, "campaign", "1"))
+	var document entity.StoredDocument
+	assert.Nil(t, json.Unmarshal([]byte(value), &document))
+	assert.Equal(t, map[string]string{"name": "Diskon Kopi"}, document.Fields)
+	assert.ElementsMatch(t, []string{
+		"elasthink:inverted:campaign:diskon",
+		"elasthink:inverted:campaign:kopi",
+		"elasthink:field:campaign:name:diskon",
+		"elasthink:field:campaign:name:kopi",
+		"elasthink:attribute:campaign:city:jakarta",
+	}, document.Keys)
+	assert.Equal(t, []string{"elasthink:range:campaign:price"}, document.RangeKeys)
+	assert.Equal(t, int64(expired), document.ExpiresAt)
+
+	//an expired document is not found even before it is swept
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi"})
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(2), ShowCount: 1, Score: 1, Rank: 1},
+		{ID: entity.Int64DocumentID(3), ShowCount: 1, Score: 1, Rank: 2},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+
+	//only the expiry of the matched documents is checked
+	filter := documentFilter{removedIDs: make(map[string]int)}
+	err := filter.removeExpiredDocuments(ctx, "campaign", map[string]map[string][]entity.FieldMatch{"gratis": {"3": nil}}, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(filter.removedIDs))
+	err = filter.removeExpiredDocuments(ctx, "campaign", map[string]map[string][]entity.FieldMatch{"kopi": {"1": nil, "2": nil, "3": nil}}, now)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"1": 1}, filter.removedIDs)
+
+	//updating the document without expiry cancels its expiry
+	response = UpdateIndex(ctx, "2", "campaign", UpdateIndexRequestPayload{OldDocumentName: "Diskon Kopi Susu", NewDocumentName: "Diskon Kopi Susu"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	expiringIDs, _ := moduleObj.Storage.ZMembersWithScores(ctx, expiryKey(ctx, "campaign"))
+	assert.Equal(t, map[string]float64{"1": float64(expired)}, expiringIDs)
+
+	//the sweeper removes the expired document from every posting and from the normal index
+	removed, err := SweepExpiredDocuments(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+
+	for _, key := range document.Keys {
+		members, _ := moduleObj.Storage.SMembers(ctx, key)
+		assert.NotContains(t, members, "1")
+	}
+	prices, _ := moduleObj.Storage.ZMembersWithScores(ctx, "elasthink:range:campaign:price")
+	assert.Equal(t, 0, len(prices))
+	cities, _ := moduleObj.Storage.SMembers(ctx, attributeValuesKey(ctx, "campaign", "city"))
+	assert.Equal(t, 0, len(cities))
+	value, _ = moduleObj.Storage.Get(ctx, normalIndexKey(ctx, "campaign", "1"))
+	assert.Equal(t, "", value)
+	expiringIDs, _ = moduleObj.Storage.ZMembersWithScores(ctx, expiryKey(ctx, "campaign"))
+	assert.Equal(t, 0, len(expiringIDs))
+
+	removed, err = SweepExpiredDocuments(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestSweepContexts(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	assert.Equal(t, 1, len(sweepContexts(ctx)))
+
+	err := InitTenants(config.TenantConfigWrap{
+		Tenant: map[string]*config.TenantConfig{
+			"payment": {APIKey: []string{"key-a", "key-b"}, DocumentType: []string{"campaign"}},
+			"ads":     {APIKey: []string{"key-c"}, DocumentType: []string{"advcampaign"}, Namespace: "ads"},
+		},
+	})
+	assert.Nil(t, err)
+
+	//one context for each tenant (not for each API key)
+	contexts := sweepContexts(ctx)
+	assert.Equal(t, 2, len(contexts))
+	assert.Equal(t, "ads:expiry:advcampaign", expiryKey(contexts[0], "advcampaign"))
+	assert.Equal(t, "elasthink:payment:expiry:campaign", expiryKey(contexts[1], "campaign"))
+}
@@ -0,0 +1,184 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+)
+
+const (
+	//ExplainReasonNoSearchWord is the reason of an unmatched document when every word of the search term is a stopword
+	ExplainReasonNoSearchWord string = "Search Term has no word to search"
+	//ExplainReasonNoMatchedWord is the reason of an unmatched document when no search word is found in its searched fields
+	ExplainReasonNoMatchedWord string = "No search word is found in the document"
+	//ExplainReasonFilteredOut is the reason of an unmatched document when a search word is found in it, but it does not pass the filter clauses or it has expired
+	ExplainReasonFilteredOut string = "Document is filtered out by the filter clauses or it has expired"
+)
+
+//ExplainResponsePayload is the response payload of explain. Reason is why the document is not matched by the search (when IsMatched is false),
+//Explanation is how the search words match the document and how it is scored and ranked (its rank is 0 when it is filtered out, and it is nil when no search word is found)
+type ExplainResponsePayload struct {
+	ID          entity.DocumentID               `json:"id"`
+	IsMatched   bool                            `json:"isMatched"`
+	Reason      string                          `json:"reason,omitempty"`
+	Explanation *entity.SearchResultExplanation `json:"explanation,omitempty"`
+}
+
+//Explain is the core function of explaining why a document is matched (or not) by a search, and how it is scored and ranked among the other matched documents.
+//The search is done like Search, but its facets, documents and highlights are not fetched
+func Explain(ctx context.Context, documentType string, rawDocumentID string, requestPayload SearchRequestPayload) Response {
+	query, err := prepareSearch(ctx, documentType, requestPayload)
+	if err != nil {
+		return Response{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+			Data:         nil,
+		}
+	}
+	documentID, err := parseDocumentID(ctx, query.docType, rawDocumentID)
+	if err != nil {
+		return Response{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+			Data:         nil,
+		}
+	}
+
+	explainResponsePayload := ExplainResponsePayload{ID: documentID}
+	if len(query.searchTermSet) == 0 {
+		explainResponsePayload.Reason = ExplainReasonNoSearchWord
+		return Response{
+			StatusCode:   http.StatusOK,
+			ErrorMessage: "",
+			Data:         explainResponsePayload,
+		}
+	}
+
+	wordMatches, filter, err := matchSearch(ctx, query, requestPayload.Filters)
+	if err != nil {
+		return matchErrorResponse(ctx, query.docType, err)
+	}
+	wordScores := wordScoresOf(wordMatches)
+	filter.apply(wordScores)
+	rankedSearchResult := rankSearchResult(ctx, wordScores, documentIDType(ctx, query.docType))
+
+	rank := 0
+	for _, rankData := range rankedSearchResult {
+		if rankData.ID.String() == documentID.String() {
+			rank = rankData.Rank
+			break
+		}
+	}
+
+	explanation := explainDocument(query.searchTermSet, wordMatches, wordScores, documentID.String(), rank, len(rankedSearchResult))
+	switch {
+	case explanation.ShowCount == 0:
+		explainResponsePayload.Reason = ExplainReasonNoMatchedWord
+	case rank == 0:
+		explainResponsePayload.Reason = ExplainReasonFilteredOut
+		explainResponsePayload.Explanation = explanation
+	default:
+		explainResponsePayload.IsMatched = true
+		explainResponsePayload.Explanation = explanation
+	}
+
+	return Response{
+		StatusCode:   http.StatusOK,
+		ErrorMessage: "",
+		Data:         explainResponsePayload,
+	}
+}
+
+//explainSearchResult puts the explanation of every ranked document into its result
+func explainSearchResult(rankedSearchResult []entity.SearchResultRankData, searchTermSet map[string]int, wordMatches map[string]map[string][]entity.FieldMatch, wordScores map[string]map[string]float64) {
+	for i, rankData := range rankedSearchResult {
+		rankedSearchResult[i].Explanation = explainDocument(searchTermSet, wordMatches, wordScores, rankData.ID.String(), rankData.Rank, len(rankedSearchResult))
+	}
+}
+
+//explainDocument explains how the search words match a document (with the fields where they are found, before the filter) and how it is scored. The document frequency of a word is counted over the filtered word scores.
+//Rank is the rank of the document among the totalMatched ranked documents (0 when it is not ranked)
+func explainDocument(searchTermSet map[string]int, wordMatches map[string]map[string][]entity.FieldMatch, wordScores map[string]map[string]float64, documentID string, rank, totalMatched int) *entity.SearchResultExplanation {
+	terms := make([]string, 0, len(searchTermSet))
+	for term := range searchTermSet {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	explanation := &entity.SearchResultExplanation{
+		MatchedTerms:   make([]entity.TermExplanation, 0),
+		UnmatchedTerms: make([]string, 0),
+		Rank:           rank,
+		TotalMatched:   totalMatched,
+	}
+	for _, term := range terms {
+		fieldMatches, ok := wordMatches[term][documentID]
+		if !ok {
+			explanation.UnmatchedTerms = append(explanation.UnmatchedTerms, term)
+			continue
+		}
+
+		termExplanation := entity.TermExplanation{
+			Term:              term,
+			DocumentFrequency: len(wordScores[term]),
+			Fields:            fieldMatches,
+		}
+		for _, fieldMatch := range fieldMatches {
+			termExplanation.Score += fieldMatch.Boost
+		}
+		explanation.MatchedTerms = append(explanation.MatchedTerms, termExplanation)
+		explanation.Score += termExplanation.Score
+		explanation.ShowCount++
+	}
+
+	explanation.Description = describeExplanation(explanation)
+	return explanation
+}
+
+//describeExplanation writes the score and rank breakdown of an explanation in words, e.g. score 4 = kopi (name 3 + description 1); rank 1 of 2 by score (descending), then show count (descending), then id (ascending)
+func describeExplanation(explanation *entity.SearchResultExplanation) string {
+	termDescriptions := make([]string, len(explanation.MatchedTerms))
+	for i, termExplanation := range explanation.MatchedTerms {
+		fieldDescriptions := make([]string, len(termExplanation.Fields))
+		for j, fieldMatch := range termExplanation.Fields {
+			fieldDescriptions[j] = fmt.Sprintf("%s %s", fieldMatch.Field, formatScore(fieldMatch.Boost))
+		}
+		termDescriptions[i] = fmt.Sprintf("%s (%s)", termExplanation.Term, strings.Join(fieldDescriptions, " + "))
+	}
+
+	description := fmt.Sprintf("score %s = %s", formatScore(explanation.Score), strings.Join(termDescriptions, " + "))
+	if len(termDescriptions) == 0 {
+		description = "score 0, no search word is found"
+	}
+	if explanation.Rank == 0 {
+		return description + "; not ranked"
+	}
+	return fmt.Sprintf("%s; rank %d of %d by score (descending), then show count (descending), then id (ascending)", description, explanation.Rank, explanation.TotalMatched)
+}
+
+//formatScore formats a score or a boost without trailing zeroes
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
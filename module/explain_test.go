@@ -0,0 +1,136 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchWithExplanation(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	err := InitDocumentFields(config.DocumentConfigWrap{
+		DocumentType: map[string]*config.DocumentTypeConfig{
+			"campaign": {Field: []string{"name:3", "description"}},
+		},
+	})
+	assert.Nil(t, err)
+
+	response := CreateIndex(ctx, "1", "campaign", CreateIndexRequestPayload{Fields: map[string]string{"name": "Diskon Kopi", "description": "kopi murah"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = CreateIndex(ctx, "2", "campaign", CreateIndexRequestPayload{Fields: map[string]string{"name": "Cashback Pulsa", "description": "diskon pulsa"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "diskon kopi", Explain: true})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	rankedResultList := response.Data.(SearchResponsePayload).RankedResultList
+	assert.Equal(t, 2, len(rankedResultList))
+	assert.Equal(t, &entity.SearchResultExplanation{
+		MatchedTerms: []entity.TermExplanation{
+			{Term: "diskon", DocumentFrequency: 2, Fields: []entity.FieldMatch{{Field: "name", Boost: 3}}, Score: 3},
+			{Term: "kopi", DocumentFrequency: 1, Fields: []entity.FieldMatch{{Field: "name", Boost: 3}, {Field: "description", Boost: 1}}, Score: 4},
+		},
+		UnmatchedTerms: []string{},
+		Score:          7,
+		ShowCount:      2,
+		Rank:           1,
+		TotalMatched:   2,
+		Description:    "score 7 = diskon (name 3) + kopi (name 3 + description 1); rank 1 of 2 by score (descending), then show count (descending), then id (ascending)",
+	}, rankedResultList[0].Explanation)
+	assert.Equal(t, []string{"kopi"}, rankedResultList[1].Explanation.UnmatchedTerms)
+	assert.Equal(t, 1.0, rankedResultList[1].Explanation.Score)
+
+	//explanations are only returned when they are requested
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "diskon kopi"})
+	assert.Nil(t, response.Data.(SearchResponsePayload).RankedResultList[0].Explanation)
+}
+
+func TestExplain(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	response := CreateIndex(ctx, "1", "campaign", CreateIndexRequestPayload{DocumentName: "Diskon Kopi", Attributes: map[string]AttributeValues{"city": {"jakarta"}}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = CreateIndex(ctx, "2", "campaign", CreateIndexRequestPayload{DocumentName: "Diskon Pulsa", Attributes: map[string]AttributeValues{"city": {"bandung"}}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	type tcase struct {
+		documentID         string
+		requestPayload     SearchRequestPayload
+		expectedStatusCode int
+		expectedIsMatched  bool
+		expectedReason     string
+		expectedRank       int
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["matched document"] = tcase{
+		documentID:         "2",
+		requestPayload:     SearchRequestPayload{SearchTerm: "diskon"},
+		expectedStatusCode: http.StatusOK,
+		expectedIsMatched:  true,
+		expectedRank:       2,
+	}
+	testCases["no matched word"] = tcase{
+		documentID:         "2",
+		requestPayload:     SearchRequestPayload{SearchTerm: "kopi"},
+		expectedStatusCode: http.StatusOK,
+		expectedReason:     ExplainReasonNoMatchedWord,
+	}
+	testCases["filtered out document"] = tcase{
+		documentID:         "2",
+		requestPayload:     SearchRequestPayload{SearchTerm: "diskon", Filters: []FilterClause{{Attribute: "city", Type: FilterTypeTerm, Value: "jakarta"}}},
+		expectedStatusCode: http.StatusOK,
+		expectedReason:     ExplainReasonFilteredOut,
+	}
+	testCases["stopwords only"] = tcase{
+		documentID:         "1",
+		requestPayload:     SearchRequestPayload{SearchTerm: "yang"},
+		expectedStatusCode: http.StatusOK,
+		expectedReason:     ExplainReasonNoSearchWord,
+	}
+	testCases["invalid document id"] = tcase{
+		documentID:         "abc",
+		requestPayload:     SearchRequestPayload{SearchTerm: "diskon"},
+		expectedStatusCode: http.StatusBadRequest,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on Explain with test case:", ktc)
+		response := Explain(ctx, "campaign", vtc.documentID, vtc.requestPayload)
+		assert.Equal(t, vtc.expectedStatusCode, response.StatusCode)
+		if vtc.expectedStatusCode != http.StatusOK {
+			continue
+		}
+
+		explainResponsePayload := response.Data.(ExplainResponsePayload)
+		assert.Equal(t, vtc.expectedIsMatched, explainResponsePayload.IsMatched)
+		assert.Equal(t, vtc.expectedReason, explainResponsePayload.Reason)
+		if explainResponsePayload.Explanation != nil {
+			assert.Equal(t, vtc.expectedRank, explainResponsePayload.Explanation.Rank)
+		}
+	}
+}
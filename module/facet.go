@@ -0,0 +1,223 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/storage"
+)
+
+const (
+	//DefaultFacetSize is the number of values of a facet when its size is not defined
+	DefaultFacetSize int = 10
+	//MaxFacetSize is the maximum number of values of a facet
+	MaxFacetSize int = 100
+)
+
+//FacetRequest is a facet on a keyword attribute of a search. Size is the maximum number of values (DefaultFacetSize when it is not defined), the counts of the remaining values go into the other bucket
+type FacetRequest struct {
+	Attribute string `json:"attribute"`
+	Size      int    `json:"size,omitempty"`
+}
+
+//FacetValueCount is the number of matched documents that have an attribute value
+type FacetValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+//FacetResult is the value counts of a facet over every matched document, ordered by count (descending).
+//OtherCount is the sum of the counts of the values beyond the size of the facet
+type FacetResult struct {
+	Attribute  string            `json:"attribute"`
+	Values     []FacetValueCount `json:"values"`
+	OtherCount int               `json:"otherCount"`
+}
+
+//RankByFacetCount is the additional struct for ordering facet values based on its Count (and its Value for the same count)
+type RankByFacetCount []FacetValueCount
+
+func (r RankByFacetCount) Len() int { return len(r) }
+func (r RankByFacetCount) Less(i, j int) bool {
+	if r[i].Count != r[j].Count {
+		return r[i].Count > r[j].Count
+	}
+	return r[i].Value < r[j].Value
+}
+func (r RankByFacetCount) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+
+//validateFacetRequests validates the facets of a search
+func validateFacetRequests(facets []FacetRequest) error {
+	attributes := make(map[string]int)
+	for _, facet := range facets {
+		attribute := strings.ToLower(strings.Trim(facet.Attribute, " "))
+		if !attributeNameRegex.MatchString(attribute) {
+			return fmt.Errorf("Invalid facet attribute %s", facet.Attribute)
+		}
+		if _, ok := attributes[attribute]; ok {
+			return fmt.Errorf("Duplicate facet attribute %s", attribute)
+		}
+		attributes[attribute] = 1
+
+		if facet.Size < 0 || facet.Size > MaxFacetSize {
+			return fmt.Errorf("Size of facet %s must be between 0 and %d (0 = default)", attribute, MaxFacetSize)
+		}
+	}
+	return nil
+}
+
+//fetchFacets counts the values of every facet attribute over the matched documents.
+//The values of an attribute are listed from its value set (kept at index time), so a facet costs one call for the values and one call for each value
+func fetchFacets(ctx context.Context, documentType entity.DocumentType, facets []FacetRequest, rankedSearchResult []entity.SearchResultRankData) ([]FacetResult, error) {
+	matchedIDs := make(map[string]int)
+	for _, rankData := range rankedSearchResult {
+		matchedIDs[rankData.ID.String()] = 1
+	}
+
+	result := make([]FacetResult, 0, len(facets))
+	for _, facet := range facets {
+		attribute := strings.ToLower(strings.Trim(facet.Attribute, " "))
+		values, err := readStorage(ctx).SMembers(ctx, attributeValuesKey(ctx, documentType, attribute))
+		if err != nil {
+			return nil, err
+		}
+
+		valueCounts := make([]FacetValueCount, 0)
+		for _, value := range values {
+			members, err := readStorage(ctx).SMembers(ctx, attributeKey(ctx, documentType, attribute, value))
+			if err != nil {
+				return nil, err
+			}
+
+			count := 0
+			for _, documentID := range members {
+				if _, ok := matchedIDs[documentID]; ok {
+					count++
+				}
+			}
+			if count > 0 {
+				valueCounts = append(valueCounts, FacetValueCount{Value: value, Count: count})
+			}
+		}
+
+		result = append(result, buildFacetResult(attribute, valueCounts, facet.Size))
+	}
+	return result, nil
+}
+
+//buildFacetResult orders the value counts of a facet and keeps the top values, the counts of the remaining values are summed into the other bucket
+func buildFacetResult(attribute string, valueCounts []FacetValueCount, size int) FacetResult {
+	if size == 0 {
+		size = DefaultFacetSize
+	}
+
+	//sort by count (descending)
+	sort.Sort(RankByFacetCount(valueCounts))
+
+	result := FacetResult{
+		Attribute: attribute,
+		Values:    valueCounts,
+	}
+	if len(valueCounts) > size {
+		result.Values = valueCounts[:size]
+		for _, valueCount := range valueCounts[size:] {
+			result.OtherCount += valueCount.Count
+		}
+	}
+	return result
+}
+
+//addAttributeValues adds the values of the attributes of a document into the value set of each attribute, so a facet lists the values of an attribute without scanning the keys.
+//Returns the key that fails to be written
+func addAttributeValues(ctx context.Context, documentType entity.DocumentType, attributes map[string]map[string]int) (string, error) {
+	for name, valueSet := range attributes {
+		key := attributeValuesKey(ctx, documentType, name)
+		values := make([]interface{}, 0, len(valueSet))
+		for value := range valueSet {
+			values = append(values, value)
+		}
+		if _, err := moduleObj.Storage.SAdd(ctx, key, values); err != nil {
+			return key, err
+		}
+	}
+	return "", nil
+}
+
+//pruneAttributeValues removes the values that no document has anymore from the value set of their attribute.
+//Returns the key that fails to be read or written
+func pruneAttributeValues(ctx context.Context, documentType entity.DocumentType, attributes map[string]map[string]int) (string, error) {
+	for name, valueSet := range attributes {
+		key := attributeValuesKey(ctx, documentType, name)
+		for value := range valueSet {
+			isUsed, err := isAttributeValueUsed(ctx, documentType, name, value)
+			if err != nil {
+				return attributeKey(ctx, documentType, name, value), err
+			}
+			if isUsed {
+				continue
+			}
+			if _, err := moduleObj.Storage.SRem(ctx, key, []interface{}{value}); err != nil {
+				return key, err
+			}
+
+			//a document with the value may have been indexed meanwhile, so its value is added back
+			isUsed, err = isAttributeValueUsed(ctx, documentType, name, value)
+			if err != nil {
+				return attributeKey(ctx, documentType, name, value), err
+			}
+			if !isUsed {
+				continue
+			}
+			if _, err := moduleObj.Storage.SAdd(ctx, key, []interface{}{value}); err != nil {
+				return key, err
+			}
+		}
+	}
+	return "", nil
+}
+
+//isAttributeValueUsed tells whether a document has the attribute value (an empty set does not exist), it is read from the primary storage
+func isAttributeValueUsed(ctx context.Context, documentType entity.DocumentType, attribute, value string) (bool, error) {
+	keyType, err := storage.Primary(moduleObj.Storage).Type(ctx, attributeKey(ctx, documentType, attribute, value))
+	if err != nil {
+		return false, err
+	}
+	return keyType != storage.KeyTypeNone, nil
+}
+
+//removedAttributeValues gets the values of the old attributes that are not in the new attributes
+func removedAttributeValues(oldAttributes, newAttributes map[string]map[string]int) map[string]map[string]int {
+	result := make(map[string]map[string]int)
+	for name, valueSet := range oldAttributes {
+		for value := range valueSet {
+			if _, ok := newAttributes[name][value]; ok {
+				continue
+			}
+			if _, ok := result[name]; !ok {
+				result[name] = make(map[string]int)
+			}
+			result[name][value] = 1
+		}
+	}
+	return result
+}
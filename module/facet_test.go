@@ -0,0 +1,172 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFacetRequests(t *testing.T) {
+	type tcase struct {
+		facets        []FacetRequest
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["valid facets"] = tcase{
+		facets: []FacetRequest{{Attribute: "category"}, {Attribute: "City", Size: 5}},
+	}
+	testCases["invalid attribute"] = tcase{
+		facets:        []FacetRequest{{Attribute: "kota asal"}},
+		expectedError: true,
+	}
+	testCases["duplicate attribute"] = tcase{
+		facets:        []FacetRequest{{Attribute: "city"}, {Attribute: "CITY"}},
+		expectedError: true,
+	}
+	testCases["size too large"] = tcase{
+		facets:        []FacetRequest{{Attribute: "city", Size: MaxFacetSize + 1}},
+		expectedError: true,
+	}
+	testCases["negative size"] = tcase{
+		facets:        []FacetRequest{{Attribute: "city", Size: -1}},
+		expectedError: true,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on validateFacetRequests with test case:", ktc)
+		err := validateFacetRequests(vtc.facets)
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+
+	err := validateFacetRequests([]FacetRequest{{Attribute: "city", Size: -1}})
+	assert.Equal(t, fmt.Sprintf("Size of facet city must be between 0 and %d (0 = default)", MaxFacetSize), err.Error())
+}
+
+func TestBuildFacetResult(t *testing.T) {
+	valueCounts := []FacetValueCount{
+		{Value: "beauty", Count: 2},
+		{Value: "fashion", Count: 45},
+		{Value: "food", Count: 120},
+		{Value: "travel", Count: 2},
+	}
+
+	result := buildFacetResult("category", valueCounts, 2)
+	assert.Equal(t, FacetResult{
+		Attribute:  "category",
+		Values:     []FacetValueCount{{Value: "food", Count: 120}, {Value: "fashion", Count: 45}},
+		OtherCount: 4,
+	}, result)
+
+	result = buildFacetResult("category", []FacetValueCount{{Value: "food", Count: 1}}, 0)
+	assert.Equal(t, FacetResult{
+		Attribute: "category",
+		Values:    []FacetValueCount{{Value: "food", Count: 1}},
+	}, result)
+}
+
+func TestSearchWithFacets(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	documents := map[string]CreateIndexRequestPayload{
+		"1": {DocumentName: "Diskon Kopi", Attributes: map[string]AttributeValues{"category": {"food"}, "city": {"jakarta", "bandung"}}},
+		"2": {DocumentName: "Diskon Kopi Susu", Attributes: map[string]AttributeValues{"category": {"food"}, "city": {"jakarta"}}},
+		"3": {DocumentName: "Diskon Baju", Attributes: map[string]AttributeValues{"category": {"fashion"}, "city": {"jakarta"}}},
+		"4": {DocumentName: "Kopi Gratis", Attributes: map[string]AttributeValues{"category": {"beverage"}, "city": {"surabaya"}}},
+	}
+	for documentID, payload := range documents {
+		response := CreateIndex(ctx, documentID, "campaign", payload)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+
+	//facets are counted over the matched documents only
+	response := Search(ctx, "campaign", SearchRequestPayload{
+		SearchTerm: "diskon",
+		Facets:     []FacetRequest{{Attribute: "category"}, {Attribute: "city", Size: 1}},
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []FacetResult{
+		{
+			Attribute: "category",
+			Values:    []FacetValueCount{{Value: "food", Count: 2}, {Value: "fashion", Count: 1}},
+		},
+		{
+			Attribute:  "city",
+			Values:     []FacetValueCount{{Value: "jakarta", Count: 3}},
+			OtherCount: 1,
+		},
+	}, response.Data.(SearchResponsePayload).Facets)
+
+	//facets are counted after the filters
+	response = Search(ctx, "campaign", SearchRequestPayload{
+		SearchTerm: "kopi",
+		Filters:    []FilterClause{{Type: "term", Attribute: "city", Value: "jakarta"}},
+		Facets:     []FacetRequest{{Attribute: "category"}},
+	})
+	assert.Equal(t, []FacetResult{
+		{
+			Attribute: "category",
+			Values:    []FacetValueCount{{Value: "food", Count: 2}},
+		},
+	}, response.Data.(SearchResponsePayload).Facets)
+
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi"})
+	assert.Nil(t, response.Data.(SearchResponsePayload).Facets)
+
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi", Facets: []FacetRequest{{Attribute: "city", Size: 1000}}})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	//the values of an attribute are kept in its value set, a value is removed once no document has it
+	values, _ := moduleObj.Storage.SMembers(ctx, attributeValuesKey(ctx, "campaign", "city"))
+	assert.ElementsMatch(t, []string{"jakarta", "bandung", "surabaya"}, values)
+
+	response = UpdateIndex(ctx, "4", "campaign", UpdateIndexRequestPayload{
+		OldDocumentName: "Kopi Gratis",
+		NewDocumentName: "Kopi Gratis",
+		OldAttributes:   map[string]AttributeValues{"category": {"beverage"}, "city": {"surabaya"}},
+		NewAttributes:   map[string]AttributeValues{"category": {"beverage"}, "city": {"jakarta"}},
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = UpdateIndex(ctx, "1", "campaign", UpdateIndexRequestPayload{
+		OldDocumentName: "Diskon Kopi",
+		NewDocumentName: "Diskon Kopi",
+		OldAttributes:   map[string]AttributeValues{"category": {"food"}, "city": {"jakarta", "bandung"}},
+		NewAttributes:   map[string]AttributeValues{"category": {"food"}, "city": {"jakarta"}},
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	values, _ = moduleObj.Storage.SMembers(ctx, attributeValuesKey(ctx, "campaign", "city"))
+	assert.Equal(t, []string{"jakarta"}, values)
+
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi", Facets: []FacetRequest{{Attribute: "city"}}})
+	assert.Equal(t, []FacetResult{
+		{
+			Attribute: "city",
+			Values:    []FacetValueCount{{Value: "jakarta", Count: 3}},
+		},
+	}, response.Data.(SearchResponsePayload).Facets)
+}
@@ -18,41 +18,98 @@ package module
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
-	"fmt"
-	"log"
+	"context"
 	"strings"
 
 	"github.com/SurgicalSteel/elasthink/entity"
-	"github.com/SurgicalSteel/elasthink/util"
+	"github.com/SurgicalSteel/elasthink/logger"
 )
 
-func fetchWordIndexSets(documentType entity.DocumentType, searchTermSet map[string]int) map[string][]int64 {
-	result := make(map[string][]int64)
+//fetchDocumentIDs fetches the document ids (as they are kept in the index) of a word set key. A key that fails to be fetched is skipped (isFetched is false), unless the request context is done
+func fetchDocumentIDs(ctx context.Context, key string) ([]string, bool, error) {
+	members, err := readStorage(ctx).SMembers(ctx, key)
+	if err != nil {
+		if isContextError(err) {
+			return nil, false, err
+		}
+		moduleLogger.Error(ctx, "Failed to get members of key", logger.Fields{"key": key, "error": err})
+		return nil, false, nil
+	}
+	return members, true, nil
+}
 
-	// set key format --> elasthink:inverted:documentType:word
+//fetchWordMatches fetches the target fields where every word is found in each document, with their boosts.
+//When every field is targeted, a document only found in the word set of the document type (indexed before the document type had fields) is matched on entity.AllDocumentField with the default boost.
+//A word whose keys all fail to be fetched is skipped, unless the request context is done
+func fetchWordMatches(ctx context.Context, documentType entity.DocumentType, searchTermSet map[string]int, fields []entity.DocumentField, isTargetingAllFields bool) (map[string]map[string][]entity.FieldMatch, error) {
+	result := make(map[string]map[string][]entity.FieldMatch)
+
+	// set key format --> elasthink:field:documentType:field:word and elasthink:inverted:documentType:word
 	for k := range searchTermSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, documentType, k)
-		members, err := moduleObj.Redis.SMembers(key)
-		if err != nil {
-			log.Println("[MODULE][FETCHER] Failed to get members of key :", key)
-			continue
+		matches := make(map[string][]entity.FieldMatch)
+		isFetched := false
+
+		for _, field := range fields {
+			documentIDs, ok, err := fetchDocumentIDs(ctx, fieldIndexKey(ctx, documentType, field.Name, k))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			isFetched = true
+			for _, documentID := range documentIDs {
+				matches[documentID] = append(matches[documentID], entity.FieldMatch{Field: field.Name, Boost: field.Boost})
+			}
+		}
+
+		if isTargetingAllFields {
+			documentIDs, ok, err := fetchDocumentIDs(ctx, invertedIndexKey(ctx, documentType, k))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				isFetched = true
+				for _, documentID := range documentIDs {
+					if _, found := matches[documentID]; !found {
+						matches[documentID] = []entity.FieldMatch{{Field: entity.AllDocumentField, Boost: entity.DefaultDocumentFieldBoost}}
+					}
+				}
+			}
+		}
+
+		if isFetched {
+			result[k] = matches
 		}
-		documentIds := util.SliceStringToInt64(members)
-		result[k] = documentIds
 	}
 
+	return result, nil
+}
+
+//wordScoresOf gets the score of the documents of every word from its matches, which is the sum of the boosts of the fields where the word is found
+func wordScoresOf(wordMatches map[string]map[string][]entity.FieldMatch) map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for word, matches := range wordMatches {
+		scores := make(map[string]float64)
+		for documentID, fieldMatches := range matches {
+			for _, fieldMatch := range fieldMatches {
+				scores[documentID] += fieldMatch.Boost
+			}
+		}
+		result[word] = scores
+	}
 	return result
 }
 
-func fetchKeywords(documentType entity.DocumentType, prefix string) ([]string, error) {
-	prefixKey := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, documentType, prefix)
-	rawKeys, err := moduleObj.Redis.KeysPrefix(prefixKey)
+func fetchKeywords(ctx context.Context, documentType entity.DocumentType, prefix string) ([]string, error) {
+	prefixKey := invertedIndexKey(ctx, documentType, prefix)
+	rawKeys, err := readStorage(ctx).KeysPrefix(ctx, prefixKey)
 	if err != nil {
-		log.Printf("[MODULE][FETCHER] Failed to get keys with prefix :%s Detail :%s\n", prefixKey, err.Error())
+		moduleLogger.Error(ctx, "Failed to get keys with prefix", logger.Fields{"prefix": prefixKey, "error": err})
 		return []string{}, err
 	}
 	finalKeywords := make([]string, len(rawKeys))
-	trimPrefix := fmt.Sprintf("%s%s:", elasthinkInvertedIndexPrefix, documentType)
+	trimPrefix := invertedIndexKeyPrefix(ctx, documentType)
 	for i := 0; i < len(rawKeys); i++ {
 		rawKey := rawKeys[i]
 		finalKeywords[i] = strings.TrimPrefix(rawKey, trimPrefix)
@@ -0,0 +1,190 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+)
+
+//fieldNameRegex is the format of a field name
+var fieldNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+//InitDocumentFields initializes the fields and the id type of every document type from the document config.
+//It fails when a document type is unknown, or a field has an invalid name or boost, or is defined twice, or an id type is unknown.
+func InitDocumentFields(documentConfig config.DocumentConfigWrap) error {
+	fieldsByDocumentType, err := buildDocumentFields(documentConfig)
+	if err != nil {
+		return err
+	}
+	idTypesByDocumentType, err := buildDocumentIDTypes(documentConfig)
+	if err != nil {
+		return err
+	}
+
+	updateSettings(func(settings *Settings) {
+		settings.DocumentFields = fieldsByDocumentType
+		settings.DocumentIDTypes = idTypesByDocumentType
+	})
+	return nil
+}
+
+//buildDocumentFields builds the fields of every document type that has configured fields
+func buildDocumentFields(documentConfig config.DocumentConfigWrap) (map[entity.DocumentType][]entity.DocumentField, error) {
+	fieldsByDocumentType := make(map[entity.DocumentType][]entity.DocumentField)
+
+	for name, vd := range documentConfig.DocumentType {
+		if vd == nil || len(vd.Field) == 0 {
+			continue
+		}
+		documentType := entity.DocumentType(strings.ToLower(strings.Trim(name, " ")))
+		if err := documentType.IsValidFromCustomDocumentType(entity.Entity.GetDocumentTypes()); err != nil {
+			return nil, fmt.Errorf("Fields of an invalid document type %s", name)
+		}
+
+		fieldNameSet := make(map[string]int)
+		fields := make([]entity.DocumentField, 0, len(vd.Field))
+		for _, rawField := range vd.Field {
+			field, err := parseDocumentField(rawField)
+			if err != nil {
+				return nil, fmt.Errorf("Document type %s has an invalid field %s", name, rawField)
+			}
+			if _, ok := fieldNameSet[field.Name]; ok {
+				return nil, fmt.Errorf("Document type %s has a duplicate field %s", name, field.Name)
+			}
+			fieldNameSet[field.Name] = 1
+			fields = append(fields, field)
+		}
+		fieldsByDocumentType[documentType] = fields
+	}
+
+	return fieldsByDocumentType, nil
+}
+
+//parseDocumentField parses a configured field. Format --> name:boost (or name, with the default boost)
+func parseDocumentField(rawField string) (entity.DocumentField, error) {
+	field := entity.DocumentField{Boost: entity.DefaultDocumentFieldBoost}
+	parts := strings.SplitN(rawField, ":", 2)
+	field.Name = strings.ToLower(strings.Trim(parts[0], " "))
+	if !fieldNameRegex.MatchString(field.Name) {
+		return field, errors.New("Invalid Field Name")
+	}
+
+	if len(parts) == 2 {
+		boost, err := strconv.ParseFloat(strings.Trim(parts[1], " "), 64)
+		if err != nil || boost <= 0 {
+			return field, errors.New("Invalid Field Boost")
+		}
+		field.Boost = boost
+	}
+	return field, nil
+}
+
+//documentFields gets the fields of a document type, which is the default field when the document type has no configured fields
+func documentFields(ctx context.Context, documentType entity.DocumentType) []entity.DocumentField {
+	if fields, ok := currentSettings(ctx).DocumentFields[documentType]; ok {
+		return fields
+	}
+	return []entity.DocumentField{{Name: entity.DefaultDocumentField, Boost: entity.DefaultDocumentFieldBoost}}
+}
+
+//targetFields gets the fields of a document type with the given names, or every field when no name is given
+func targetFields(ctx context.Context, documentType entity.DocumentType, names []string) ([]entity.DocumentField, error) {
+	fields := documentFields(ctx, documentType)
+	if len(names) == 0 {
+		return fields, nil
+	}
+
+	fieldsByName := make(map[string]entity.DocumentField)
+	for _, field := range fields {
+		fieldsByName[field.Name] = field
+	}
+
+	result := make([]entity.DocumentField, 0, len(names))
+	nameSet := make(map[string]int)
+	for _, name := range names {
+		name = strings.ToLower(strings.Trim(name, " "))
+		field, ok := fieldsByName[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown field %s", name)
+		}
+		if _, ok := nameSet[name]; !ok {
+			nameSet[name] = 1
+			result = append(result, field)
+		}
+	}
+	return result, nil
+}
+
+//documentFieldValues gets the values of every field of a document. The document name is the value of the default field.
+//isCheckingConfiguredFields makes sure every field is configured for the document type (it is not checked on the old document of an update, whose fields might not be configured anymore)
+func documentFieldValues(ctx context.Context, documentType entity.DocumentType, documentName string, fields map[string]string, isCheckingConfiguredFields bool) (map[string]string, error) {
+	values := make(map[string]string)
+	for name, value := range fields {
+		name = strings.ToLower(strings.Trim(name, " "))
+		if !fieldNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("Invalid field %s", name)
+		}
+		if len(strings.Trim(value, " ")) > 0 {
+			values[name] = value
+		}
+	}
+
+	if len(strings.Trim(documentName, " ")) > 0 {
+		if _, ok := values[entity.DefaultDocumentField]; ok {
+			return nil, fmt.Errorf("Document Name and %s field must not be both defined", entity.DefaultDocumentField)
+		}
+		values[entity.DefaultDocumentField] = documentName
+	}
+
+	if isCheckingConfiguredFields {
+		configuredFieldSet := make(map[string]int)
+		for _, field := range documentFields(ctx, documentType) {
+			configuredFieldSet[field.Name] = 1
+		}
+		for name := range values {
+			if _, ok := configuredFieldSet[name]; !ok {
+				return nil, fmt.Errorf("Unknown field %s", name)
+			}
+		}
+	}
+	return values, nil
+}
+
+//documentIndexKeys gets every word set key of a document: the key of each word in the document type and the key of each word in its field
+func documentIndexKeys(ctx context.Context, documentType entity.DocumentType, fieldWordSets map[string]map[string]int) []string {
+	wordSet := make(map[string]int)
+	keys := make([]string, 0)
+	for field, fieldWordSet := range fieldWordSets {
+		for word := range fieldWordSet {
+			if _, ok := wordSet[word]; !ok {
+				wordSet[word] = 1
+				keys = append(keys, invertedIndexKey(ctx, documentType, word))
+			}
+			keys = append(keys, fieldIndexKey(ctx, documentType, field, word))
+		}
+	}
+	return keys
+}
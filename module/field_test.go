@@ -0,0 +1,114 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocumentField(t *testing.T) {
+	type tcase struct {
+		rawField      string
+		expected      entity.DocumentField
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["field with boost"] = tcase{rawField: "Title:3", expected: entity.DocumentField{Name: "title", Boost: 3}}
+	testCases["field without boost"] = tcase{rawField: "tags", expected: entity.DocumentField{Name: "tags", Boost: 1}}
+	testCases["invalid field name"] = tcase{rawField: "merchant name:2", expectedError: true}
+	testCases["invalid boost"] = tcase{rawField: "title:-1", expectedError: true}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on parseDocumentField with test case:", ktc)
+		actual, err := parseDocumentField(vtc.rawField)
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, vtc.expected, actual)
+	}
+}
+
+func TestSearchWithFields(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	err := InitDocumentFields(config.DocumentConfigWrap{
+		DocumentType: map[string]*config.DocumentTypeConfig{
+			"campaign": {Field: []string{"name:3", "description"}},
+		},
+	})
+	assert.Nil(t, err)
+
+	response := CreateIndex(ctx, "1", "campaign", CreateIndexRequestPayload{Fields: map[string]string{"name": "Diskon Kopi", "description": "diskon akhir pekan"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = CreateIndex(ctx, "2", "campaign", CreateIndexRequestPayload{Fields: map[string]string{"name": "Cashback Kopi", "description": "diskon spesial"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = CreateIndex(ctx, "3", "campaign", CreateIndexRequestPayload{Fields: map[string]string{"merchant": "Kopi Kenangan"}})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	//a name match outranks a description match
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "diskon"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(1), ShowCount: 1, Score: 4, Rank: 1},
+		{ID: entity.Int64DocumentID(2), ShowCount: 1, Score: 1, Rank: 2},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "diskon", Fields: []string{"name"}})
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(1), ShowCount: 1, Score: 3, Rank: 1},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "diskon", Fields: []string{"merchant"}})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	//the document is removed from the keys of its old fields
+	response = UpdateIndex(ctx, "2", "campaign", UpdateIndexRequestPayload{
+		OldFields: map[string]string{"name": "Cashback Kopi", "description": "diskon spesial"},
+		NewFields: map[string]string{"name": "Cashback Kopi"},
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "diskon"})
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(1), ShowCount: 1, Score: 4, Rank: 1},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+}
+
+func TestSearchDocumentIndexedWithoutFields(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	//a document indexed before the document type had fields is only in the word set of the document type
+	_, err := moduleObj.Storage.SAdd(ctx, "elasthink:inverted:campaign:kopi", []interface{}{"7"})
+	assert.Nil(t, err)
+
+	response := Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi"})
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(7), ShowCount: 1, Score: 1, Rank: 1},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+}
@@ -0,0 +1,137 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/util"
+)
+
+//MaxHighlightTagLength is the maximum length of a highlight tag
+const MaxHighlightTagLength int = 64
+
+//HighlightRequest is the highlighting of the search words in the searched fields of every result. PreTag and PostTag wrap every found word, util.DefaultHighlightPreTag and util.DefaultHighlightPostTag are used when they are not defined
+type HighlightRequest struct {
+	PreTag  string `json:"preTag,omitempty"`
+	PostTag string `json:"postTag,omitempty"`
+}
+
+//tags gets the pre and post tags of the highlighting, with the default tags for the tags that are not defined
+func (hr HighlightRequest) tags() (string, string) {
+	preTag, postTag := hr.PreTag, hr.PostTag
+	if preTag == "" {
+		preTag = util.DefaultHighlightPreTag
+	}
+	if postTag == "" {
+		postTag = util.DefaultHighlightPostTag
+	}
+	return preTag, postTag
+}
+
+//validateHighlightRequest validates the tags of the highlighting (if it is requested)
+func validateHighlightRequest(highlight *HighlightRequest) error {
+	if highlight == nil {
+		return nil
+	}
+	if len(highlight.PreTag) > MaxHighlightTagLength || len(highlight.PostTag) > MaxHighlightTagLength {
+		return errors.New("Highlight Tag is too long")
+	}
+	return nil
+}
+
+//fetchStoredDocuments fetches the stored documents of the document ids from the normal index in one call, in the order of the ids.
+//A document is nil when it is not stored (e.g. it was indexed before documents were stored)
+func fetchStoredDocuments(ctx context.Context, documentType entity.DocumentType, documentIDs []string) ([]*entity.StoredDocument, error) {
+	keys := make([]string, len(documentIDs))
+	for i, documentID := range documentIDs {
+		keys[i] = normalIndexKey(ctx, documentType, documentID)
+	}
+	values, err := readStorage(ctx).MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]*entity.StoredDocument, len(values))
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		document := new(entity.StoredDocument)
+		if err := json.Unmarshal([]byte(value), document); err != nil {
+			return nil, err
+		}
+		documents[i] = document
+	}
+	return documents, nil
+}
+
+//attachDocuments puts the stored values of the fields of every ranked document into its result (when includeDocument is true), and the searched fields where a search word is found with the found words highlighted (when highlight is not nil).
+//A document that is not stored is returned without them
+func attachDocuments(ctx context.Context, documentType entity.DocumentType, rankedSearchResult []entity.SearchResultRankData, searchTermSet map[string]int, fields []entity.DocumentField, includeDocument bool, highlight *HighlightRequest) error {
+	if len(rankedSearchResult) == 0 {
+		return nil
+	}
+
+	documentIDs := make([]string, len(rankedSearchResult))
+	for i, rankData := range rankedSearchResult {
+		documentIDs[i] = rankData.ID.String()
+	}
+	documents, err := fetchStoredDocuments(ctx, documentType, documentIDs)
+	if err != nil {
+		return err
+	}
+
+	for i, document := range documents {
+		if document == nil {
+			continue
+		}
+
+		if includeDocument {
+			rankedSearchResult[i].Document = document.Fields
+		}
+		if highlight != nil {
+			rankedSearchResult[i].Highlights = highlightFields(document.Fields, searchTermSet, fields, *highlight)
+		}
+	}
+	return nil
+}
+
+//highlightFields highlights the search words in the searched fields of a stored document. Returns only the fields where a search word is found (nil when there is none)
+func highlightFields(fieldValues map[string]string, searchTermSet map[string]int, fields []entity.DocumentField, highlight HighlightRequest) map[string]string {
+	preTag, postTag := highlight.tags()
+	var highlights map[string]string
+	for _, field := range fields {
+		value, ok := fieldValues[field.Name]
+		if !ok {
+			continue
+		}
+		highlighted, isHighlighted := util.Highlight(value, searchTermSet, preTag, postTag)
+		if !isHighlighted {
+			continue
+		}
+		if highlights == nil {
+			highlights = make(map[string]string)
+		}
+		highlights[field.Name] = highlighted
+	}
+	return highlights
+}
@@ -0,0 +1,101 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHighlightRequest(t *testing.T) {
+	type tcase struct {
+		highlight     *HighlightRequest
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["no highlight"] = tcase{}
+	testCases["default tags"] = tcase{highlight: &HighlightRequest{}}
+	testCases["custom tags"] = tcase{highlight: &HighlightRequest{PreTag: "<b>", PostTag: "</b>"}}
+	testCases["too long tag"] = tcase{highlight: &HighlightRequest{PreTag: strings.Repeat("b", MaxHighlightTagLength+1)}, expectedError: true}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on validateHighlightRequest with test case:", ktc)
+		err := validateHighlightRequest(vtc.highlight)
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+	}
+}
+
+func TestSearchWithDocumentsAndHighlights(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	err := InitDocumentFields(config.DocumentConfigWrap{
+		DocumentType: map[string]*config.DocumentTypeConfig{
+			"campaign": {Field: []string{"name:3", "description"}},
+		},
+	})
+	assert.Nil(t, err)
+
+	response := CreateIndex(ctx, "1", "campaign", CreateIndexRequestPayload{Fields: map[string]string{"name": "Diskon Kopi", "description": "Kopi yang murah!"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response = CreateIndex(ctx, "2", "campaign", CreateIndexRequestPayload{Fields: map[string]string{"name": "Cashback Pulsa", "description": "Gratis kopi"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	//documents and highlights are only returned when they are requested
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi yang"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	for _, rankData := range response.Data.(SearchResponsePayload).RankedResultList {
+		assert.Nil(t, rankData.Document)
+		assert.Nil(t, rankData.Highlights)
+	}
+
+	//stopwords are not highlighted
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi yang", IncludeDocument: true, Highlight: &HighlightRequest{}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []entity.SearchResultRankData{
+		{
+			ID: entity.Int64DocumentID(1), ShowCount: 1, Score: 4, Rank: 1,
+			Document:   map[string]string{"name": "Diskon Kopi", "description": "Kopi yang murah!"},
+			Highlights: map[string]string{"name": "Diskon <em>Kopi</em>", "description": "<em>Kopi</em> yang murah!"},
+		},
+		{
+			ID: entity.Int64DocumentID(2), ShowCount: 1, Score: 1, Rank: 2,
+			Document:   map[string]string{"name": "Cashback Pulsa", "description": "Gratis kopi"},
+			Highlights: map[string]string{"description": "Gratis <em>kopi</em>"},
+		},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+
+	//only the searched fields are highlighted
+	response = Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi", Fields: []string{"name"}, Highlight: &HighlightRequest{PreTag: "<b>", PostTag: "</b>"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []entity.SearchResultRankData{
+		{ID: entity.Int64DocumentID(1), ShowCount: 1, Score: 3, Rank: 1, Highlights: map[string]string{"name": "Diskon <b>Kopi</b>"}},
+	}, response.Data.(SearchResponsePayload).RankedResultList)
+}
@@ -21,39 +21,78 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
 	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/metrics"
 	"github.com/SurgicalSteel/elasthink/util"
 )
 
-//CreateIndexRequestPayload is the universal request payload for create index handler
+//CreateIndexRequestPayload is the universal request payload for create index handler.
+//Fields are the values of the named fields of the document, DocumentName is the value of the default (name) field.
+//Attributes are the keyword values (e.g. status, city) the document can be filtered by on search, NumericAttributes are the numbers and dates (e.g. price, start_date) the document can be filtered by range.
+//ExpiresAt is the optional expiry time of the document (a date or unix seconds), the document is not found anymore once it has expired
 type CreateIndexRequestPayload struct {
-	DocumentName string `json:"documentName"`
+	DocumentName      string                     `json:"documentName"`
+	Fields            map[string]string          `json:"fields,omitempty"`
+	Attributes        map[string]AttributeValues `json:"attributes,omitempty"`
+	NumericAttributes map[string]NumericValue    `json:"numericAttributes,omitempty"`
+	ExpiresAt         *NumericValue              `json:"expiresAt,omitempty"`
 }
 
-func validateCreateIndexRequestPayload(documentID int64, documentType, documentName string) error {
-	err := validateDocumentType(documentType, entity.Entity.GetDocumentTypes())
+//documentContent is the content of a document to index: the values of its fields, the values of its attributes, the values of its numeric attributes and its expiry time (0 when it does not expire)
+type documentContent struct {
+	fieldValues       map[string]string
+	attributes        map[string]map[string]int
+	numericAttributes map[string]float64
+	expiresAt         int64
+}
+
+//validateCreateIndexRequestPayload validates the document id (with the id type of its document type) and the request payload, and gets the content of the document
+func validateCreateIndexRequestPayload(ctx context.Context, rawDocumentID string, documentType string, requestPayload CreateIndexRequestPayload) (entity.DocumentID, documentContent, error) {
+	var content documentContent
+	err := validateDocumentType(documentType, documentTypes(ctx))
+	if err != nil {
+		return entity.DocumentID{}, content, err
+	}
+
+	documentID, err := parseDocumentID(ctx, getDocumentType(documentType, documentTypes(ctx)), rawDocumentID)
 	if err != nil {
-		return err
+		return documentID, content, err
 	}
 
-	if documentID <= 0 {
-		return errors.New("Invalid Document ID")
+	content.fieldValues, err = documentFieldValues(ctx, getDocumentType(documentType, documentTypes(ctx)), requestPayload.DocumentName, requestPayload.Fields, true)
+	if err != nil {
+		return documentID, content, err
+	}
+	if len(content.fieldValues) == 0 {
+		return documentID, content, errors.New("Document Name must not be empty")
 	}
 
-	if len(strings.Trim(documentName, " ")) == 0 {
-		return errors.New("Document Name must not be empty")
+	content.attributes, err = normalizeAttributes(requestPayload.Attributes)
+	if err != nil {
+		return documentID, content, err
 	}
 
-	return nil
+	content.numericAttributes, err = normalizeNumericAttributes(requestPayload.NumericAttributes)
+	if err != nil {
+		return documentID, content, err
+	}
+
+	content.expiresAt, err = validateExpiresAt(requestPayload.ExpiresAt)
+	if err != nil {
+		return documentID, content, err
+	}
+
+	return documentID, content, nil
 }
 
-//CreateIndex is the core function to create an index of a document
-func CreateIndex(ctx context.Context, documentID int64, documentType string, requestPayload CreateIndexRequestPayload) Response {
-	err := validateCreateIndexRequestPayload(documentID, documentType, requestPayload.DocumentName)
+//CreateIndex is the core function to create an index of a document. Every word is indexed in the document type and in its field, the document is added to the set of each attribute value and scored in the sorted set of each numeric attribute.
+//The document is then stored in the normal index with the keys it is indexed in, so it can be removed once it expires
+func CreateIndex(ctx context.Context, rawDocumentID string, documentType string, requestPayload CreateIndexRequestPayload) Response {
+	documentID, content, err := validateCreateIndexRequestPayload(ctx, rawDocumentID, documentType, requestPayload)
 	if err != nil {
 		return Response{
 			StatusCode:   http.StatusBadRequest,
@@ -62,26 +101,77 @@ func CreateIndex(ctx context.Context, documentID int64, documentType string, req
 		}
 	}
 
-	documentNameSet := util.Tokenize(requestPayload.DocumentName, moduleObj.IsUsingStopwordRemoval, moduleObj.StopwordSet)
+	docType := getDocumentType(documentType, documentTypes(ctx))
+	fieldWordSets := requestStopwordSets(ctx, docType).tokenizeFields(content.fieldValues)
+	keys := append(documentIndexKeys(ctx, docType, fieldWordSets), documentAttributeKeys(ctx, docType, content.attributes)...)
 
-	docType := getDocumentType(documentType, entity.Entity.GetDocumentTypes())
 	errorExist := false
 	errorKeys := ""
 
-	for k := range documentNameSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, docType, k)
+	for _, key := range keys {
 		value := make([]interface{}, 1)
-		value[0] = fmt.Sprintf("%d", documentID)
-		_, err = moduleObj.Redis.SAdd(key, value)
+		value[0] = documentID.String()
+		_, err = moduleObj.Storage.SAdd(ctx, key, value)
+		if err != nil {
+			if isContextError(err) {
+				// the remaining keys are not indexed, the document must be indexed again
+				metrics.IncIndexingError(string(docType), "create")
+				return contextErrorResponse(err)
+			}
+			errorExist = true
+			errorKeys = errorKeys + " " + key + ","
+			moduleLogger.Error(ctx, "Failed to add index", logger.Fields{"operation": "create_index", "key": key, "document_id": documentID.String(), "error": err})
+			continue
+		}
+	}
+
+	rangeKeys := make([]string, 0, len(content.numericAttributes))
+	for attribute, score := range content.numericAttributes {
+		key := rangeKey(ctx, docType, attribute)
+		rangeKeys = append(rangeKeys, key)
+		_, err = moduleObj.Storage.ZAdd(ctx, key, score, documentID.String())
 		if err != nil {
+			if isContextError(err) {
+				// the remaining keys are not indexed, the document must be indexed again
+				metrics.IncIndexingError(string(docType), "create")
+				return contextErrorResponse(err)
+			}
 			errorExist = true
 			errorKeys = errorKeys + " " + key + ","
-			log.Println("[MODULE][CREATE INDEX] failed to add index on key :", key, "and document ID:", documentID)
+			moduleLogger.Error(ctx, "Failed to add index", logger.Fields{"operation": "create_index", "key": key, "document_id": documentID.String(), "error": err})
 			continue
 		}
 	}
 
+	errorKey, err := addAttributeValues(ctx, docType, content.attributes)
+	if err != nil {
+		if isContextError(err) {
+			metrics.IncIndexingError(string(docType), "create")
+			return contextErrorResponse(err)
+		}
+		errorExist = true
+		errorKeys = errorKeys + " " + errorKey + ","
+		moduleLogger.Error(ctx, "Failed to add attribute values", logger.Fields{"operation": "create_index", "key": errorKey, "document_id": documentID.String(), "error": err})
+	}
+
+	errorKey, err = storeDocument(ctx, docType, documentID, entity.StoredDocument{
+		Fields:    content.fieldValues,
+		Keys:      keys,
+		RangeKeys: rangeKeys,
+		ExpiresAt: content.expiresAt,
+	})
+	if err != nil {
+		if isContextError(err) {
+			metrics.IncIndexingError(string(docType), "create")
+			return contextErrorResponse(err)
+		}
+		errorExist = true
+		errorKeys = errorKeys + " " + errorKey + ","
+		moduleLogger.Error(ctx, "Failed to store document", logger.Fields{"operation": "create_index", "key": errorKey, "document_id": documentID.String(), "error": err})
+	}
+
 	if errorExist {
+		metrics.IncIndexingError(string(docType), "create")
 		errorKeys = strings.TrimRight(errorKeys, ",")
 		errorKeys = strings.TrimLeft(errorKeys, " ")
 		return Response{
@@ -98,36 +188,82 @@ func CreateIndex(ctx context.Context, documentID int64, documentType string, req
 
 }
 
-//UpdateIndexRequestPayload is the universal request payload for update index handler
+//UpdateIndexRequestPayload is the universal request payload for update index handler.
+//OldFields and NewFields are the values of the named fields of the document, OldDocumentName and NewDocumentName are the values of the default (name) field.
+//OldAttributes and NewAttributes are the attributes of the document, OldNumericAttributes and NewNumericAttributes are the numeric attributes of the document.
+//ExpiresAt is the expiry time of the new document, the document does not expire anymore when it is not defined
 type UpdateIndexRequestPayload struct {
-	OldDocumentName string `json:"oldDocumentName"`
-	NewDocumentName string `json:"newDocumentName"`
+	OldDocumentName      string                     `json:"oldDocumentName"`
+	NewDocumentName      string                     `json:"newDocumentName"`
+	OldFields            map[string]string          `json:"oldFields,omitempty"`
+	NewFields            map[string]string          `json:"newFields,omitempty"`
+	OldAttributes        map[string]AttributeValues `json:"oldAttributes,omitempty"`
+	NewAttributes        map[string]AttributeValues `json:"newAttributes,omitempty"`
+	OldNumericAttributes map[string]NumericValue    `json:"oldNumericAttributes,omitempty"`
+	NewNumericAttributes map[string]NumericValue    `json:"newNumericAttributes,omitempty"`
+	ExpiresAt            *NumericValue              `json:"expiresAt,omitempty"`
 }
 
-func validateUpdateIndexRequestPayload(documentID int64, documentType, oldDocumentName, newDocumentName string) error {
-	err := validateDocumentType(documentType, entity.Entity.GetDocumentTypes())
+//validateUpdateIndexRequestPayload validates the document id (with the id type of its document type) and the request payload, and gets the content of the old and the new document
+func validateUpdateIndexRequestPayload(ctx context.Context, rawDocumentID string, documentType string, requestPayload UpdateIndexRequestPayload) (entity.DocumentID, documentContent, documentContent, error) {
+	var oldContent, newContent documentContent
+	err := validateDocumentType(documentType, documentTypes(ctx))
 	if err != nil {
-		return err
+		return entity.DocumentID{}, oldContent, newContent, err
 	}
 
-	if documentID <= 0 {
-		return errors.New("Invalid Document ID")
+	docType := getDocumentType(documentType, documentTypes(ctx))
+	documentID, err := parseDocumentID(ctx, docType, rawDocumentID)
+	if err != nil {
+		return documentID, oldContent, newContent, err
 	}
 
-	if len(strings.Trim(oldDocumentName, " ")) == 0 {
-		return errors.New("Old Document Name must not be empty")
+	oldContent.fieldValues, err = documentFieldValues(ctx, docType, requestPayload.OldDocumentName, requestPayload.OldFields, false)
+	if err != nil {
+		return documentID, oldContent, newContent, err
+	}
+	if len(oldContent.fieldValues) == 0 {
+		return documentID, oldContent, newContent, errors.New("Old Document Name must not be empty")
 	}
 
-	if len(strings.Trim(newDocumentName, " ")) == 0 {
-		return errors.New("Document Name must not be empty")
+	newContent.fieldValues, err = documentFieldValues(ctx, docType, requestPayload.NewDocumentName, requestPayload.NewFields, true)
+	if err != nil {
+		return documentID, oldContent, newContent, err
+	}
+	if len(newContent.fieldValues) == 0 {
+		return documentID, oldContent, newContent, errors.New("Document Name must not be empty")
 	}
 
-	return nil
+	oldContent.attributes, err = normalizeAttributes(requestPayload.OldAttributes)
+	if err != nil {
+		return documentID, oldContent, newContent, err
+	}
+	newContent.attributes, err = normalizeAttributes(requestPayload.NewAttributes)
+	if err != nil {
+		return documentID, oldContent, newContent, err
+	}
+
+	oldContent.numericAttributes, err = normalizeNumericAttributes(requestPayload.OldNumericAttributes)
+	if err != nil {
+		return documentID, oldContent, newContent, err
+	}
+	newContent.numericAttributes, err = normalizeNumericAttributes(requestPayload.NewNumericAttributes)
+	if err != nil {
+		return documentID, oldContent, newContent, err
+	}
+
+	newContent.expiresAt, err = validateExpiresAt(requestPayload.ExpiresAt)
+	if err != nil {
+		return documentID, oldContent, newContent, err
+	}
+
+	return documentID, oldContent, newContent, nil
 }
 
-//UpdateIndex is the core function to update the index of a document. This requires old document name and new document name
-func UpdateIndex(ctx context.Context, documentID int64, documentType string, requestPayload UpdateIndexRequestPayload) Response {
-	err := validateUpdateIndexRequestPayload(documentID, documentType, requestPayload.OldDocumentName, requestPayload.NewDocumentName)
+//UpdateIndex is the core function to update the index of a document. This requires old document name and new document name (or the old and the new fields), the old and the new attributes, and the old and the new numeric attributes.
+//The stored document is replaced by the new document (with its new expiry time)
+func UpdateIndex(ctx context.Context, rawDocumentID string, documentType string, requestPayload UpdateIndexRequestPayload) Response {
+	documentID, oldContent, newContent, err := validateUpdateIndexRequestPayload(ctx, rawDocumentID, documentType, requestPayload)
 	if err != nil {
 		return Response{
 			StatusCode:   http.StatusBadRequest,
@@ -136,24 +272,54 @@ func UpdateIndex(ctx context.Context, documentID int64, documentType string, req
 		}
 	}
 
-	oldDocumentNameSet := util.Tokenize(requestPayload.OldDocumentName, moduleObj.IsUsingStopwordRemoval, moduleObj.StopwordSet)
-	newDocumentNameSet := util.Tokenize(requestPayload.NewDocumentName, moduleObj.IsUsingStopwordRemoval, moduleObj.StopwordSet)
+	docType := getDocumentType(documentType, documentTypes(ctx))
 
-	docType := getDocumentType(documentType, entity.Entity.GetDocumentTypes())
+	//runtime stopwords are kept in the old document, so the document is also removed from the index of a word that became a stopword
+	stopwordSets := requestStopwordSets(ctx, docType)
+	oldFieldWordSets := make(map[string]map[string]int)
+	for field, value := range oldContent.fieldValues {
+		oldFieldWordSets[field] = util.Tokenize(value, moduleObj.IsUsingStopwordRemoval, stopwordSets.fileSet)
+	}
+	newFieldWordSets := stopwordSets.tokenizeFields(newContent.fieldValues)
+	oldKeys := append(documentIndexKeys(ctx, docType, oldFieldWordSets), documentAttributeKeys(ctx, docType, oldContent.attributes)...)
+	newKeys := append(documentIndexKeys(ctx, docType, newFieldWordSets), documentAttributeKeys(ctx, docType, newContent.attributes)...)
 
 	// remove old document indexes
 	isErrorRemoveExist := false
 	errorRemoveKeys := ""
 
-	for k := range oldDocumentNameSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, docType, k)
+	for _, key := range oldKeys {
 		value := make([]interface{}, 1)
-		value[0] = fmt.Sprintf("%d", documentID)
-		_, err = moduleObj.Redis.SRem(key, value)
+		value[0] = documentID.String()
+		_, err = moduleObj.Storage.SRem(ctx, key, value)
 		if err != nil {
+			if isContextError(err) {
+				// the remaining keys are not indexed, the document must be indexed again
+				metrics.IncIndexingError(string(docType), "update")
+				return contextErrorResponse(err)
+			}
 			isErrorRemoveExist = true
 			errorRemoveKeys = errorRemoveKeys + " " + key + ","
-			log.Println("[MODULE][UPDATE INDEX] failed to remove index on key :", key, "and document ID:", documentID)
+			moduleLogger.Error(ctx, "Failed to remove index", logger.Fields{"operation": "update_index", "key": key, "document_id": documentID.String(), "error": err})
+			continue
+		}
+	}
+
+	//a numeric attribute kept in the new document only gets its score updated
+	for _, attribute := range removedNumericAttributes(oldContent.numericAttributes, newContent.numericAttributes) {
+		key := rangeKey(ctx, docType, attribute)
+		value := make([]interface{}, 1)
+		value[0] = documentID.String()
+		_, err = moduleObj.Storage.ZRem(ctx, key, value)
+		if err != nil {
+			if isContextError(err) {
+				// the remaining keys are not indexed, the document must be indexed again
+				metrics.IncIndexingError(string(docType), "update")
+				return contextErrorResponse(err)
+			}
+			isErrorRemoveExist = true
+			errorRemoveKeys = errorRemoveKeys + " " + key + ","
+			moduleLogger.Error(ctx, "Failed to remove index", logger.Fields{"operation": "update_index", "key": key, "document_id": documentID.String(), "error": err})
 			continue
 		}
 	}
@@ -162,20 +328,82 @@ func UpdateIndex(ctx context.Context, documentID int64, documentType string, req
 	isErrorAddExist := false
 	errorAddKeys := ""
 
-	for k := range newDocumentNameSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, docType, k)
+	for _, key := range newKeys {
 		value := make([]interface{}, 1)
-		value[0] = fmt.Sprintf("%d", documentID)
-		_, err = moduleObj.Redis.SAdd(key, value)
+		value[0] = documentID.String()
+		_, err = moduleObj.Storage.SAdd(ctx, key, value)
 		if err != nil {
+			if isContextError(err) {
+				// the remaining keys are not indexed, the document must be indexed again
+				metrics.IncIndexingError(string(docType), "update")
+				return contextErrorResponse(err)
+			}
 			isErrorAddExist = true
 			errorAddKeys = errorAddKeys + " " + key + ","
-			log.Println("[MODULE][UPDATE INDEX] failed to add index on key :", key, "and document ID:", documentID)
+			moduleLogger.Error(ctx, "Failed to add index", logger.Fields{"operation": "update_index", "key": key, "document_id": documentID.String(), "error": err})
 			continue
 		}
 	}
 
+	newRangeKeys := make([]string, 0, len(newContent.numericAttributes))
+	for attribute, score := range newContent.numericAttributes {
+		key := rangeKey(ctx, docType, attribute)
+		newRangeKeys = append(newRangeKeys, key)
+		_, err = moduleObj.Storage.ZAdd(ctx, key, score, documentID.String())
+		if err != nil {
+			if isContextError(err) {
+				// the remaining keys are not indexed, the document must be indexed again
+				metrics.IncIndexingError(string(docType), "update")
+				return contextErrorResponse(err)
+			}
+			isErrorAddExist = true
+			errorAddKeys = errorAddKeys + " " + key + ","
+			moduleLogger.Error(ctx, "Failed to add index", logger.Fields{"operation": "update_index", "key": key, "document_id": documentID.String(), "error": err})
+			continue
+		}
+	}
+
+	errorKey, err := addAttributeValues(ctx, docType, newContent.attributes)
+	if err != nil {
+		if isContextError(err) {
+			metrics.IncIndexingError(string(docType), "update")
+			return contextErrorResponse(err)
+		}
+		isErrorAddExist = true
+		errorAddKeys = errorAddKeys + " " + errorKey + ","
+		moduleLogger.Error(ctx, "Failed to add attribute values", logger.Fields{"operation": "update_index", "key": errorKey, "document_id": documentID.String(), "error": err})
+	}
+
+	//the values the document does not have anymore are removed once no document has them
+	errorKey, err = pruneAttributeValues(ctx, docType, removedAttributeValues(oldContent.attributes, newContent.attributes))
+	if err != nil {
+		if isContextError(err) {
+			metrics.IncIndexingError(string(docType), "update")
+			return contextErrorResponse(err)
+		}
+		isErrorRemoveExist = true
+		errorRemoveKeys = errorRemoveKeys + " " + errorKey + ","
+		moduleLogger.Error(ctx, "Failed to remove attribute values", logger.Fields{"operation": "update_index", "key": errorKey, "document_id": documentID.String(), "error": err})
+	}
+
+	errorKey, err = storeDocument(ctx, docType, documentID, entity.StoredDocument{
+		Fields:    newContent.fieldValues,
+		Keys:      newKeys,
+		RangeKeys: newRangeKeys,
+		ExpiresAt: newContent.expiresAt,
+	})
+	if err != nil {
+		if isContextError(err) {
+			metrics.IncIndexingError(string(docType), "update")
+			return contextErrorResponse(err)
+		}
+		isErrorAddExist = true
+		errorAddKeys = errorAddKeys + " " + errorKey + ","
+		moduleLogger.Error(ctx, "Failed to store document", logger.Fields{"operation": "update_index", "key": errorKey, "document_id": documentID.String(), "error": err})
+	}
+
 	if isErrorAddExist || isErrorRemoveExist {
+		metrics.IncIndexingError(string(docType), "update")
 		errorRemoveKeys = strings.TrimRight(errorRemoveKeys, ",")
 		errorRemoveKeys = strings.TrimLeft(errorRemoveKeys, " ")
 
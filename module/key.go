@@ -0,0 +1,74 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+)
+
+//invertedIndexKeyPrefix is the prefix of every word set key of a document type. Key format --> namespace:inverted:documentType:
+func invertedIndexKeyPrefix(ctx context.Context, documentType entity.DocumentType) string {
+	return keys(ctx).InvertedIndexKeyPrefix(string(documentType))
+}
+
+//invertedIndexKey is the word set key of a word in a document type. Key format --> namespace:inverted:documentType:word
+func invertedIndexKey(ctx context.Context, documentType entity.DocumentType, word string) string {
+	return keys(ctx).InvertedIndexKey(string(documentType), word)
+}
+
+//stopwordKey is the key of the runtime stopword set of a document type. Key format --> namespace:stopword:documentType
+func stopwordKey(ctx context.Context, documentType entity.DocumentType) string {
+	return keys(ctx).StopwordKey(string(documentType))
+}
+
+//fieldIndexKey is the word set key of a word in a field of a document type. Key format --> namespace:field:documentType:field:word
+func fieldIndexKey(ctx context.Context, documentType entity.DocumentType, field, word string) string {
+	return keys(ctx).FieldIndexKey(string(documentType), field, word)
+}
+
+//attributeValuesKey is the value set key of an attribute of a document type. Key format --> namespace:attributevalues:documentType:attribute
+func attributeValuesKey(ctx context.Context, documentType entity.DocumentType, attribute string) string {
+	return keys(ctx).AttributeValuesKey(string(documentType), attribute)
+}
+
+//attributeKey is the document id set key of an attribute value of a document type. Key format --> namespace:attribute:documentType:attribute:value
+func attributeKey(ctx context.Context, documentType entity.DocumentType, attribute, value string) string {
+	return keys(ctx).AttributeKey(string(documentType), attribute, value)
+}
+
+//parseAttributeKey gets the attribute and the value of a document id set key of an attribute value of a document type, ok is false when the key is not one
+func parseAttributeKey(ctx context.Context, documentType entity.DocumentType, key string) (string, string, bool) {
+	return keys(ctx).ParseAttributeKey(string(documentType), key)
+}
+
+//rangeKey is the sorted set key of a numeric attribute of a document type. Key format --> namespace:range:documentType:attribute
+func rangeKey(ctx context.Context, documentType entity.DocumentType, attribute string) string {
+	return keys(ctx).RangeKey(string(documentType), attribute)
+}
+
+//normalIndexKey is the key of the stored document of a document id. Key format --> namespace:normal:documentType:documentID
+func normalIndexKey(ctx context.Context, documentType entity.DocumentType, documentID string) string {
+	return keys(ctx).NormalIndexKey(string(documentType), documentID)
+}
+
+//expiryKey is the sorted set key of the documents of a document type that expire. Key format --> namespace:expiry:documentType
+func expiryKey(ctx context.Context, documentType entity.DocumentType) string {
+	return keys(ctx).ExpiryKey(string(documentType))
+}
@@ -24,11 +24,9 @@ import (
 	"net/http"
 	"sort"
 	"strings"
-
-	"github.com/SurgicalSteel/elasthink/entity"
 )
 
-func validateKeywordSuggestionRequest(documentType, prefix string) error {
+func validateKeywordSuggestionRequest(ctx context.Context, documentType, prefix string) error {
 	if len(strings.Trim(prefix, " ")) == 0 {
 		return errors.New("Keyword prefix is required to get suggested keywords")
 	}
@@ -37,7 +35,7 @@ func validateKeywordSuggestionRequest(documentType, prefix string) error {
 		return errors.New("Document Type is required")
 	}
 
-	err := validateDocumentType(documentType, entity.Entity.GetDocumentTypes())
+	err := validateDocumentType(documentType, documentTypes(ctx))
 	if err != nil {
 		return err
 	}
@@ -52,7 +50,7 @@ type KeywordSuggestionResponsePayload struct {
 
 //SuggestKeywords is the core function for keyword suggestion (by document type and prefix)
 func SuggestKeywords(ctx context.Context, documentType, prefix string) Response {
-	err := validateKeywordSuggestionRequest(documentType, prefix)
+	err := validateKeywordSuggestionRequest(ctx, documentType, prefix)
 	if err != nil {
 		return Response{
 			StatusCode:   http.StatusBadRequest,
@@ -61,15 +59,29 @@ func SuggestKeywords(ctx context.Context, documentType, prefix string) Response
 		}
 	}
 	prefix = strings.ToLower(prefix)
-	docType := getDocumentType(documentType, entity.Entity.GetDocumentTypes())
-	keywords, err := fetchKeywords(docType, prefix)
+	docType := getDocumentType(documentType, documentTypes(ctx))
+	keywords, err := fetchKeywords(ctx, docType, prefix)
 	if err != nil {
+		if isContextError(err) {
+			return contextErrorResponse(err)
+		}
 		return Response{
 			StatusCode:   http.StatusInternalServerError,
 			ErrorMessage: "There's an error when suggesting keywords.",
 			Data:         nil,
 		}
 	}
+
+	//keywords that became runtime stopwords are not suggested, even before their index keys are purged
+	runtimeStopwords := runtimeStopwordSet(ctx, docType)
+	suggestedKeywords := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if _, ok := runtimeStopwords[keyword]; !ok {
+			suggestedKeywords = append(suggestedKeywords, keyword)
+		}
+	}
+	keywords = suggestedKeywords
+
 	sort.Strings(keywords)
 	return Response{
 		StatusCode: http.StatusOK,
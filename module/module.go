@@ -19,24 +19,111 @@ package module
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SurgicalSteel/elasthink/config"
 	"github.com/SurgicalSteel/elasthink/entity"
-	"github.com/SurgicalSteel/elasthink/redis"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/storage"
 	"github.com/SurgicalSteel/elasthink/util"
 )
 
 //Module is the main struct to represent a core module
 type Module struct {
-	StopwordSet            map[string]int
-	Redis                  *redis.Redis
+	Storage                storage.Storage
 	IsUsingStopwordRemoval bool
+	Keys                   storage.KeyBuilder
+
+	settings         atomic.Value
+	settingsMutex    sync.Mutex
+	runtimeStopwords *runtimeStopwordCache
+}
+
+//Settings are the reloadable settings of the module. They are swapped as a whole, so a request always works with one version of them
+type Settings struct {
+	StopwordSet     map[string]int
+	TenantsByAPIKey map[string]entity.Tenant
+	DocumentFields  map[entity.DocumentType][]entity.DocumentField
+	DocumentIDTypes map[entity.DocumentType]entity.DocumentIDType
 }
 
 var moduleObj *Module
 
+var moduleLogger = logger.New("module")
+
 //InitModule is a function that initializes a module object and its requirements (dependencies)
-func InitModule(stopwordData entity.StopwordData, redisObject *redis.Redis, stopwordRemovalUsage bool) {
+//keyBuilder builds every key under the configured namespace (and hash tag on a redis cluster)
+func InitModule(stopwordData entity.StopwordData, storageObject storage.Storage, stopwordRemovalUsage bool, keyBuilder storage.KeyBuilder) {
 	moduleObj = new(Module)
-	moduleObj.StopwordSet = util.CreateWordSet(stopwordData.Words)
-	moduleObj.Redis = redisObject
+	moduleObj.Storage = storageObject
 	moduleObj.IsUsingStopwordRemoval = stopwordRemovalUsage
+	moduleObj.Keys = keyBuilder
+	moduleObj.runtimeStopwords = newRuntimeStopwordCache()
+	moduleObj.settings.Store(&Settings{
+		StopwordSet:     util.CreateWordSet(stopwordData.Words),
+		TenantsByAPIKey: make(map[string]entity.Tenant),
+		DocumentFields:  make(map[entity.DocumentType][]entity.DocumentField),
+		DocumentIDTypes: make(map[entity.DocumentType]entity.DocumentIDType),
+	})
+}
+
+const settingsContextKey contextKey = "settings"
+
+//WithSettings returns a copy of ctx that keeps the settings in use, so every module call of the request works with the same settings even when they are reloaded meanwhile.
+//ctx is returned as is when it already keeps settings.
+func WithSettings(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(settingsContextKey).(*Settings); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, settingsContextKey, loadSettings())
+}
+
+//currentSettings gets the settings kept by ctx (see WithSettings), or the settings in use when ctx keeps none
+func currentSettings(ctx context.Context) *Settings {
+	if settings, ok := ctx.Value(settingsContextKey).(*Settings); ok {
+		return settings
+	}
+	return loadSettings()
+}
+
+func loadSettings() *Settings {
+	return moduleObj.settings.Load().(*Settings)
+}
+
+//updateSettings swaps in a copy of the settings in use, changed by update
+func updateSettings(update func(settings *Settings)) {
+	moduleObj.settingsMutex.Lock()
+	defer moduleObj.settingsMutex.Unlock()
+	newSettings := *loadSettings()
+	update(&newSettings)
+	moduleObj.settings.Store(&newSettings)
+}
+
+//ReloadSettings builds the stopwords, the tenants and the document fields (and id types) again and swaps them in at once.
+//Requests whose context keeps settings (see WithSettings) finish with them, and the settings in use are kept when the tenant or document config is invalid.
+func ReloadSettings(stopwordData entity.StopwordData, tenantConfig config.TenantConfigWrap, documentConfig config.DocumentConfigWrap) error {
+	tenantsByAPIKey, err := buildTenants(tenantConfig)
+	if err != nil {
+		return err
+	}
+	fieldsByDocumentType, err := buildDocumentFields(documentConfig)
+	if err != nil {
+		return err
+	}
+	idTypesByDocumentType, err := buildDocumentIDTypes(documentConfig)
+	if err != nil {
+		return err
+	}
+
+	moduleObj.settingsMutex.Lock()
+	defer moduleObj.settingsMutex.Unlock()
+	moduleObj.settings.Store(&Settings{
+		StopwordSet:     util.CreateWordSet(stopwordData.Words),
+		TenantsByAPIKey: tenantsByAPIKey,
+		DocumentFields:  fieldsByDocumentType,
+		DocumentIDTypes: idTypesByDocumentType,
+	})
+	return nil
 }
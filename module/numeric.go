@@ -0,0 +1,143 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+)
+
+//NumericValueNow is the numeric value of the current time (in unix seconds), e.g. to filter documents active now
+const NumericValueNow string = "now"
+
+//numericDateLayouts are the accepted formats of a date numeric value
+var numericDateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+//NumericValue is the value of a numeric attribute. It can be sent as a number, a date (RFC 3339 or yyyy-mm-dd, stored as unix seconds) or "now"
+type NumericValue float64
+
+//UnmarshalJSON reads the numeric value from a number, a date or "now"
+func (nv *NumericValue) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*nv = NumericValue(number)
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("Numeric value must be a number or a date")
+	}
+	value = strings.Trim(value, " ")
+	if strings.ToLower(value) == NumericValueNow {
+		*nv = NumericValue(time.Now().Unix())
+		return nil
+	}
+	for _, layout := range numericDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			*nv = NumericValue(date.Unix())
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid numeric value %s", value)
+}
+
+//normalizeNumericAttributes validates the numeric attributes of a document and lowercases their names
+func normalizeNumericAttributes(attributes map[string]NumericValue) (map[string]float64, error) {
+	result := make(map[string]float64)
+	for name, value := range attributes {
+		name = strings.ToLower(strings.Trim(name, " "))
+		if !attributeNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("Invalid numeric attribute %s", name)
+		}
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return nil, fmt.Errorf("Invalid value of numeric attribute %s", name)
+		}
+		result[name] = float64(value)
+	}
+	return result, nil
+}
+
+//removedNumericAttributes gets the numeric attributes of the old document that the new document does not have anymore
+func removedNumericAttributes(oldAttributes, newAttributes map[string]float64) []string {
+	result := make([]string, 0)
+	for name := range oldAttributes {
+		if _, ok := newAttributes[name]; !ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+//formatScoreBound formats a range bound into a sorted set score bound (prefixed by "(" when exclusive)
+func formatScoreBound(value NumericValue, isExclusive bool) string {
+	bound := strconv.FormatFloat(float64(value), 'f', -1, 64)
+	if isExclusive {
+		return "(" + bound
+	}
+	return bound
+}
+
+//scoreRange gets the min and the max score bound of a range filter clause ("-inf" and "+inf" when there's no bound)
+func (fc FilterClause) scoreRange() (string, string) {
+	min, max := "-inf", "+inf"
+	if fc.Gte != nil {
+		min = formatScoreBound(*fc.Gte, false)
+	} else if fc.Gt != nil {
+		min = formatScoreBound(*fc.Gt, true)
+	}
+	if fc.Lte != nil {
+		max = formatScoreBound(*fc.Lte, false)
+	} else if fc.Lt != nil {
+		max = formatScoreBound(*fc.Lt, true)
+	}
+	return min, max
+}
+
+//hasRangeBound tells whether a filter clause has any range bound
+func (fc FilterClause) hasRangeBound() bool {
+	return fc.Gte != nil || fc.Gt != nil || fc.Lte != nil || fc.Lt != nil
+}
+
+//validateRangeClause validates the bounds of a range filter clause
+func validateRangeClause(filter FilterClause, attribute string) error {
+	if len(filter.clauseValues()) > 0 {
+		return fmt.Errorf("Filter %s on attribute %s does not take values", FilterTypeRange, attribute)
+	}
+	if !filter.hasRangeBound() {
+		return fmt.Errorf("Filter %s on attribute %s requires a bound", FilterTypeRange, attribute)
+	}
+	if (filter.Gte != nil && filter.Gt != nil) || (filter.Lte != nil && filter.Lt != nil) {
+		return fmt.Errorf("Filter %s on attribute %s has duplicate bounds", FilterTypeRange, attribute)
+	}
+	return nil
+}
+
+//fetchRangeDocumentIDs fetches the ids of the documents whose numeric attribute is within the range of a filter clause
+func fetchRangeDocumentIDs(ctx context.Context, documentType entity.DocumentType, attribute string, filter FilterClause) ([]string, error) {
+	min, max := filter.scoreRange()
+	return readStorage(ctx).ZRangeByScore(ctx, rangeKey(ctx, documentType, attribute), min, max)
+}
@@ -0,0 +1,181 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericValueUnmarshalJSON(t *testing.T) {
+	var payload CreateIndexRequestPayload
+	err := json.Unmarshal([]byte(`{"documentName": "Diskon Kopi", "numericAttributes": {"price": 15000.5, "start_date": "2020-01-02", "end_date": "2020-01-02T10:00:00+07:00"}}`), &payload)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]NumericValue{
+		"price":      15000.5,
+		"start_date": 1577923200,
+		"end_date":   1577934000,
+	}, payload.NumericAttributes)
+
+	var clause FilterClause
+	before := time.Now().Unix()
+	err = json.Unmarshal([]byte(`{"type": "range", "attribute": "end_date", "gte": "now"}`), &clause)
+	assert.Nil(t, err)
+	assert.True(t, float64(*clause.Gte) >= float64(before))
+	assert.True(t, float64(*clause.Gte) <= float64(time.Now().Unix()))
+
+	err = json.Unmarshal([]byte(`{"numericAttributes": {"price": "murah"}}`), &payload)
+	assert.NotNil(t, err)
+}
+
+func TestValidateRangeClause(t *testing.T) {
+	lower, upper := NumericValue(10), NumericValue(20)
+
+	type tcase struct {
+		filter        FilterClause
+		expectedMin   string
+		expectedMax   string
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["inclusive bounds"] = tcase{
+		filter:      FilterClause{Type: "range", Attribute: "price", Gte: &lower, Lte: &upper},
+		expectedMin: "10",
+		expectedMax: "20",
+	}
+	testCases["exclusive upper bound only"] = tcase{
+		filter:      FilterClause{Type: "range", Attribute: "price", Lt: &upper},
+		expectedMin: "-inf",
+		expectedMax: "(20",
+	}
+	testCases["no bound"] = tcase{
+		filter:        FilterClause{Type: "range", Attribute: "price"},
+		expectedError: true,
+	}
+	testCases["duplicate bounds"] = tcase{
+		filter:        FilterClause{Type: "range", Attribute: "price", Gte: &lower, Gt: &lower},
+		expectedError: true,
+	}
+	testCases["range with value"] = tcase{
+		filter:        FilterClause{Type: "range", Attribute: "price", Value: "10", Gte: &lower},
+		expectedError: true,
+	}
+	testCases["term with bound"] = tcase{
+		filter:        FilterClause{Type: "term", Attribute: "price", Value: "10", Gte: &lower},
+		expectedError: true,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on validateFilterClauses with range test case:", ktc)
+		err := validateFilterClauses([]FilterClause{vtc.filter})
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		actualMin, actualMax := vtc.filter.scoreRange()
+		assert.Equal(t, vtc.expectedMin, actualMin)
+		assert.Equal(t, vtc.expectedMax, actualMax)
+	}
+}
+
+func TestSearchWithRangeFilters(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	now := time.Now().Unix()
+	documents := map[string]map[string]NumericValue{
+		"1": {"price": 50000, "start_date": NumericValue(now - 3600), "end_date": NumericValue(now + 3600)},
+		"2": {"price": 150000, "start_date": NumericValue(now - 3600), "end_date": NumericValue(now + 3600)},
+		"3": {"price": 75000, "start_date": NumericValue(now - 7200), "end_date": NumericValue(now - 3600)},
+		"4": {"price": 100000},
+	}
+	for documentID, numericAttributes := range documents {
+		response := CreateIndex(ctx, documentID, "campaign", CreateIndexRequestPayload{DocumentName: "Diskon Kopi", NumericAttributes: numericAttributes})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+
+	maxPrice := NumericValue(100000)
+	current := NumericValue(now)
+
+	type tcase struct {
+		filters     []FilterClause
+		expectedIDs []string
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["price under 100k"] = tcase{
+		filters:     []FilterClause{{Type: "range", Attribute: "price", Lt: &maxPrice}},
+		expectedIDs: []string{"1", "3"},
+	}
+	testCases["price up to 100k"] = tcase{
+		filters:     []FilterClause{{Type: "range", Attribute: "price", Lte: &maxPrice}},
+		expectedIDs: []string{"1", "3", "4"},
+	}
+	testCases["active now"] = tcase{
+		filters: []FilterClause{
+			{Type: "range", Attribute: "start_date", Lte: &current},
+			{Type: "range", Attribute: "end_date", Gte: &current},
+		},
+		expectedIDs: []string{"1", "2"},
+	}
+	testCases["active now under 100k"] = tcase{
+		filters: []FilterClause{
+			{Type: "range", Attribute: "start_date", Lte: &current},
+			{Type: "range", Attribute: "end_date", Gte: &current},
+			{Type: "range", Attribute: "price", Lt: &maxPrice},
+		},
+		expectedIDs: []string{"1"},
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on Search with range filters with test case:", ktc)
+		response := Search(ctx, "campaign", SearchRequestPayload{SearchTerm: "kopi", Filters: vtc.filters})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		actualIDs := make([]string, 0)
+		for _, result := range response.Data.(SearchResponsePayload).RankedResultList {
+			actualIDs = append(actualIDs, result.ID.String())
+		}
+		assert.Equal(t, vtc.expectedIDs, actualIDs)
+	}
+
+	//a numeric attribute missing from the new document is removed from its sorted set, a kept one gets its new score
+	response := UpdateIndex(ctx, "2", "campaign", UpdateIndexRequestPayload{
+		OldDocumentName:      "Diskon Kopi",
+		NewDocumentName:      "Diskon Kopi",
+		OldNumericAttributes: map[string]NumericValue{"price": 150000, "end_date": NumericValue(now + 3600)},
+		NewNumericAttributes: map[string]NumericValue{"price": 90000},
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	scores, _ := moduleObj.Storage.ZMembersWithScores(ctx, rangeKey(ctx, "campaign", "price"))
+	assert.Equal(t, float64(90000), scores["2"])
+	endDates, _ := moduleObj.Storage.ZMembersWithScores(ctx, rangeKey(ctx, "campaign", "end_date"))
+	_, ok := endDates["2"]
+	assert.False(t, ok)
+
+	response = CreateIndex(ctx, "5", "campaign", CreateIndexRequestPayload{DocumentName: "Diskon Kopi", NumericAttributes: map[string]NumericValue{"Harga Promo": 1}})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -18,8 +18,11 @@ package module
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
-	"github.com/SurgicalSteel/elasthink/entity"
+	"context"
 	"sort"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/logger"
 )
 
 //RankByShowCount is the additional struct for document ranking purpose based on its ShowCount
@@ -29,32 +32,50 @@ func (r RankByShowCount) Len() int           { return len(r) }
 func (r RankByShowCount) Less(i, j int) bool { return r[i].ShowCount > r[j].ShowCount }
 func (r RankByShowCount) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
 
-//rankSearchResult ranks search result (document id by its appeareance count). word indexes is a map with word as a key and slice of ids as value. Returns ordered search result rank slice.
-func rankSearchResult(wordIndexes map[string][]int64) []entity.SearchResultRankData {
-	counterMap := make(map[int64]int)
-	for _, ids := range wordIndexes {
-		for i := 0; i < len(ids); i++ {
-			if vcm, ok := counterMap[ids[i]]; ok {
-				counterMap[ids[i]] = vcm + 1
-			} else {
-				counterMap[ids[i]] = 1
-			}
+//RankByScore is the additional struct for document ranking purpose based on its Score, then its ShowCount (and its ID for the same score and show count)
+type RankByScore []entity.SearchResultRankData
+
+func (r RankByScore) Len() int { return len(r) }
+func (r RankByScore) Less(i, j int) bool {
+	if r[i].Score != r[j].Score {
+		return r[i].Score > r[j].Score
+	}
+	if r[i].ShowCount != r[j].ShowCount {
+		return r[i].ShowCount > r[j].ShowCount
+	}
+	return r[i].ID.Less(r[j].ID)
+}
+func (r RankByScore) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+
+//rankSearchResult ranks search result (document id by its score, which is the sum of the boosts of the fields where each word is found). word scores is a map with word as a key and the score of each document id as value.
+//Document ids are parsed with the id type of the document type, an id that is not valid for it is skipped. Returns ordered search result rank slice.
+func rankSearchResult(ctx context.Context, wordScores map[string]map[string]float64, idType entity.DocumentIDType) []entity.SearchResultRankData {
+	counterMap := make(map[string]int)
+	scoreMap := make(map[string]float64)
+	for _, scores := range wordScores {
+		for id, score := range scores {
+			counterMap[id]++
+			scoreMap[id] += score
 		}
 	}
 
-	result := make([]entity.SearchResultRankData, len(counterMap))
+	result := make([]entity.SearchResultRankData, 0, len(counterMap))
 
-	iterator := 0
 	for kcm, vcm := range counterMap {
-		result[iterator] = entity.SearchResultRankData{
-			ID:        kcm,
-			ShowCount: vcm,
+		documentID, err := entity.ParseDocumentID(kcm, idType)
+		if err != nil {
+			moduleLogger.Warn(ctx, "Skipping invalid document id", logger.Fields{"document_id": kcm, "id_type": idType})
+			continue
 		}
-		iterator++
+		result = append(result, entity.SearchResultRankData{
+			ID:        documentID,
+			ShowCount: vcm,
+			Score:     scoreMap[kcm],
+		})
 	}
 
-	//sort by appeareance count (descending)
-	sort.Sort(RankByShowCount(result))
+	//sort by score (descending)
+	sort.Sort(RankByScore(result))
 
 	//assign rank to each search result data
 	for i := 0; i < len(result); i++ {
@@ -17,7 +17,11 @@ package module
 //
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
-import ()
+import (
+	"context"
+	"errors"
+	"net/http"
+)
 
 //Response is the universal response struct for all API
 type Response struct {
@@ -25,3 +29,27 @@ type Response struct {
 	ErrorMessage string
 	Data         interface{}
 }
+
+//StatusClientClosedRequest is the (non standard) status code of a request whose client has gone before the response is written
+const StatusClientClosedRequest int = 499
+
+//isContextError tells whether err is caused by a done context (deadline exceeded or cancelled)
+func isContextError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+//contextErrorResponse builds the response of a request whose context is done: 504 when its deadline is exceeded, 499 when its client has gone
+func contextErrorResponse(err error) Response {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Response{
+			StatusCode:   http.StatusGatewayTimeout,
+			ErrorMessage: "Request Timeout",
+			Data:         nil,
+		}
+	}
+	return Response{
+		StatusCode:   StatusClientClosedRequest,
+		ErrorMessage: "Client Closed Request",
+		Data:         nil,
+	}
+}
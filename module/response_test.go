@@ -0,0 +1,61 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextErrorResponse(t *testing.T) {
+	type tcase struct {
+		err                error
+		expectedIsContext  bool
+		expectedStatusCode int
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["deadline exceeded"] = tcase{
+		err:                context.DeadlineExceeded,
+		expectedIsContext:  true,
+		expectedStatusCode: http.StatusGatewayTimeout,
+	}
+
+	testCases["wrapped cancellation"] = tcase{
+		err:                fmt.Errorf("failed to get members: %w", context.Canceled),
+		expectedIsContext:  true,
+		expectedStatusCode: StatusClientClosedRequest,
+	}
+
+	testCases["redis failure"] = tcase{
+		err: errors.New("connection refused"),
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on contextErrorResponse with test case:", ktc)
+		assert.Equal(t, vtc.expectedIsContext, isContextError(vtc.err))
+		if vtc.expectedIsContext {
+			assert.Equal(t, vtc.expectedStatusCode, contextErrorResponse(vtc.err).StatusCode)
+		}
+	}
+}
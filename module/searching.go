@@ -22,17 +22,27 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/SurgicalSteel/elasthink/entity"
-	"github.com/SurgicalSteel/elasthink/util"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/metrics"
 )
 
-//SearchRequestPayload is the universal request payload for search handlers
+//SearchRequestPayload is the universal request payload for search handlers. Fields are the names of the fields to search in, every field is searched when it is empty.
+//Filters are the filter clauses on the document attributes, a document must pass every clause. Facets are the keyword attributes whose values are counted over the matched documents.
+//IncludeDocument returns the stored values of the fields of every result, Highlight returns the searched fields of every result with the search words wrapped in tags, and Explain returns how every result is matched, scored and ranked
 type SearchRequestPayload struct {
-	SearchTerm string `json:"searchTerm"`
+	SearchTerm      string            `json:"searchTerm"`
+	Fields          []string          `json:"fields,omitempty"`
+	Filters         []FilterClause    `json:"filters,omitempty"`
+	Facets          []FacetRequest    `json:"facets,omitempty"`
+	IncludeDocument bool              `json:"includeDocument,omitempty"`
+	Highlight       *HighlightRequest `json:"highlight,omitempty"`
+	Explain         bool              `json:"explain,omitempty"`
 }
 
-func validateSearchRequestPayload(documentType, searchTerm string) error {
+func validateSearchRequestPayload(ctx context.Context, documentType, searchTerm string) error {
 	if len(strings.Trim(searchTerm, " ")) == 0 {
 		return errors.New("Search Term is required")
 	}
@@ -41,7 +51,7 @@ func validateSearchRequestPayload(documentType, searchTerm string) error {
 		return errors.New("Document Type is required")
 	}
 
-	err := validateDocumentType(documentType, entity.Entity.GetDocumentTypes())
+	err := validateDocumentType(documentType, documentTypes(ctx))
 	if err != nil {
 		return err
 	}
@@ -49,14 +59,86 @@ func validateSearchRequestPayload(documentType, searchTerm string) error {
 	return nil
 }
 
-//SearchResponsePayload is the universal response payload for search handlers
+//SearchResponsePayload is the universal response payload for search handlers. Facets are only returned when they are requested
 type SearchResponsePayload struct {
 	RankedResultList []entity.SearchResultRankData `json:"rankedResultList"`
+	Facets           []FacetResult                 `json:"facets,omitempty"`
+}
+
+//searchQuery is a validated search: the document type, the fields to search in and the words of the search term (without stopwords)
+type searchQuery struct {
+	docType              entity.DocumentType
+	fields               []entity.DocumentField
+	isTargetingAllFields bool
+	searchTermSet        map[string]int
+}
+
+//prepareSearch validates the search request payload and tokenizes its search term
+func prepareSearch(ctx context.Context, documentType string, requestPayload SearchRequestPayload) (searchQuery, error) {
+	var query searchQuery
+	err := validateSearchRequestPayload(ctx, documentType, requestPayload.SearchTerm)
+	if err != nil {
+		return query, err
+	}
+
+	query.docType = getDocumentType(documentType, documentTypes(ctx))
+	query.fields, err = targetFields(ctx, query.docType, requestPayload.Fields)
+	if err != nil {
+		return query, err
+	}
+	query.isTargetingAllFields = len(requestPayload.Fields) == 0
+
+	err = validateFilterClauses(requestPayload.Filters)
+	if err != nil {
+		return query, err
+	}
+	err = validateFacetRequests(requestPayload.Facets)
+	if err != nil {
+		return query, err
+	}
+	err = validateHighlightRequest(requestPayload.Highlight)
+	if err != nil {
+		return query, err
+	}
+
+	query.searchTermSet = requestStopwordSets(ctx, query.docType).tokenize(requestPayload.SearchTerm)
+	return query, nil
+}
+
+//matchSearch fetches the fields where every word of the search is found in each document, and the filter of the filter clauses and the expired documents (which is not applied yet)
+func matchSearch(ctx context.Context, query searchQuery, filters []FilterClause) (map[string]map[string][]entity.FieldMatch, documentFilter, error) {
+	wordMatches, err := fetchWordMatches(ctx, query.docType, query.searchTermSet, query.fields, query.isTargetingAllFields)
+	if err != nil {
+		return nil, documentFilter{}, err
+	}
+
+	filter, err := fetchDocumentFilter(ctx, query.docType, filters)
+	if err != nil {
+		return nil, documentFilter{}, err
+	}
+	err = filter.removeExpiredDocuments(ctx, query.docType, wordMatches, time.Now())
+	if err != nil {
+		return nil, documentFilter{}, err
+	}
+	return wordMatches, filter, nil
+}
+
+//matchErrorResponse is the response of a search whose words or filters fail to be fetched
+func matchErrorResponse(ctx context.Context, documentType entity.DocumentType, err error) Response {
+	if isContextError(err) {
+		return contextErrorResponse(err)
+	}
+	moduleLogger.Error(ctx, "Failed to filter documents", logger.Fields{"document_type": documentType, "error": err})
+	return Response{
+		StatusCode:   http.StatusInternalServerError,
+		ErrorMessage: "There's an error when filtering documents.",
+		Data:         nil,
+	}
 }
 
 //Search is the core function of searching a document
 func Search(ctx context.Context, documentType string, requestPayload SearchRequestPayload) Response {
-	err := validateSearchRequestPayload(documentType, requestPayload.SearchTerm)
+	query, err := prepareSearch(ctx, documentType, requestPayload)
 	if err != nil {
 		return Response{
 			StatusCode:   http.StatusBadRequest,
@@ -64,8 +146,8 @@ func Search(ctx context.Context, documentType string, requestPayload SearchReque
 			Data:         nil,
 		}
 	}
+	docType, fields, searchTermSet := query.docType, query.fields, query.searchTermSet
 
-	searchTermSet := util.Tokenize(requestPayload.SearchTerm, moduleObj.IsUsingStopwordRemoval, moduleObj.StopwordSet)
 	if len(searchTermSet) == 0 {
 		return Response{
 			StatusCode:   http.StatusOK,
@@ -74,11 +156,16 @@ func Search(ctx context.Context, documentType string, requestPayload SearchReque
 		}
 	}
 
-	docType := getDocumentType(documentType, entity.Entity.GetDocumentTypes())
-
-	wordIndexSets := fetchWordIndexSets(docType, searchTermSet)
+	//filter clauses and expired documents are applied on the matched documents before ranking
+	wordMatches, filter, err := matchSearch(ctx, query, requestPayload.Filters)
+	if err != nil {
+		return matchErrorResponse(ctx, docType, err)
+	}
+	wordScores := wordScoresOf(wordMatches)
+	filter.apply(wordScores)
 
-	if len(wordIndexSets) == 0 {
+	if len(wordScores) == 0 {
+		metrics.ObserveSearchResult(string(docType), 0)
 		return Response{
 			StatusCode:   http.StatusOK,
 			ErrorMessage: "",
@@ -86,9 +173,45 @@ func Search(ctx context.Context, documentType string, requestPayload SearchReque
 		}
 	}
 
-	rankedSearchResult := rankSearchResult(wordIndexSets)
+	rankedSearchResult := rankSearchResult(ctx, wordScores, documentIDType(ctx, docType))
+	metrics.ObserveSearchResult(string(docType), len(rankedSearchResult))
 	searchResponsePayload := SearchResponsePayload{RankedResultList: rankedSearchResult}
 
+	if requestPayload.Explain {
+		explainSearchResult(rankedSearchResult, searchTermSet, wordMatches, wordScores)
+	}
+
+	if requestPayload.IncludeDocument || requestPayload.Highlight != nil {
+		err = attachDocuments(ctx, docType, rankedSearchResult, searchTermSet, fields, requestPayload.IncludeDocument, requestPayload.Highlight)
+		if err != nil {
+			if isContextError(err) {
+				return contextErrorResponse(err)
+			}
+			moduleLogger.Error(ctx, "Failed to fetch stored documents", logger.Fields{"document_type": docType, "error": err})
+			return Response{
+				StatusCode:   http.StatusInternalServerError,
+				ErrorMessage: "There's an error when fetching documents.",
+				Data:         nil,
+			}
+		}
+	}
+
+	//facets are counted over every matched document
+	if len(requestPayload.Facets) > 0 {
+		searchResponsePayload.Facets, err = fetchFacets(ctx, docType, requestPayload.Facets, rankedSearchResult)
+		if err != nil {
+			if isContextError(err) {
+				return contextErrorResponse(err)
+			}
+			moduleLogger.Error(ctx, "Failed to count facets", logger.Fields{"document_type": docType, "error": err})
+			return Response{
+				StatusCode:   http.StatusInternalServerError,
+				ErrorMessage: "There's an error when counting facets.",
+				Data:         nil,
+			}
+		}
+	}
+
 	return Response{
 		StatusCode:   http.StatusOK,
 		ErrorMessage: "",
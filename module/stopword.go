@@ -0,0 +1,311 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/storage"
+	"github.com/SurgicalSteel/elasthink/util"
+)
+
+//runtimeStopwordRefreshInterval is how long the runtime stopwords of a document type are cached, stopwords changed on another instance are picked up after it
+const runtimeStopwordRefreshInterval time.Duration = 5 * time.Second
+
+//stopwordRegex is the format of a stopword, which is a single word produced by the tokenizer
+var stopwordRegex = regexp.MustCompile(`^[a-z0-9]+$`)
+
+//StopwordRequestPayload is the request payload to add or remove runtime stopwords of a document type
+type StopwordRequestPayload struct {
+	Words []string `json:"words"`
+}
+
+//StopwordResponsePayload is the response payload of the runtime stopwords API.
+//AffectedCount is the number of stopwords actually added or removed. IndexKeys are the existing inverted index keys of the added stopwords, which can be purged (only when they are reported)
+type StopwordResponsePayload struct {
+	Words         []string `json:"words"`
+	AffectedCount int64    `json:"affectedCount"`
+	IndexKeys     []string `json:"indexKeys,omitempty"`
+}
+
+type runtimeStopwordCacheEntry struct {
+	wordSet   map[string]int
+	fetchedAt time.Time
+}
+
+//runtimeStopwordCache caches the runtime stopword set of every stopword key
+type runtimeStopwordCache struct {
+	mutex   sync.RWMutex
+	entries map[string]runtimeStopwordCacheEntry
+}
+
+func newRuntimeStopwordCache() *runtimeStopwordCache {
+	return &runtimeStopwordCache{
+		entries: make(map[string]runtimeStopwordCacheEntry),
+	}
+}
+
+func (rc *runtimeStopwordCache) get(key string) (runtimeStopwordCacheEntry, bool) {
+	rc.mutex.RLock()
+	defer rc.mutex.RUnlock()
+	entry, ok := rc.entries[key]
+	return entry, ok
+}
+
+func (rc *runtimeStopwordCache) set(key string, wordSet map[string]int) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.entries[key] = runtimeStopwordCacheEntry{wordSet: wordSet, fetchedAt: time.Now()}
+}
+
+func (rc *runtimeStopwordCache) invalidate(key string) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	delete(rc.entries, key)
+}
+
+//runtimeStopwordSet gets the runtime stopwords of a document type (cached for runtimeStopwordRefreshInterval).
+//When they fail to be fetched, the last fetched stopwords are used (or none), so searching and indexing keep working
+func runtimeStopwordSet(ctx context.Context, documentType entity.DocumentType) map[string]int {
+	key := stopwordKey(ctx, documentType)
+	entry, ok := moduleObj.runtimeStopwords.get(key)
+	if ok && time.Since(entry.fetchedAt) < runtimeStopwordRefreshInterval {
+		return entry.wordSet
+	}
+
+	members, err := readStorage(ctx).SMembers(ctx, key)
+	if err != nil {
+		moduleLogger.Warn(ctx, "Failed to get runtime stopwords", logger.Fields{"key": key, "error": err})
+		if ok {
+			return entry.wordSet
+		}
+		return make(map[string]int)
+	}
+
+	wordSet := util.CreateWordSet(members)
+	moduleObj.runtimeStopwords.set(key, wordSet)
+	return wordSet
+}
+
+//stopwordSets are the stopwords of a request: the stopwords file words (removed when stopword removal is used) and the runtime stopwords of the document type (always removed)
+type stopwordSets struct {
+	fileSet    map[string]int
+	runtimeSet map[string]int
+}
+
+func requestStopwordSets(ctx context.Context, documentType entity.DocumentType) stopwordSets {
+	return stopwordSets{
+		fileSet:    currentSettings(ctx).StopwordSet,
+		runtimeSet: runtimeStopwordSet(ctx, documentType),
+	}
+}
+
+//tokenize tokenizes a document name or a search term without the stopwords of the request
+func (ss stopwordSets) tokenize(s string) map[string]int {
+	wordSet := util.Tokenize(s, moduleObj.IsUsingStopwordRemoval, ss.fileSet)
+	return util.WordsSetSubtraction(wordSet, ss.runtimeSet)
+}
+
+//tokenizeFields tokenizes the value of every field without the stopwords of the request
+func (ss stopwordSets) tokenizeFields(fieldValues map[string]string) map[string]map[string]int {
+	fieldWordSets := make(map[string]map[string]int)
+	for field, value := range fieldValues {
+		fieldWordSets[field] = ss.tokenize(value)
+	}
+	return fieldWordSets
+}
+
+func validateStopwordDocumentType(ctx context.Context, documentType string) error {
+	if len(strings.Trim(documentType, " ")) == 0 {
+		return errors.New("Document Type is required")
+	}
+	return validateDocumentType(documentType, documentTypes(ctx))
+}
+
+//normalizeStopwords lowercases and trims the given words, every word must be a single word of letters and digits
+func normalizeStopwords(words []string) ([]string, error) {
+	if len(words) == 0 {
+		return nil, errors.New("Words are required")
+	}
+
+	wordSet := make(map[string]int)
+	for _, word := range words {
+		word = strings.ToLower(strings.Trim(word, " "))
+		if !stopwordRegex.MatchString(word) {
+			return nil, fmt.Errorf("Invalid stopword %s", word)
+		}
+		wordSet[word] = 1
+	}
+
+	normalizedWords := make([]string, 0, len(wordSet))
+	for word := range wordSet {
+		normalizedWords = append(normalizedWords, word)
+	}
+	sort.Strings(normalizedWords)
+	return normalizedWords, nil
+}
+
+func stopwordErrorResponse(err error, errorMessage string) Response {
+	if isContextError(err) {
+		return contextErrorResponse(err)
+	}
+	return Response{
+		StatusCode:   http.StatusInternalServerError,
+		ErrorMessage: errorMessage,
+		Data:         nil,
+	}
+}
+
+//GetStopwords is the core function to list the runtime stopwords of a document type
+func GetStopwords(ctx context.Context, documentType string) Response {
+	err := validateStopwordDocumentType(ctx, documentType)
+	if err != nil {
+		return Response{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+			Data:         nil,
+		}
+	}
+
+	docType := getDocumentType(documentType, documentTypes(ctx))
+	words, err := readStorage(ctx).SMembers(ctx, stopwordKey(ctx, docType))
+	if err != nil {
+		return stopwordErrorResponse(err, "There's an error when getting stopwords.")
+	}
+	sort.Strings(words)
+
+	return Response{
+		StatusCode: http.StatusOK,
+		Data:       StopwordResponsePayload{Words: words},
+	}
+}
+
+//AddStopwords is the core function to add runtime stopwords of a document type, they are removed from the next searches and indexings on every instance.
+//isReportingIndexKeys reports the existing inverted index keys of the added stopwords, so they can be purged
+func AddStopwords(ctx context.Context, documentType string, requestPayload StopwordRequestPayload, isReportingIndexKeys bool) Response {
+	err := validateStopwordDocumentType(ctx, documentType)
+	if err != nil {
+		return Response{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+			Data:         nil,
+		}
+	}
+	words, err := normalizeStopwords(requestPayload.Words)
+	if err != nil {
+		return Response{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+			Data:         nil,
+		}
+	}
+
+	docType := getDocumentType(documentType, documentTypes(ctx))
+	key := stopwordKey(ctx, docType)
+	addedCount, err := moduleObj.Storage.SAdd(ctx, key, util.SliceStringToInterface(words))
+	moduleObj.runtimeStopwords.invalidate(key)
+	if err != nil {
+		return stopwordErrorResponse(err, "There's an error when adding stopwords.")
+	}
+
+	responsePayload := StopwordResponsePayload{
+		Words:         words,
+		AffectedCount: addedCount,
+	}
+	if isReportingIndexKeys {
+		responsePayload.IndexKeys, err = existingIndexKeys(ctx, docType, words)
+		if err != nil {
+			return stopwordErrorResponse(err, "There's an error when reporting index keys of stopwords.")
+		}
+	}
+
+	return Response{
+		StatusCode: http.StatusOK,
+		Data:       responsePayload,
+	}
+}
+
+//RemoveStopwords is the core function to remove runtime stopwords of a document type
+func RemoveStopwords(ctx context.Context, documentType string, requestPayload StopwordRequestPayload) Response {
+	err := validateStopwordDocumentType(ctx, documentType)
+	if err != nil {
+		return Response{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+			Data:         nil,
+		}
+	}
+	words, err := normalizeStopwords(requestPayload.Words)
+	if err != nil {
+		return Response{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+			Data:         nil,
+		}
+	}
+
+	docType := getDocumentType(documentType, documentTypes(ctx))
+	key := stopwordKey(ctx, docType)
+	removedCount, err := moduleObj.Storage.SRem(ctx, key, util.SliceStringToInterface(words))
+	moduleObj.runtimeStopwords.invalidate(key)
+	if err != nil {
+		return stopwordErrorResponse(err, "There's an error when removing stopwords.")
+	}
+
+	return Response{
+		StatusCode: http.StatusOK,
+		Data: StopwordResponsePayload{
+			Words:         words,
+			AffectedCount: removedCount,
+		},
+	}
+}
+
+//existingIndexKeys gets the inverted index keys and the field index keys of the words that exist (on the primary storage)
+func existingIndexKeys(ctx context.Context, documentType entity.DocumentType, words []string) ([]string, error) {
+	candidateKeys := make([]string, 0)
+	for _, word := range words {
+		candidateKeys = append(candidateKeys, invertedIndexKey(ctx, documentType, word))
+		for _, field := range documentFields(ctx, documentType) {
+			candidateKeys = append(candidateKeys, fieldIndexKey(ctx, documentType, field.Name, word))
+		}
+	}
+
+	indexKeys := make([]string, 0)
+	primaryStorage := storage.Primary(moduleObj.Storage)
+	for _, key := range candidateKeys {
+		keyType, err := primaryStorage.Type(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if keyType != storage.KeyTypeNone {
+			indexKeys = append(indexKeys, key)
+		}
+	}
+	return indexKeys, nil
+}
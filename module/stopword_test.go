@@ -0,0 +1,114 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/bolt"
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func initTestModuleWithBolt(t *testing.T) {
+	boltObject, err := bolt.InitBolt(config.StorageConfigWrap{
+		BoltElasthink: config.BoltConfig{
+			Path:    filepath.Join(t.TempDir(), "elasthink.db"),
+			Timeout: 1,
+		},
+	})
+	if err != nil {
+		t.Fatal("Expected : ok, but found error! err:", err.Error())
+	}
+	t.Cleanup(func() {
+		boltObject.Close()
+	})
+
+	entity.Entity.Initialize(entity.StopwordData{Words: []string{"yang"}})
+	InitModule(entity.Entity.GetStopwordData(), boltObject, true, storage.NewKeyBuilder("", false))
+}
+
+func TestNormalizeStopwords(t *testing.T) {
+	type tcase struct {
+		words         []string
+		expected      []string
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["no word"] = tcase{expectedError: true}
+	testCases["words with spaces, uppercase and duplicate"] = tcase{
+		words:    []string{" Promo", "diskon", "promo "},
+		expected: []string{"diskon", "promo"},
+	}
+	testCases["more than one word"] = tcase{
+		words:         []string{"promo diskon"},
+		expectedError: true,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on normalizeStopwords with test case:", ktc)
+		actual, err := normalizeStopwords(vtc.words)
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, vtc.expected, actual)
+	}
+}
+
+func TestRuntimeStopwords(t *testing.T) {
+	initTestModuleWithBolt(t)
+	ctx := context.Background()
+
+	response := CreateIndex(ctx, "1", "campaign", CreateIndexRequestPayload{DocumentName: "Promo yang hemat"})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response = AddStopwords(ctx, "campaign", StopwordRequestPayload{Words: []string{"promo", "murah"}}, true)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, StopwordResponsePayload{
+		Words:         []string{"murah", "promo"},
+		AffectedCount: 2,
+		IndexKeys:     []string{"elasthink:inverted:campaign:promo", "elasthink:field:campaign:name:promo"},
+	}, response.Data)
+
+	response = GetStopwords(ctx, "campaign")
+	assert.Equal(t, StopwordResponsePayload{Words: []string{"murah", "promo"}}, response.Data)
+
+	//the runtime stopwords are picked up by the next search, and only apply to their document type
+	assert.Equal(t, map[string]int{"hemat": 1}, requestStopwordSets(ctx, entity.CampaignDocument).tokenize("promo yang hemat"))
+	assert.Equal(t, map[string]int{"promo": 1, "hemat": 1}, requestStopwordSets(ctx, entity.AdvertisementCampaignDocument).tokenize("promo yang hemat"))
+
+	response = SuggestKeywords(ctx, "campaign", "p")
+	assert.Equal(t, []string{}, response.Data)
+
+	response = RemoveStopwords(ctx, "campaign", StopwordRequestPayload{Words: []string{"promo"}})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, int64(1), response.Data.(StopwordResponsePayload).AffectedCount)
+	assert.Equal(t, map[string]int{"promo": 1, "hemat": 1}, requestStopwordSets(ctx, entity.CampaignDocument).tokenize("promo yang hemat"))
+
+	response = AddStopwords(ctx, "voucher", StopwordRequestPayload{Words: []string{"promo"}}, false)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -0,0 +1,136 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/storage"
+)
+
+const tenantContextKey contextKey = "tenant"
+
+//InitTenants initializes the tenants of the module from the tenant config. Every API key is kept as its SHA-256 digest.
+//It fails when an API key is used by several tenants, or a tenant has no API key, an unknown document type or an invalid namespace.
+func InitTenants(tenantConfig config.TenantConfigWrap) error {
+	tenantsByAPIKey, err := buildTenants(tenantConfig)
+	if err != nil {
+		return err
+	}
+
+	updateSettings(func(settings *Settings) {
+		settings.TenantsByAPIKey = tenantsByAPIKey
+	})
+	return nil
+}
+
+//buildTenants builds the tenants (by API key digest) from the tenant config
+func buildTenants(tenantConfig config.TenantConfigWrap) (map[string]entity.Tenant, error) {
+	tenantsByAPIKey := make(map[string]entity.Tenant)
+
+	for name, vt := range tenantConfig.Tenant {
+		if vt == nil {
+			continue
+		}
+		if len(vt.APIKey) == 0 {
+			return nil, fmt.Errorf("Tenant %s has no API key", name)
+		}
+
+		tenant := entity.Tenant{
+			Name:          name,
+			Namespace:     strings.Trim(vt.Namespace, " "),
+			DocumentTypes: make(map[entity.DocumentType]int),
+		}
+		if len(tenant.Namespace) == 0 {
+			tenant.Namespace = fmt.Sprintf("%s%s", moduleObj.Keys.NamespacePrefix(), name)
+		}
+		if err := storage.ValidateNamespace(tenant.Namespace); err != nil {
+			return nil, fmt.Errorf("Tenant %s has an invalid namespace", name)
+		}
+
+		for _, dt := range vt.DocumentType {
+			documentType := entity.DocumentType(strings.ToLower(strings.Trim(dt, " ")))
+			if err := documentType.IsValidFromCustomDocumentType(entity.Entity.GetDocumentTypes()); err != nil {
+				return nil, fmt.Errorf("Tenant %s has an invalid document type %s", name, dt)
+			}
+			tenant.DocumentTypes[documentType] = 1
+		}
+
+		for _, apiKey := range vt.APIKey {
+			digest := apiKeyDigest(apiKey)
+			if _, ok := tenantsByAPIKey[digest]; ok {
+				return nil, fmt.Errorf("API key of tenant %s is already used", name)
+			}
+			tenantsByAPIKey[digest] = tenant
+		}
+	}
+
+	return tenantsByAPIKey, nil
+}
+
+func apiKeyDigest(apiKey string) string {
+	digest := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(digest[:])
+}
+
+//IsUsingTenant tells whether at least one tenant is configured, every request must be scoped to a tenant when it is true
+func IsUsingTenant(ctx context.Context) bool {
+	return len(currentSettings(ctx).TenantsByAPIKey) > 0
+}
+
+//ResolveTenant gets the tenant that owns the API key
+func ResolveTenant(ctx context.Context, apiKey string) (entity.Tenant, bool) {
+	if len(apiKey) == 0 {
+		return entity.Tenant{}, false
+	}
+	tenant, ok := currentSettings(ctx).TenantsByAPIKey[apiKeyDigest(apiKey)]
+	return tenant, ok
+}
+
+//WithTenant returns a copy of ctx scoped to the tenant, so every module call only accesses the tenant's document types and namespace
+func WithTenant(ctx context.Context, tenant entity.Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey, tenant)
+}
+
+//TenantFromContext gets the tenant the request is scoped to (resolved from a valid API key)
+func TenantFromContext(ctx context.Context) (entity.Tenant, bool) {
+	tenant, ok := ctx.Value(tenantContextKey).(entity.Tenant)
+	return tenant, ok
+}
+
+//documentTypes are the document types allowed for the request, which are the tenant's document types when the request is scoped to a tenant
+func documentTypes(ctx context.Context) map[entity.DocumentType]int {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return tenant.DocumentTypes
+	}
+	return entity.Entity.GetDocumentTypes()
+}
+
+//keys is the key builder of the request, under the tenant's namespace when the request is scoped to a tenant
+func keys(ctx context.Context) storage.KeyBuilder {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return moduleObj.Keys.WithNamespace(tenant.Namespace)
+	}
+	return moduleObj.Keys
+}
@@ -0,0 +1,173 @@
+package module
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func initTestModule() {
+	entity.Entity.Initialize(entity.StopwordData{})
+	InitModule(entity.Entity.GetStopwordData(), nil, false, storage.NewKeyBuilder("", false))
+}
+
+func TestInitTenants(t *testing.T) {
+	type tcase struct {
+		tenantConfig  config.TenantConfigWrap
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["no tenant"] = tcase{
+		tenantConfig: config.TenantConfigWrap{},
+	}
+
+	testCases["valid tenants"] = tcase{
+		tenantConfig: config.TenantConfigWrap{
+			Tenant: map[string]*config.TenantConfig{
+				"payment": {APIKey: []string{"key-a", "key-b"}, DocumentType: []string{"campaign"}},
+				"ads":     {APIKey: []string{"key-c"}, DocumentType: []string{"advcampaign"}, Namespace: "ads"},
+			},
+		},
+	}
+
+	testCases["tenant without API key"] = tcase{
+		tenantConfig: config.TenantConfigWrap{
+			Tenant: map[string]*config.TenantConfig{
+				"payment": {DocumentType: []string{"campaign"}},
+			},
+		},
+		expectedError: true,
+	}
+
+	testCases["tenant with unknown document type"] = tcase{
+		tenantConfig: config.TenantConfigWrap{
+			Tenant: map[string]*config.TenantConfig{
+				"payment": {APIKey: []string{"key-a"}, DocumentType: []string{"voucher"}},
+			},
+		},
+		expectedError: true,
+	}
+
+	testCases["API key used by several tenants"] = tcase{
+		tenantConfig: config.TenantConfigWrap{
+			Tenant: map[string]*config.TenantConfig{
+				"payment": {APIKey: []string{"key-a"}, DocumentType: []string{"campaign"}},
+				"ads":     {APIKey: []string{"key-a"}, DocumentType: []string{"advcampaign"}},
+			},
+		},
+		expectedError: true,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on InitTenants with test case:", ktc)
+		initTestModule()
+		err := InitTenants(vtc.tenantConfig)
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	initTestModule()
+	assert.False(t, IsUsingTenant(context.Background()))
+
+	err := InitTenants(config.TenantConfigWrap{
+		Tenant: map[string]*config.TenantConfig{
+			"payment": {APIKey: []string{"key-a"}, DocumentType: []string{"campaign"}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.True(t, IsUsingTenant(context.Background()))
+
+	_, ok := ResolveTenant(context.Background(), "")
+	assert.False(t, ok)
+	_, ok = ResolveTenant(context.Background(), "key-unknown")
+	assert.False(t, ok)
+
+	tenant, ok := ResolveTenant(context.Background(), "key-a")
+	assert.True(t, ok)
+	assert.Equal(t, "payment", tenant.Name)
+	assert.Equal(t, "elasthink:payment", tenant.Namespace)
+
+	//module calls are scoped to the tenant
+	ctx := WithTenant(context.Background(), tenant)
+	assert.Equal(t, map[entity.DocumentType]int{entity.CampaignDocument: 1}, documentTypes(ctx))
+	assert.Equal(t, "elasthink:payment:inverted:campaign:promo", invertedIndexKey(ctx, entity.CampaignDocument, "promo"))
+	assert.Equal(t, "elasthink:inverted:campaign:promo", invertedIndexKey(context.Background(), entity.CampaignDocument, "promo"))
+}
+
+func TestReloadSettings(t *testing.T) {
+	initTestModule()
+	inUseSettings := currentSettings(context.Background())
+	requestCtx := WithSettings(context.Background())
+
+	//an invalid tenant config keeps the settings in use
+	err := ReloadSettings(entity.StopwordData{Words: []string{"yang"}}, config.TenantConfigWrap{
+		Tenant: map[string]*config.TenantConfig{
+			"payment": {DocumentType: []string{"campaign"}},
+		},
+	}, config.DocumentConfigWrap{})
+	assert.NotNil(t, err)
+	assert.True(t, inUseSettings == currentSettings(context.Background()))
+
+	//an invalid document config keeps the settings in use
+	err = ReloadSettings(entity.StopwordData{Words: []string{"yang"}}, config.TenantConfigWrap{}, config.DocumentConfigWrap{
+		DocumentType: map[string]*config.DocumentTypeConfig{
+			"campaign": {Field: []string{"title:zero"}},
+		},
+	})
+	assert.NotNil(t, err)
+	assert.True(t, inUseSettings == currentSettings(context.Background()))
+
+	err = ReloadSettings(entity.StopwordData{Words: []string{"yang"}}, config.TenantConfigWrap{
+		Tenant: map[string]*config.TenantConfig{
+			"payment": {APIKey: []string{"key-a"}, DocumentType: []string{"campaign"}},
+		},
+	}, config.DocumentConfigWrap{
+		DocumentType: map[string]*config.DocumentTypeConfig{
+			"campaign": {Field: []string{"title:3"}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"yang": 1}, currentSettings(context.Background()).StopwordSet)
+	assert.Equal(t, []entity.DocumentField{{Name: "title", Boost: 3}}, documentFields(context.Background(), entity.CampaignDocument))
+	_, ok := ResolveTenant(context.Background(), "key-a")
+	assert.True(t, ok)
+
+	//the settings a request got before the reload are not changed
+	assert.Equal(t, map[string]int{}, inUseSettings.StopwordSet)
+	assert.Equal(t, 0, len(inUseSettings.TenantsByAPIKey))
+
+	//a request that kept the settings before the reload still works with them
+	assert.True(t, inUseSettings == currentSettings(requestCtx))
+	assert.True(t, requestCtx == WithSettings(requestCtx))
+	assert.False(t, IsUsingTenant(requestCtx))
+	assert.Equal(t, []entity.DocumentField{{Name: entity.DefaultDocumentField, Boost: entity.DefaultDocumentFieldBoost}}, documentFields(requestCtx, entity.CampaignDocument))
+	assert.True(t, IsUsingTenant(WithSettings(context.Background())))
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	//StoreMemory keeps the buckets in memory, limits are per instance (default)
+	StoreMemory string = "memory"
+	//StoreRedis keeps the buckets in redis, limits are shared across instances
+	StoreRedis string = "redis"
+)
+
+//Limit is a token bucket limit. Rate is the number of tokens refilled per second, Burst is the bucket size
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+//IsUnlimited tells whether the limit does not limit anything
+func (l Limit) IsUnlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+//Store takes tokens from the bucket of a key.
+//Take returns whether a token is taken, and if not, how long to wait until the next token is available
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error)
+}
+
+//bucket is a token bucket of a key with the limit it was last taken with, so it is pruned with its own limit
+type bucket struct {
+	tokens   float64
+	lastFill time.Time
+	limit    Limit
+}
+
+//MemoryStore keeps the buckets in memory. Full buckets are dropped from time to time to keep the memory bounded
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+//memoryPruneInterval is the minimum interval between two prunes of the full buckets
+const memoryPruneInterval time.Duration = time.Minute
+
+//NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		lastPrune: time.Now(),
+	}
+}
+
+//Take takes a token from the bucket of a key
+func (ms *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if now.Sub(ms.lastPrune) > memoryPruneInterval {
+		ms.prune(now)
+	}
+
+	b, ok := ms.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), lastFill: now}
+		ms.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, now.Sub(b.lastFill), limit)
+	b.lastFill = now
+	b.limit = limit
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, waitDuration(b.tokens, limit), nil
+}
+
+//prune drops the buckets that have been idle long enough to be full again (with their own limit)
+func (ms *MemoryStore) prune(now time.Time) {
+	for kb, vb := range ms.buckets {
+		if refill(vb.tokens, now.Sub(vb.lastFill), vb.limit) >= float64(vb.limit.Burst) {
+			delete(ms.buckets, kb)
+		}
+	}
+	ms.lastPrune = now
+}
+
+//refill adds the tokens refilled during elapsed into a bucket, up to the burst
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+}
+
+//waitDuration is the duration until a bucket has a whole token
+func waitDuration(tokens float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / limit.Rate * float64(time.Second)))
+}
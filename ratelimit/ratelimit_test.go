@@ -0,0 +1,104 @@
+package ratelimit
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 2, Burst: 3}
+	now := time.Now()
+
+	type tcase struct {
+		at                 time.Duration
+		expectedAllowed    bool
+		expectedRetryAfter time.Duration
+	}
+	//steps must run in order, as every step takes a token from the same bucket
+	steps := []tcase{
+		{at: 0, expectedAllowed: true},
+		{at: 0, expectedAllowed: true},
+		{at: 0, expectedAllowed: true},
+		{at: 0, expectedAllowed: false, expectedRetryAfter: 500 * time.Millisecond},
+		{at: 250 * time.Millisecond, expectedAllowed: false, expectedRetryAfter: 250 * time.Millisecond},
+		{at: 500 * time.Millisecond, expectedAllowed: true},
+		{at: 500 * time.Millisecond, expectedAllowed: false, expectedRetryAfter: 500 * time.Millisecond},
+	}
+
+	for i, step := range steps {
+		fmt.Println("doing test on MemoryStore Take with step:", i)
+		isAllowed, retryAfter, err := store.Take(context.Background(), "client", limit, now.Add(step.at))
+		assert.Nil(t, err)
+		assert.Equal(t, step.expectedAllowed, isAllowed)
+		assert.Equal(t, step.expectedRetryAfter, retryAfter)
+	}
+
+	//another key has its own bucket
+	isAllowed, _, err := store.Take(context.Background(), "another client", limit, now)
+	assert.Nil(t, err)
+	assert.True(t, isAllowed)
+
+	//full buckets are dropped on prune
+	store.Take(context.Background(), "client", limit, now.Add(2*memoryPruneInterval))
+	assert.Equal(t, 1, len(store.buckets))
+
+	//a bucket with a larger burst is pruned with its own limit, so it is kept while it is still refilling
+	slowLimit := Limit{Rate: 0.001, Burst: 1000}
+	pruneAt := now.Add(2 * memoryPruneInterval)
+	for i := 0; i < 500; i++ {
+		store.Take(context.Background(), "slow client", slowLimit, pruneAt)
+	}
+	store.Take(context.Background(), "client", limit, pruneAt.Add(2*memoryPruneInterval))
+	_, ok := store.buckets["slow client"]
+	assert.True(t, ok)
+}
+
+type mockScriptRunner struct {
+	conn redigo.Conn
+}
+
+func (msr *mockScriptRunner) EvalScript(ctx context.Context, script *redigo.Script, key string, args ...interface{}) (interface{}, error) {
+	return script.Do(msr.conn, redigo.Args{key}.Add(args...)...)
+}
+
+func TestRedisStoreTake(t *testing.T) {
+	conn := redigomock.NewConn()
+	store := NewRedisStore(&mockScriptRunner{conn: conn})
+	limit := Limit{Rate: 2, Burst: 3}
+
+	conn.GenericCommand("EVALSHA").Expect([]interface{}{int64(0), int64(500)})
+	isAllowed, retryAfter, err := store.Take(context.Background(), "elasthink:ratelimit:search:ip:10.0.0.1", limit, time.Now())
+	assert.Nil(t, err)
+	assert.False(t, isAllowed)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	conn.Clear()
+	conn.GenericCommand("EVALSHA").Expect([]interface{}{int64(1), int64(0)})
+	isAllowed, _, err = store.Take(context.Background(), "elasthink:ratelimit:search:ip:10.0.0.1", limit, time.Now())
+	assert.Nil(t, err)
+	assert.True(t, isAllowed)
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+//ScriptRunner runs a lua script on the redis node that owns the key
+type ScriptRunner interface {
+	EvalScript(ctx context.Context, script *redigo.Script, key string, args ...interface{}) (interface{}, error)
+}
+
+//takeScript refills and takes a token from a bucket stored as a hash (tokens and last fill time in millisecond).
+//The bucket expires once it would be full again, so idle clients do not leave keys behind.
+var takeScript = redigo.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, wait}
+`)
+
+//RedisStore keeps the buckets in redis, so the limits are shared across instances
+type RedisStore struct {
+	runner ScriptRunner
+}
+
+//NewRedisStore creates a store that keeps the buckets in redis
+func NewRedisStore(runner ScriptRunner) *RedisStore {
+	return &RedisStore{runner: runner}
+}
+
+//Take takes a token from the bucket of a key
+func (rs *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	nowMillisecond := now.UnixNano() / int64(time.Millisecond)
+	result, err := redigo.Int64s(rs.runner.EvalScript(ctx, takeScript, key, limit.Rate, limit.Burst, nowMillisecond))
+	if err != nil {
+		return false, 0, err
+	}
+	if len(result) != 2 {
+		return false, 0, redigo.Error("Unexpected rate limit script result")
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
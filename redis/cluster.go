@@ -0,0 +1,61 @@
+package redis
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+//clusterMaxAttempts is the maximum attempts of a command on a redis cluster (following MOVED / ASK redirections)
+const clusterMaxAttempts int = 3
+
+//clusterTryAgainDelay is the delay before retrying a command that got a TRYAGAIN reply (during a slot migration)
+const clusterTryAgainDelay time.Duration = 100 * time.Millisecond
+
+//initCluster initializes a redis cluster client. The startup nodes are the configured cluster addresses, or the redis address if there are none.
+func initCluster(redisConfig config.RedisConfig, connDialer dialer) *redisc.Cluster {
+	startupNodes := redisConfig.ClusterAddress
+	if len(startupNodes) == 0 {
+		startupNodes = []string{redisConfig.Address}
+	}
+
+	return &redisc.Cluster{
+		StartupNodes: startupNodes,
+		CreatePool: func(address string, options ...redigo.DialOption) (*redigo.Pool, error) {
+			return &redigo.Pool{
+				MaxIdle:     redisConfig.MaxIdle,
+				MaxActive:   redisConfig.MaxActive,
+				IdleTimeout: time.Duration(redisConfig.Timeout) * time.Second,
+				Dial: func() (redigo.Conn, error) {
+					return connDialer.dial(address, options...)
+				},
+				TestOnBorrow: testOnBorrow,
+			}, nil
+		},
+	}
+}
+
+//HashTag wraps a key part into a redis cluster hash tag, so every key that contains the same hash tag is stored in the same slot
+func HashTag(keyPart string) string {
+	return fmt.Sprintf("{%s}", keyPart)
+}
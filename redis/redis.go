@@ -19,74 +19,633 @@ package redis
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/metrics"
 	redigo "github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+	"io/ioutil"
 	"strings"
 	"sync"
 	"time"
 )
 
-// Redis main struct
+// Redis main struct. Pool is used on standalone and sentinel mode, Cluster is used on cluster mode.
 type Redis struct {
-	Pool  *redigo.Pool
-	mutex sync.Mutex
+	Pool    *redigo.Pool
+	Cluster *redisc.Cluster
+	mutex   sync.Mutex
+
+	operationTimeout time.Duration
+
+	replicas        []*replica
+	replicaCursor   uint32
+	stopHealthCheck chan struct{}
 }
 
+var redisLogger = logger.New("redis")
+
 //NetworkTCP is the default network TCP
 const NetworkTCP string = "tcp"
 
-//InitRedis is a func to initialize redis that we are going to use
+const (
+	//ModeStandalone is the redis mode to connect to a single redis address (default)
+	ModeStandalone string = "standalone"
+	//ModeSentinel is the redis mode to discover the master through redis sentinel and follow its failovers
+	ModeSentinel string = "sentinel"
+	//ModeCluster is the redis mode to connect to a redis cluster
+	ModeCluster string = "cluster"
+)
+
+//healthCheckInterval is the minimum idle duration of a pooled connection before it is checked with PING on borrow
+const healthCheckInterval time.Duration = time.Minute
+
+//InitRedis is a func to initialize redis that we are going to use.
+//Depends on the configured mode, it connects to a single redis, to the master discovered through redis sentinel, or to a redis cluster.
+//When replica addresses are configured, reads are routed to the healthy replicas (round robin) and writes go to the primary.
 func InitRedis(redisConfig config.RedisConfigWrap) *Redis {
-	newRedis := &Redis{
-		Pool: &redigo.Pool{
-			MaxIdle:     redisConfig.RedisElasthink.MaxIdle,
-			MaxActive:   redisConfig.RedisElasthink.MaxActive,
-			IdleTimeout: time.Duration(redisConfig.RedisElasthink.Timeout) * time.Second,
-			Dial: func() (redigo.Conn, error) {
-				return redigo.Dial(NetworkTCP, redisConfig.RedisElasthink.Address)
-			},
-		},
+	elasthinkConfig := redisConfig.RedisElasthink
+
+	operationTimeout := time.Duration(elasthinkConfig.OperationTimeout) * time.Millisecond
+	connDialer := newDialer(elasthinkConfig)
+
+	var newRedis *Redis
+	switch GetMode(elasthinkConfig.Mode) {
+	case ModeSentinel:
+		newRedis = &Redis{Pool: initSentinelPool(elasthinkConfig, connDialer), operationTimeout: operationTimeout}
+	case ModeCluster:
+		// reading from replicas is not supported on cluster mode
+		return &Redis{Cluster: initCluster(elasthinkConfig, connDialer), operationTimeout: operationTimeout}
+	default:
+		newRedis = &Redis{Pool: initPool(elasthinkConfig, connDialer, elasthinkConfig.Address), operationTimeout: operationTimeout}
+	}
+
+	if len(elasthinkConfig.ReplicaAddress) > 0 {
+		newRedis.initReplicas(elasthinkConfig, connDialer)
 	}
 	return newRedis
 }
 
+//dialer dials redis connections with the dial options that are built once from the config, so the CA certificate file is not read again on every new connection.
+//When the dial options fail to be built, every dial fails with the same error
+type dialer struct {
+	options []redigo.DialOption
+	err     error
+}
+
+//newDialer builds the dial options of the redis connections
+func newDialer(redisConfig config.RedisConfig) dialer {
+	options, err := dialOptions(redisConfig)
+	if err != nil {
+		redisLogger.Error(context.Background(), "Failed to build redis dial options", logger.Fields{"error": err})
+	}
+	return dialer{options: options, err: err}
+}
+
+//dial dials a redis connection to the address with the dial options (after the given extra options)
+func (d dialer) dial(address string, extraOptions ...redigo.DialOption) (redigo.Conn, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return redigo.Dial(NetworkTCP, address, append(extraOptions, d.options...)...)
+}
+
+//initPool initializes a connection pool to a single redis address
+func initPool(redisConfig config.RedisConfig, connDialer dialer, address string) *redigo.Pool {
+	return &redigo.Pool{
+		MaxIdle:     redisConfig.MaxIdle,
+		MaxActive:   redisConfig.MaxActive,
+		IdleTimeout: time.Duration(redisConfig.Timeout) * time.Second,
+		Dial: func() (redigo.Conn, error) {
+			return connDialer.dial(address)
+		},
+		TestOnBorrow: testOnBorrow,
+	}
+}
+
+//GetMode normalizes the given redis mode, an empty or unknown mode means standalone
+func GetMode(mode string) string {
+	mode = strings.ToLower(strings.Trim(mode, " "))
+	switch mode {
+	case ModeSentinel, ModeCluster:
+		return mode
+	}
+	return ModeStandalone
+}
+
+//getConn gets a connection from the pool, waiting for a connection no longer than the deadline of ctx.
+//On a redis cluster, the connection is bound to the node serving the slot of the given key and follows MOVED / ASK redirections.
+func (r *Redis) getConn(ctx context.Context, key string) (redigo.Conn, error) {
+	if r.Cluster == nil {
+		return r.Pool.GetContext(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn := r.Cluster.Get()
+	err := redisc.BindConn(conn, key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return redisc.RetryConn(conn, clusterMaxAttempts, clusterTryAgainDelay)
+}
+
+//dialOptions builds the dial options (authentication, database selection, TLS and timeouts) of a redis connection
+func dialOptions(redisConfig config.RedisConfig) ([]redigo.DialOption, error) {
+	options := []redigo.DialOption{
+		redigo.DialDatabase(redisConfig.Database),
+	}
+
+	if len(redisConfig.Password) > 0 {
+		options = append(options, redigo.DialPassword(redisConfig.Password))
+	}
+	if len(redisConfig.Username) > 0 {
+		options = append(options, redigo.DialUsername(redisConfig.Username))
+	}
+
+	if redisConfig.ConnectTimeout > 0 {
+		options = append(options, redigo.DialConnectTimeout(time.Duration(redisConfig.ConnectTimeout)*time.Second))
+	}
+	if redisConfig.ReadTimeout > 0 {
+		options = append(options, redigo.DialReadTimeout(time.Duration(redisConfig.ReadTimeout)*time.Second))
+	}
+	if redisConfig.WriteTimeout > 0 {
+		options = append(options, redigo.DialWriteTimeout(time.Duration(redisConfig.WriteTimeout)*time.Second))
+	}
+
+	if redisConfig.UseTLS {
+		tlsConfig, err := buildTLSConfig(redisConfig)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options,
+			redigo.DialUseTLS(true),
+			redigo.DialTLSSkipVerify(redisConfig.TLSSkipVerify),
+			redigo.DialTLSConfig(tlsConfig),
+		)
+	}
+
+	return options, nil
+}
+
+//buildTLSConfig builds the TLS config of a redis connection, with an optional CA certificate file for a private CA
+func buildTLSConfig(redisConfig config.RedisConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         redisConfig.TLSServerName,
+		InsecureSkipVerify: redisConfig.TLSSkipVerify,
+	}
+
+	if len(redisConfig.TLSCACertFile) == 0 {
+		return tlsConfig, nil
+	}
+
+	caCert, err := ioutil.ReadFile(redisConfig.TLSCACertFile)
+	if err != nil {
+		return nil, err
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("Failed to parse redis CA certificate file")
+	}
+	tlsConfig.RootCAs = caCertPool
+
+	return tlsConfig, nil
+}
+
+//testOnBorrow checks the health of an idle pooled connection before it is used
+func testOnBorrow(conn redigo.Conn, lastUsed time.Time) error {
+	if time.Since(lastUsed) < healthCheckInterval {
+		return nil
+	}
+	_, err := conn.Do("PING")
+	return err
+}
+
+//operationContext limits ctx to the operation timeout (if any)
+func (r *Redis) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.operationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.operationTimeout)
+}
+
+//contextlessConn is a connection without context support
+type contextlessConn struct {
+	redigo.Conn
+}
+
+//errContextNotSupported is the error of redigo when a connection (or the connection under a pooled connection) does not support context
+var errContextNotSupported = func() error {
+	_, err := redigo.DoContext(contextlessConn{}, context.Background(), "PING")
+	return err
+}()
+
+//doCommand sends a command bounded by the deadline of ctx, records its latency and logs its failure with the request ID of ctx.
+//Connections without context support (e.g. mocks) only check ctx before sending the command.
+func doCommand(ctx context.Context, conn redigo.Conn, command string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+
+	reply, err := redigo.DoContext(conn, ctx, command, args...)
+	if err == errContextNotSupported {
+		if err = ctx.Err(); err == nil {
+			reply, err = conn.Do(command, args...)
+		}
+	}
+
+	err = contextError(ctx, err)
+	metrics.ObserveRedisCommand(command, start, err)
+	if err != nil && err != redigo.ErrNil {
+		redisLogger.Warn(ctx, "Redis command failed", logger.Fields{"command": command, "error": err})
+	}
+	return reply, err
+}
+
+//contextError returns the error of ctx when the command failed because ctx is done, so callers can tell a timeout or a cancellation from a redis failure
+func contextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
 // SAdd add an item into a set
-func (r *Redis) SAdd(key string, args []interface{}) (int64, error) {
-	conn := r.Pool.Get()
+func (r *Redis) SAdd(ctx context.Context, key string, args []interface{}) (int64, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, key)
+	if err != nil {
+		return 0, err
+	}
 	defer conn.Close()
 
-	return redigo.Int64(conn.Do("SADD", redigo.Args{key}.AddFlat(args)...))
+	return redigo.Int64(doCommand(ctx, conn, "SADD", redigo.Args{key}.AddFlat(args)...))
 }
 
 // SMembers get members of a set
-func (r *Redis) SMembers(key string) ([]string, error) {
-	conn := r.Pool.Get()
+func (r *Redis) SMembers(ctx context.Context, key string) ([]string, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getReadConn(ctx, key)
+	if err != nil {
+		return nil, err
+	}
 	defer conn.Close()
 
-	return redigo.Strings(conn.Do("SMEMBERS", key))
+	return redigo.Strings(doCommand(ctx, conn, "SMEMBERS", key))
 }
 
 // SRem remove an item from a set
-func (r *Redis) SRem(keyRedis string, members []interface{}) (int64, error) {
-	conn := r.Pool.Get()
+func (r *Redis) SRem(ctx context.Context, keyRedis string, members []interface{}) (int64, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, keyRedis)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	return redigo.Int64(doCommand(ctx, conn, "SREM", redigo.Args{keyRedis}.AddFlat(members)...))
+}
+
+// ZAdd adds a member with its score into a sorted set (the score of an existing member is updated)
+func (r *Redis) ZAdd(ctx context.Context, key string, score float64, member string) (int64, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	return redigo.Int64(doCommand(ctx, conn, "ZADD", key, score, member))
+}
+
+// ZRem removes members from a sorted set
+func (r *Redis) ZRem(ctx context.Context, key string, members []interface{}) (int64, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	return redigo.Int64(doCommand(ctx, conn, "ZREM", redigo.Args{key}.AddFlat(members)...))
+}
+
+// ZRangeByScore gets the members of a sorted set with a score between min and max (inclusive, or exclusive when prefixed by "(", and "-inf" or "+inf" for no bound)
+func (r *Redis) ZRangeByScore(ctx context.Context, key, min, max string) ([]string, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getReadConn(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return redigo.Strings(doCommand(ctx, conn, "ZRANGEBYSCORE", key, min, max))
+}
+
+// ZMembersWithScores gets every member of a sorted set with its score
+func (r *Redis) ZMembersWithScores(ctx context.Context, key string) (map[string]float64, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getReadConn(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	values, err := redigo.Values(doCommand(ctx, conn, "ZRANGE", key, 0, -1, "WITHSCORES"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64)
+	for len(values) > 0 {
+		var member string
+		var score float64
+		values, err = redigo.Scan(values, &member, &score)
+		if err != nil {
+			return nil, err
+		}
+		result[member] = score
+	}
+	return result, nil
+}
+
+//zScoresScript gets the score of each member of a sorted set (false when the member is not in it) in one call, ZMSCORE needs redis 6.2
+var zScoresScript = redigo.NewScript(1, `
+local scores = {}
+for i, member in ipairs(ARGV) do
+	scores[i] = redis.call("ZSCORE", KEYS[1], member)
+end
+return scores
+`)
+
+// ZScores gets the scores of the given members that are in a sorted set, in one call
+func (r *Redis) ZScores(ctx context.Context, key string, members []string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	if len(members) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getReadConn(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	values, err := redigo.Values(doScript(ctx, conn, zScoresScript, redigo.Args{key}.AddFlat(members)...))
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if value == nil || i >= len(members) {
+			continue
+		}
+		score, err := redigo.Float64(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		result[members[i]] = score
+	}
+	return result, nil
+}
+
+// Set sets the string value of a key
+func (r *Redis) Set(ctx context.Context, key, value string) error {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = doCommand(ctx, conn, "SET", key, value)
+	return err
+}
+
+// Get gets the string value of a key, the value is empty when the key does not exist
+func (r *Redis) Get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getReadConn(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	value, err := redigo.String(doCommand(ctx, conn, "GET", key))
+	if err == redigo.ErrNil {
+		return "", nil
+	}
+	return value, err
+}
+
+// MGet gets the string values of several keys in one round trip, in the order of the keys. A value is empty when its key does not exist.
+// On a redis cluster, the keys are fetched with one MGET for each slot.
+func (r *Redis) MGet(ctx context.Context, keys []string) ([]string, error) {
+	result := make([]string, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	for _, indexes := range r.keyIndexesBySlot(keys) {
+		args := make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			args[i] = keys[index]
+		}
+
+		values, err := r.mget(ctx, keys[indexes[0]], args)
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range indexes {
+			result[index] = values[i]
+		}
+	}
+	return result, nil
+}
+
+func (r *Redis) mget(ctx context.Context, key string, args []interface{}) ([]string, error) {
+	conn, err := r.getReadConn(ctx, key)
+	if err != nil {
+		return nil, err
+	}
 	defer conn.Close()
 
-	return redigo.Int64(conn.Do("SREM", redigo.Args{keyRedis}.AddFlat(members)...))
+	return redigo.Strings(doCommand(ctx, conn, "MGET", args...))
+}
+
+//keyIndexesBySlot groups the indexes of the keys by the redis cluster slot of the keys, every key is in a single group when redis is not a cluster
+func (r *Redis) keyIndexesBySlot(keys []string) [][]int {
+	if r.Cluster == nil {
+		indexes := make([]int, len(keys))
+		for i := range keys {
+			indexes[i] = i
+		}
+		return [][]int{indexes}
+	}
+
+	groups := make([][]int, 0)
+	groupBySlot := make(map[int]int)
+	for i, key := range keys {
+		slot := redisc.Slot(key)
+		group, ok := groupBySlot[slot]
+		if !ok {
+			group = len(groups)
+			groupBySlot[slot] = group
+			groups = append(groups, make([]int, 0))
+		}
+		groups[group] = append(groups[group], i)
+	}
+	return groups
 }
 
-// KeysPrefix get keys by a defined prefix
-func (r *Redis) KeysPrefix(prefix string) ([]string, error) {
+// Del removes a key (of any type)
+func (r *Redis) Del(ctx context.Context, key string) (int64, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	return redigo.Int64(doCommand(ctx, conn, "DEL", key))
+}
+
+// KeysPrefix get keys by a defined prefix.
+// On a redis cluster, only the node serving the slot of the prefix is asked, so the prefix must contain the hash tag of the keys.
+func (r *Redis) KeysPrefix(ctx context.Context, prefix string) ([]string, error) {
 	prefix = strings.Trim(prefix, " ")
 	if len(prefix) == 0 {
 		return make([]string, 0), errors.New("Prefix must be defined!")
 	}
 
-	conn := r.Pool.Get()
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getReadConn(ctx, prefix)
+	if err != nil {
+		return make([]string, 0), err
+	}
 	defer conn.Close()
 
 	finalKeyPrefix := fmt.Sprintf("%s*", prefix)
 
-	return redigo.Strings(conn.Do("KEYS", finalKeyPrefix))
+	return redigo.Strings(doCommand(ctx, conn, "KEYS", finalKeyPrefix))
+}
+
+// Type get the type of the value stored in a key
+func (r *Redis) Type(ctx context.Context, key string) (string, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getReadConn(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	return redigo.String(doCommand(ctx, conn, "TYPE", key))
+}
+
+// EvalScript runs a lua script with a single key on the primary (on a redis cluster, the node serving the slot of the key)
+func (r *Redis) EvalScript(ctx context.Context, script *redigo.Script, key string, args ...interface{}) (interface{}, error) {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return doScript(ctx, conn, script, redigo.Args{key}.Add(args...)...)
+}
+
+//doScript runs a lua script bounded by the deadline of ctx and records its latency, the same way as doCommand
+func doScript(ctx context.Context, conn redigo.Conn, script *redigo.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	start := time.Now()
+	reply, err := script.DoContext(ctx, conn, keysAndArgs...)
+	if err == errContextNotSupported {
+		if err = ctx.Err(); err == nil {
+			reply, err = script.Do(conn, keysAndArgs...)
+		}
+	}
+	err = contextError(ctx, err)
+	metrics.ObserveRedisCommand("EVALSHA", start, err)
+	return reply, err
+}
+
+// Ping checks the connectivity to the primary (on a redis cluster, to one of the nodes) with PING
+func (r *Redis) Ping(ctx context.Context) error {
+	ctx, cancel := r.operationContext(ctx)
+	defer cancel()
+
+	conn, err := r.getConn(ctx, "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = doCommand(ctx, conn, "PING")
+	return err
+}
+
+// PoolStats gets the active and idle connections of the primary pool (or of every node pool of the redis cluster) and of the replica pools
+func (r *Redis) PoolStats() map[string]metrics.PoolStats {
+	poolStats := make(map[string]metrics.PoolStats)
+	if r.Cluster != nil {
+		for address, stats := range r.Cluster.Stats() {
+			poolStats[address] = metrics.PoolStats{ActiveCount: stats.ActiveCount, IdleCount: stats.IdleCount}
+		}
+		return poolStats
+	}
+
+	stats := r.Pool.Stats()
+	poolStats["primary"] = metrics.PoolStats{ActiveCount: stats.ActiveCount, IdleCount: stats.IdleCount}
+	for _, rp := range r.replicas {
+		stats := rp.pool.Stats()
+		poolStats[rp.address] = metrics.PoolStats{ActiveCount: stats.ActiveCount, IdleCount: stats.IdleCount}
+	}
+	return poolStats
+}
+
+// Close closes the redis connection pool (or every node pool of the redis cluster) and the replica pools
+func (r *Redis) Close() error {
+	r.closeReplicas()
+	if r.Cluster != nil {
+		return r.Cluster.Close()
+	}
+	return r.Pool.Close()
 }
@@ -18,7 +18,9 @@ package redis
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/SurgicalSteel/elasthink/config"
 	redigo "github.com/gomodule/redigo/redis"
 	"github.com/rafaeljusto/redigomock"
@@ -54,9 +56,93 @@ func TestInitRedis(t *testing.T) {
 	assert.Equal(t, expectedRedis.Pool.MaxIdle, actualRedis.Pool.MaxIdle)
 	assert.Equal(t, expectedRedis.Pool.IdleTimeout, actualRedis.Pool.IdleTimeout)
 	assert.NotNil(t, actualRedis.Pool.Dial)
+	assert.NotNil(t, actualRedis.Pool.TestOnBorrow)
 
 }
 
+func TestDialOptions(t *testing.T) {
+	type tcase struct {
+		redisConfig     config.RedisConfig
+		expectedOptions int
+		expectedError   bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["plain connection"] = tcase{
+		redisConfig:     config.RedisConfig{Address: "fake.redis.address"},
+		expectedOptions: 1,
+	}
+
+	testCases["authenticated connection with timeouts"] = tcase{
+		redisConfig: config.RedisConfig{
+			Address:        "fake.redis.address",
+			Username:       "elasthink",
+			Password:       "rahasia",
+			Database:       2,
+			ConnectTimeout: 5,
+			ReadTimeout:    3,
+			WriteTimeout:   3,
+		},
+		expectedOptions: 6,
+	}
+
+	testCases["tls connection"] = tcase{
+		redisConfig: config.RedisConfig{
+			Address:       "fake.redis.address",
+			UseTLS:        true,
+			TLSServerName: "fake.redis.address",
+		},
+		expectedOptions: 4,
+	}
+
+	testCases["tls connection with missing CA certificate file"] = tcase{
+		redisConfig: config.RedisConfig{
+			Address:       "fake.redis.address",
+			UseTLS:        true,
+			TLSCACertFile: "/not/exist/ca.pem",
+		},
+		expectedError: true,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on dialOptions with test case:", ktc)
+		options, err := dialOptions(vtc.redisConfig)
+		if vtc.expectedError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, vtc.expectedOptions, len(options))
+	}
+}
+
+func TestDialer(t *testing.T) {
+	//the dial options are built once, a missing CA certificate file fails every dial
+	connDialer := newDialer(config.RedisConfig{Address: "fake.redis.address", UseTLS: true, TLSCACertFile: "/not/exist/ca.pem"})
+	assert.NotNil(t, connDialer.err)
+	_, err := connDialer.dial("fake.redis.address")
+	assert.Equal(t, connDialer.err, err)
+
+	connDialer = newDialer(config.RedisConfig{Address: "fake.redis.address", Password: "rahasia"})
+	assert.Nil(t, connDialer.err)
+	assert.Equal(t, 2, len(connDialer.options))
+}
+
+func TestTestOnBorrow(t *testing.T) {
+	conn := redigomock.NewConn()
+	cmd := conn.Command("PING").Expect("PONG")
+
+	//recently used connection is not checked
+	err := testOnBorrow(conn, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, conn.Stats(cmd))
+
+	err = testOnBorrow(conn, time.Now().Add(-2*healthCheckInterval))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, conn.Stats(cmd))
+	conn.Clear()
+}
+
 func TestSAdd(t *testing.T) {
 	conn := redigomock.NewConn()
 	redisMock := &Redis{
@@ -65,7 +151,7 @@ func TestSAdd(t *testing.T) {
 		}, 10),
 	}
 	cmd := conn.Command("SADD", "campaign:ganteng", 666).Expect(int64(1))
-	_, err := redisMock.SAdd("campaign:ganteng", []interface{}{666})
+	_, err := redisMock.SAdd(context.Background(), "campaign:ganteng", []interface{}{666})
 	if err != nil {
 		t.Error("Expected : ok, but found error! err:", err.Error())
 		return
@@ -85,7 +171,7 @@ func TestSMembers(t *testing.T) {
 		}, 10),
 	}
 	cmd := conn.Command("SMEMBERS", "campaign:bangun").Expect([]interface{}{"123", "234", "345", "456"})
-	_, err := redisMock.SMembers("campaign:bangun")
+	_, err := redisMock.SMembers(context.Background(), "campaign:bangun")
 	if err != nil {
 		t.Error("Expected : ok, but found error! err:", err.Error())
 		return
@@ -105,7 +191,7 @@ func TestSRem(t *testing.T) {
 		}, 10),
 	}
 	cmd := conn.Command("SREM", "campaign:ganteng", 666).Expect(int64(1))
-	_, err := redisMock.SRem("campaign:ganteng", []interface{}{666})
+	_, err := redisMock.SRem(context.Background(), "campaign:ganteng", []interface{}{666})
 	if err != nil {
 		t.Error("Expected : ok, but found error! err:", err.Error())
 		return
@@ -117,6 +203,208 @@ func TestSRem(t *testing.T) {
 	conn.Clear()
 }
 
+func TestZAdd(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("ZADD", "campaign:price", float64(150000), "666").Expect(int64(1))
+	_, err := redisMock.ZAdd(context.Background(), "campaign:price", 150000, "666")
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command ZADD is not used!")
+		return
+	}
+	conn.Clear()
+}
+
+func TestZRem(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("ZREM", "campaign:price", "666").Expect(int64(1))
+	_, err := redisMock.ZRem(context.Background(), "campaign:price", []interface{}{"666"})
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command ZREM is not used!")
+		return
+	}
+	conn.Clear()
+}
+
+func TestZRangeByScore(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("ZRANGEBYSCORE", "campaign:price", "-inf", "(100000").Expect([]interface{}{"123", "234"})
+	members, err := redisMock.ZRangeByScore(context.Background(), "campaign:price", "-inf", "(100000")
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	assert.Equal(t, []string{"123", "234"}, members)
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command ZRANGEBYSCORE is not used!")
+		return
+	}
+	conn.Clear()
+}
+
+func TestZMembersWithScores(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("ZRANGE", "campaign:price", 0, -1, "WITHSCORES").Expect([]interface{}{[]byte("123"), []byte("50000"), []byte("234"), []byte("99999.5")})
+	members, err := redisMock.ZMembersWithScores(context.Background(), "campaign:price")
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	assert.Equal(t, map[string]float64{"123": 50000, "234": 99999.5}, members)
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command ZRANGE is not used!")
+		return
+	}
+	conn.Clear()
+}
+
+func TestZScores(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+
+	//a member that is not in the sorted set has no score
+	cmd := conn.Command("EVALSHA", zScoresScript.Hash(), 1, "campaign:expiry", "123", "234", "345").Expect([]interface{}{[]byte("1580000000"), nil, []byte("1590000000.5")})
+	scores, err := redisMock.ZScores(context.Background(), "campaign:expiry", []string{"123", "234", "345"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{"123": 1580000000, "345": 1590000000.5}, scores)
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command EVALSHA is not used!")
+		return
+	}
+
+	//no command is sent without members
+	scores, err = redisMock.ZScores(context.Background(), "campaign:expiry", []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(scores))
+	assert.Equal(t, 1, conn.Stats(cmd))
+	conn.Clear()
+}
+
+func TestSet(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("SET", "campaign:666", "ganteng").Expect("OK")
+	err := redisMock.Set(context.Background(), "campaign:666", "ganteng")
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command SET is not used!")
+		return
+	}
+	conn.Clear()
+}
+
+func TestGet(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("GET", "campaign:666").Expect([]byte("ganteng"))
+	value, err := redisMock.Get(context.Background(), "campaign:666")
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	assert.Equal(t, "ganteng", value)
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command GET is not used!")
+		return
+	}
+
+	//a missing key has an empty value
+	conn.Command("GET", "campaign:777").Expect(nil)
+	value, err = redisMock.Get(context.Background(), "campaign:777")
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+	conn.Clear()
+}
+
+func TestMGet(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+
+	//a missing key has an empty value
+	cmd := conn.Command("MGET", "campaign:666", "campaign:777", "campaign:888").Expect([]interface{}{[]byte("ganteng"), nil, []byte("banget")})
+	values, err := redisMock.MGet(context.Background(), []string{"campaign:666", "campaign:777", "campaign:888"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ganteng", "", "banget"}, values)
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command MGET is not used!")
+		return
+	}
+
+	//no command is sent without keys
+	values, err = redisMock.MGet(context.Background(), []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(values))
+	assert.Equal(t, 1, conn.Stats(cmd))
+	conn.Clear()
+}
+
+func TestDel(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("DEL", "campaign:666").Expect(int64(1))
+	removed, err := redisMock.Del(context.Background(), "campaign:666")
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	assert.Equal(t, int64(1), removed)
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command DEL is not used!")
+		return
+	}
+	conn.Clear()
+}
+
 func TestKeysPrefix(t *testing.T) {
 	conn := redigomock.NewConn()
 	redisMock := &Redis{
@@ -126,7 +414,7 @@ func TestKeysPrefix(t *testing.T) {
 	}
 	//test case 1 : normal
 	cmd := conn.Command("KEYS", "campaign:*").Expect([]interface{}{"campaign:bangun", "campaign:tidur", "campaign:ganteng", "campaign:jalan"})
-	_, err := redisMock.KeysPrefix("campaign:")
+	_, err := redisMock.KeysPrefix(context.Background(), "campaign:")
 	if err != nil {
 		t.Error("Expected : ok, but found error! err:", err.Error())
 		return
@@ -137,8 +425,109 @@ func TestKeysPrefix(t *testing.T) {
 	}
 
 	//test case 2 : expect error
-	keys, err := redisMock.KeysPrefix("             ")
+	keys, err := redisMock.KeysPrefix(context.Background(), "             ")
 	assert.Equal(t, errors.New("Prefix must be defined!"), err)
 	assert.Equal(t, make([]string, 0), keys)
 	conn.Clear()
 }
+
+func TestType(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("TYPE", "campaign:bangun").Expect("set")
+	keyType, err := redisMock.Type(context.Background(), "campaign:bangun")
+	if err != nil {
+		t.Error("Expected : ok, but found error! err:", err.Error())
+		return
+	}
+	assert.Equal(t, "set", keyType)
+	if conn.Stats(cmd) != 1 {
+		t.Error("Command TYPE is not used!")
+		return
+	}
+	conn.Clear()
+}
+
+func TestGetMode(t *testing.T) {
+	assert.Equal(t, ModeStandalone, GetMode(""))
+	assert.Equal(t, ModeStandalone, GetMode("replication"))
+	assert.Equal(t, ModeSentinel, GetMode(" Sentinel "))
+	assert.Equal(t, ModeCluster, GetMode("CLUSTER"))
+}
+
+func TestInitRedisMode(t *testing.T) {
+	sentinelRedis := InitRedis(config.RedisConfigWrap{
+		RedisElasthink: config.RedisConfig{
+			Mode:               ModeSentinel,
+			SentinelAddress:    []string{"fake.sentinel.address:26379"},
+			SentinelMasterName: "elasthink",
+			MaxActive:          30,
+		},
+	})
+	assert.NotNil(t, sentinelRedis.Pool)
+	assert.Nil(t, sentinelRedis.Cluster)
+	assert.Equal(t, 30, sentinelRedis.Pool.MaxActive)
+
+	clusterRedis := InitRedis(config.RedisConfigWrap{
+		RedisElasthink: config.RedisConfig{
+			Mode:    ModeCluster,
+			Address: "fake.cluster.address:7000",
+		},
+	})
+	assert.Nil(t, clusterRedis.Pool)
+	assert.NotNil(t, clusterRedis.Cluster)
+	assert.Equal(t, []string{"fake.cluster.address:7000"}, clusterRedis.Cluster.StartupNodes)
+}
+
+func TestTestMasterRole(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("ROLE").Expect([]interface{}{"master", int64(3129659), []interface{}{}})
+	assert.Nil(t, testMasterRole(conn))
+	conn.Clear()
+
+	conn.Command("ROLE").Expect([]interface{}{"slave", "127.0.0.1", int64(9999), "connected", int64(3167038)})
+	assert.NotNil(t, testMasterRole(conn))
+	conn.Clear()
+}
+
+func TestTestIdleMasterRole(t *testing.T) {
+	conn := redigomock.NewConn()
+	cmd := conn.Command("ROLE").Expect([]interface{}{"slave", "127.0.0.1", int64(9999), "connected", int64(3167038)})
+
+	//recently used connection is not checked
+	assert.Nil(t, testIdleMasterRole(conn, time.Now()))
+	assert.Equal(t, 0, conn.Stats(cmd))
+
+	assert.NotNil(t, testIdleMasterRole(conn, time.Now().Add(-2*healthCheckInterval)))
+	assert.Equal(t, 1, conn.Stats(cmd))
+}
+
+func TestHashTag(t *testing.T) {
+	assert.Equal(t, "{campaign}", HashTag("campaign"))
+}
+
+func TestDoneContext(t *testing.T) {
+	conn := redigomock.NewConn()
+	redisMock := &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return conn, nil
+		}, 10),
+	}
+	cmd := conn.Command("SMEMBERS", "campaign:bangun").Expect([]interface{}{"123"})
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := redisMock.SMembers(cancelledCtx, "campaign:bangun")
+	assert.Equal(t, context.Canceled, err)
+
+	expiredCtx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = redisMock.SMembers(expiredCtx, "campaign:bangun")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 0, conn.Stats(cmd))
+	conn.Clear()
+}
@@ -0,0 +1,147 @@
+package redis
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/logger"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+//defaultReplicaHealthCheckInterval is the interval of checking the health of every replica when it is not configured
+const defaultReplicaHealthCheckInterval time.Duration = 5 * time.Second
+
+//replica is a read replica of the primary redis
+type replica struct {
+	address string
+	pool    *redigo.Pool
+	healthy int32
+}
+
+func (rp *replica) isHealthy() bool {
+	return atomic.LoadInt32(&rp.healthy) == 1
+}
+
+func (rp *replica) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&rp.healthy, 1)
+		return
+	}
+	atomic.StoreInt32(&rp.healthy, 0)
+}
+
+//initReplicas initializes a pool for each replica address and starts checking their health in background
+func (r *Redis) initReplicas(redisConfig config.RedisConfig, connDialer dialer) {
+	r.replicas = make([]*replica, len(redisConfig.ReplicaAddress))
+	for i, address := range redisConfig.ReplicaAddress {
+		r.replicas[i] = &replica{
+			address: address,
+			pool:    initPool(redisConfig, connDialer, address),
+			healthy: 1,
+		}
+	}
+
+	interval := time.Duration(redisConfig.ReplicaHealthCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultReplicaHealthCheckInterval
+	}
+	r.stopHealthCheck = make(chan struct{})
+	go r.checkReplicasHealth(interval, r.stopHealthCheck)
+}
+
+//checkReplicasHealth pings every replica on each interval until it is stopped
+func (r *Redis) checkReplicasHealth(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, rp := range r.replicas {
+				conn := rp.pool.Get()
+				_, err := conn.Do("PING")
+				conn.Close()
+
+				isHealthy := err == nil
+				if isHealthy != rp.isHealthy() {
+					if isHealthy {
+						redisLogger.Info(context.Background(), "Replica is healthy again", logger.Fields{"address": rp.address})
+					} else {
+						redisLogger.Warn(context.Background(), "Replica is unhealthy, reads go to other replicas or the primary", logger.Fields{"address": rp.address, "error": err})
+					}
+				}
+				rp.setHealthy(isHealthy)
+			}
+		}
+	}
+}
+
+//getReadConn gets a connection for a read command. It is taken from the next healthy replica (round robin), or from the primary if there is no healthy replica.
+func (r *Redis) getReadConn(ctx context.Context, key string) (redigo.Conn, error) {
+	total := len(r.replicas)
+	if total > 0 {
+		start := atomic.AddUint32(&r.replicaCursor, 1)
+		for i := 0; i < total; i++ {
+			rp := r.replicas[(int(start)+i)%total]
+			if !rp.isHealthy() {
+				continue
+			}
+			conn, err := rp.pool.GetContext(ctx)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, ctxErr
+				}
+				rp.setHealthy(false)
+				continue
+			}
+			if conn.Err() != nil {
+				conn.Close()
+				rp.setHealthy(false)
+				continue
+			}
+			return conn, nil
+		}
+	}
+	return r.getConn(ctx, key)
+}
+
+//Primary returns a redis object that sends every command (including reads) to the primary, sharing the connection pools of r
+func (r *Redis) Primary() *Redis {
+	return &Redis{
+		Pool:             r.Pool,
+		Cluster:          r.Cluster,
+		operationTimeout: r.operationTimeout,
+	}
+}
+
+//closeReplicas stops the health check and closes every replica pool
+func (r *Redis) closeReplicas() {
+	if r.stopHealthCheck != nil {
+		close(r.stopHealthCheck)
+		r.stopHealthCheck = nil
+	}
+	for _, rp := range r.replicas {
+		rp.pool.Close()
+	}
+}
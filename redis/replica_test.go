@@ -0,0 +1,85 @@
+package redis
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"testing"
+
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func initMockRedisWithReplica(primaryConn, replicaConn *redigomock.Conn) *Redis {
+	return &Redis{
+		Pool: redigo.NewPool(func() (redigo.Conn, error) {
+			return primaryConn, nil
+		}, 10),
+		replicas: []*replica{
+			{
+				address: "fake.replica.address",
+				pool: redigo.NewPool(func() (redigo.Conn, error) {
+					return replicaConn, nil
+				}, 10),
+				healthy: 1,
+			},
+		},
+	}
+}
+
+func TestReadFromReplica(t *testing.T) {
+	primaryConn := redigomock.NewConn()
+	replicaConn := redigomock.NewConn()
+	redisMock := initMockRedisWithReplica(primaryConn, replicaConn)
+
+	primaryCmd := primaryConn.Command("SMEMBERS", "campaign:bangun").Expect([]interface{}{"123"})
+	replicaCmd := replicaConn.Command("SMEMBERS", "campaign:bangun").Expect([]interface{}{"123"})
+	_, err := redisMock.SMembers(context.Background(), "campaign:bangun")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, replicaConn.Stats(replicaCmd))
+	assert.Equal(t, 0, primaryConn.Stats(primaryCmd))
+
+	//writes always go to the primary
+	addCmd := primaryConn.Command("SADD", "campaign:bangun", 666).Expect(int64(1))
+	_, err = redisMock.SAdd(context.Background(), "campaign:bangun", []interface{}{666})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, primaryConn.Stats(addCmd))
+}
+
+func TestReadFromPrimary(t *testing.T) {
+	primaryConn := redigomock.NewConn()
+	replicaConn := redigomock.NewConn()
+	redisMock := initMockRedisWithReplica(primaryConn, replicaConn)
+
+	primaryCmd := primaryConn.Command("SMEMBERS", "campaign:bangun").Expect([]interface{}{"123"})
+	replicaCmd := replicaConn.Command("SMEMBERS", "campaign:bangun").Expect([]interface{}{"123"})
+
+	//forced read from primary
+	_, err := redisMock.Primary().SMembers(context.Background(), "campaign:bangun")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, primaryConn.Stats(primaryCmd))
+	assert.Equal(t, 0, replicaConn.Stats(replicaCmd))
+
+	//unhealthy replica is skipped
+	redisMock.replicas[0].setHealthy(false)
+	_, err = redisMock.SMembers(context.Background(), "campaign:bangun")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, primaryConn.Stats(primaryCmd))
+	assert.Equal(t, 0, replicaConn.Stats(replicaCmd))
+}
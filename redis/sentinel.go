@@ -0,0 +1,125 @@
+package redis
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/logger"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+//roleMaster is the role reply of a redis master
+const roleMaster string = "master"
+
+//initSentinelPool initializes a pool whose connections are dialed to the current master reported by the sentinels.
+//A connection that has been idle is checked for the master role when it is borrowed, so idle connections to a demoted master are dropped after a failover.
+//A connection in use to a failed master breaks, so it is not returned to the pool and the next dial asks the sentinels again.
+func initSentinelPool(redisConfig config.RedisConfig, connDialer dialer) *redigo.Pool {
+	return &redigo.Pool{
+		MaxIdle:     redisConfig.MaxIdle,
+		MaxActive:   redisConfig.MaxActive,
+		IdleTimeout: time.Duration(redisConfig.Timeout) * time.Second,
+		Dial: func() (redigo.Conn, error) {
+			masterAddress, err := sentinelMasterAddress(redisConfig)
+			if err != nil {
+				return nil, err
+			}
+			return connDialer.dial(masterAddress)
+		},
+		TestOnBorrow: testIdleMasterRole,
+	}
+}
+
+//sentinelMasterAddress asks the sentinels (in the configured order) for the current address of the master
+func sentinelMasterAddress(redisConfig config.RedisConfig) (string, error) {
+	if len(redisConfig.SentinelAddress) == 0 {
+		return "", errors.New("Sentinel address must be defined!")
+	}
+
+	options := make([]redigo.DialOption, 0)
+	if len(redisConfig.SentinelPassword) > 0 {
+		options = append(options, redigo.DialPassword(redisConfig.SentinelPassword))
+	}
+	if redisConfig.ConnectTimeout > 0 {
+		options = append(options, redigo.DialConnectTimeout(time.Duration(redisConfig.ConnectTimeout)*time.Second))
+	}
+
+	var lastErr error
+	for _, sentinelAddress := range redisConfig.SentinelAddress {
+		masterAddress, err := querySentinel(strings.Trim(sentinelAddress, " "), redisConfig.SentinelMasterName, options)
+		if err != nil {
+			redisLogger.Warn(context.Background(), "Failed to ask sentinel for the master address", logger.Fields{"sentinel": sentinelAddress, "master": redisConfig.SentinelMasterName, "error": err})
+			lastErr = err
+			continue
+		}
+		return masterAddress, nil
+	}
+
+	return "", fmt.Errorf("No sentinel knows the address of master %s. Last error : %v", redisConfig.SentinelMasterName, lastErr)
+}
+
+func querySentinel(sentinelAddress, masterName string, options []redigo.DialOption) (string, error) {
+	conn, err := redigo.Dial(NetworkTCP, sentinelAddress, options...)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	reply, err := redigo.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", masterName))
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("Invalid reply of sentinel %s", sentinelAddress)
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+//testIdleMasterRole checks the master role of a pooled connection that has been idle for the health check interval, a recently used connection is not checked
+func testIdleMasterRole(conn redigo.Conn, lastUsed time.Time) error {
+	if time.Since(lastUsed) < healthCheckInterval {
+		return nil
+	}
+	return testMasterRole(conn)
+}
+
+//testMasterRole checks that the connection is connected to a redis master
+func testMasterRole(conn redigo.Conn) error {
+	reply, err := redigo.Values(conn.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(reply) == 0 {
+		return errors.New("Invalid reply of ROLE")
+	}
+	role, err := redigo.String(reply[0], nil)
+	if err != nil {
+		return err
+	}
+	if role != roleMaster {
+		return fmt.Errorf("Connected redis is a %s, not a master", role)
+	}
+	return nil
+}
@@ -26,7 +26,10 @@ import (
 //RegisterAppHandler register app handlers (external endpoints)
 func (rw *RouterWrap) RegisterAppHandler() {
 	subRouteV1 := rw.Router.PathPrefix("/v1").Subrouter()
+	// the rate limiter keys clients on the API key that the tenant middleware has resolved
+	subRouteV1.Use(service.TenantMiddleware, service.RateLimitMiddleware)
 
-	subRouteV1.HandleFunc("/{document_type}/_search", service.HandleSearch).Methods(http.MethodPost)
-	subRouteV1.HandleFunc("/{document_type}/{prefix}/_suggest", service.HandleKeywordSuggestion).Methods(http.MethodGet)
+	subRouteV1.HandleFunc("/{document_type}/_search", service.HandleSearch).Methods(http.MethodPost).Name(service.RouteSearch)
+	subRouteV1.HandleFunc("/{document_type}/{prefix}/_suggest", service.HandleKeywordSuggestion).Methods(http.MethodGet).Name(service.RouteSuggest)
+	subRouteV1.HandleFunc("/{document_type}/{document_id}/_explain", service.HandleExplain).Methods(http.MethodPost).Name(service.RouteExplain)
 }
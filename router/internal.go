@@ -27,8 +27,19 @@ import (
 //RegisterInternalHandler registers internal handlers (internal endpoints)
 func (rw *RouterWrap) RegisterInternalHandler() {
 	subRouteInternalV1 := rw.Router.PathPrefix("/internal/v1").Subrouter()
+	subRouteInternalV1.Use(service.InternalAuthMiddleware, service.TenantMiddleware)
 
 	subRouteInternalV1.HandleFunc("/index/{document_type}/{document_id}", service.HandleCreateIndex).Methods(http.MethodPost)
 	subRouteInternalV1.HandleFunc("/index/{document_type}/{document_id}", service.HandleUpdateIndex).Methods(http.MethodPut)
 
+	subRouteInternalV1.HandleFunc("/stopwords/{document_type}", service.HandleGetStopwords).Methods(http.MethodGet)
+	subRouteInternalV1.HandleFunc("/stopwords/{document_type}", service.HandleAddStopwords).Methods(http.MethodPost)
+	subRouteInternalV1.HandleFunc("/stopwords/{document_type}", service.HandleRemoveStopwords).Methods(http.MethodDelete)
+
+	//reload is not scoped to a tenant, it reloads the settings of every tenant
+	subRouteInternalAdminV1 := rw.Router.PathPrefix("/internal/v1").Subrouter()
+	subRouteInternalAdminV1.Use(service.InternalAuthMiddleware)
+
+	subRouteInternalAdminV1.HandleFunc("/_reload", service.HandleReload).Methods(http.MethodPost)
+
 }
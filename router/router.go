@@ -19,6 +19,7 @@ package router
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"github.com/SurgicalSteel/elasthink/metrics"
 	"github.com/SurgicalSteel/elasthink/service"
 	"github.com/gorilla/mux"
 	"net/http"
@@ -34,7 +35,12 @@ var routeWrap *RouterWrap
 // RegisterHandler is a RouterWrap 'method' to register your API endpoints.
 // Usually handler calls services module
 func (rw *RouterWrap) RegisterHandler() {
+	rw.Router.Use(service.RequestIDMiddleware, service.MetricsMiddleware)
+
 	rw.Router.HandleFunc("/ping", service.HandlePing).Methods(http.MethodGet)
+	rw.Router.HandleFunc("/healthz", service.HandleLiveness).Methods(http.MethodGet)
+	rw.Router.HandleFunc("/readyz", service.HandleReadiness).Methods(http.MethodGet)
+	rw.Router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 }
 
 // InitializeRoute is a function which returns new RouterWrap which has a mux's router inside
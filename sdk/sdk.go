@@ -19,37 +19,43 @@ package sdk
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
 	"github.com/SurgicalSteel/elasthink/redis"
+	"github.com/SurgicalSteel/elasthink/storage"
 	"github.com/SurgicalSteel/elasthink/util"
 )
 
-/// Const
-//elasthinkInvertedIndexPrefix is the prefix key for each word set in the inverted index
-const elasthinkInvertedIndexPrefix string = "elasthink:inverted:"
-
-//elasthinkNormalIndexPrefix is the prefix key for each normal index (followed by document type and document id). The value with this key contains the original document name
-const elasthinkNormalIndexPrefix string = "elasthink:normal:"
-
 /// Variables and structures
 
 // ElasthinkSDK is the main struct of elasthink SDK, initialized using initalize function
+// Redis is only set when the SDK uses the redis storage backend
+// Storage is the storage backend (redis or bolt) used by every SDK function
 type ElasthinkSDK struct {
 	Redis                   *redis.Redis
+	Storage                 storage.Storage
+	storageErr              error
+	keys                    storage.KeyBuilder
 	isUsingStopWordsRemoval bool
 	stopWordRemovalData     []string
 	availableDocumentType   map[string]int
+	documentIDTypes         map[string]entity.DocumentIDType
 }
 
 // InitializeSpec is the payload to initialize Elasthink SDK
 type InitializeSpec struct {
-	RedisConfig RedisConfig
-	SdkConfig   SdkConfig
+	RedisConfig   RedisConfig
+	SdkConfig     SdkConfig
+	StorageConfig StorageConfig
 }
 
 // RedisConfig is the basic configuration to initialize a redis connection
@@ -57,61 +63,125 @@ type InitializeSpec struct {
 // MaxActive is the maximum of access to the redis, example value is 30
 // MaxIdle is the maximum idle access to the redis, example value is 10
 // Timeout is the timeout of accessing redis (in second), example value is 10
+// Username and Password are the credentials (redis ACL user and/or password) of the redis, leave them empty if the redis has no authentication
+// Database is the selected redis database index, default is 0
+// UseTLS enables TLS connection to the redis, TLSSkipVerify skips the server certificate verification (don't use it in production)
+// TLSServerName is the expected server name of the redis certificate, TLSCACertFile is the path of a CA certificate file (PEM) for a private CA
+// ConnectTimeout, ReadTimeout and WriteTimeout are the timeouts of connecting, reading and writing to the redis (in second), zero means using the redigo default
+// Mode is the redis mode, "standalone" (default when empty), "sentinel" or "cluster"
+// SentinelAddress is the list of sentinel addresses, SentinelMasterName is the name of the monitored master and SentinelPassword is the password of the sentinels (sentinel mode only)
+// ClusterAddress is the list of startup nodes of the redis cluster, Address is used if it is empty (cluster mode only). On cluster mode, every key of a document type is wrapped in a hash tag
+// ReplicaAddress is the list of read replicas, Search and GetKeywordSuggestion read from the healthy replicas (round robin) while index updates go to the primary (standalone and sentinel mode only)
+// ReplicaHealthCheckInterval is the interval of checking the health of every replica (in second), default is 5
 type RedisConfig struct {
-	Address   string
-	MaxActive int
-	MaxIdle   int
-	Timeout   int
+	Address        string
+	MaxActive      int
+	MaxIdle        int
+	Timeout        int
+	Username       string
+	Password       string
+	Database       int
+	UseTLS         bool
+	TLSSkipVerify  bool
+	TLSServerName  string
+	TLSCACertFile  string
+	ConnectTimeout int
+	ReadTimeout    int
+	WriteTimeout   int
+
+	Mode               string
+	SentinelAddress    []string
+	SentinelMasterName string
+	SentinelPassword   string
+	ClusterAddress     []string
+
+	ReplicaAddress             []string
+	ReplicaHealthCheckInterval int
+}
+
+// StorageConfig is the configuration to select the storage backend of Elasthink SDK
+// Backend is the storage backend, "redis" (default when empty) or "bolt" for an embedded database file without any redis
+// BoltPath is the path of the bolt database file, required when using "bolt" backend
+// BoltTimeout is the timeout of opening the bolt database file (in second), example value is 1
+type StorageConfig struct {
+	Backend     string
+	BoltPath    string
+	BoltTimeout int
 }
 
 // SdkConfig is the configuration to initialize Elasthink SDK
 // IsUsingStopWordsRemoval enables Elasthink to remove stop words
 // StopWordRemovalData define the stop words
 // AvailableDocumentType the document type available, for example "campaign"
+// Namespace is the prefix of every key the SDK reads or writes (default is "elasthink"), use a different namespace for each service sharing the same redis
+// DocumentIDType is the id type of a document type, "int64" (default when the document type is not in it) or "string" for opaque string ids (e.g. UUID or slug)
 type SdkConfig struct {
 	IsUsingStopWordsRemoval bool
 	StopWordRemovalData     []string
 	AvailableDocumentType   []string
+	Namespace               string
+	DocumentIDType          map[string]string
 }
 
 // CreateIndexSpec is the spec of CreateIndex function
 // DocumentType is the type of the document
 // DocumentName is the name of the document
-// DocumentID is the id of the document
+// DocumentID is the id of the document when its document type uses int64 ids
+// StringDocumentID is the id of the document when its document type uses string ids, it must not contain whitespaces nor *?[] and is at most 128 characters long
+// ExpiresAt is the optional expiry time of the document (zero time when it does not expire), the document is not found anymore once it has expired
 type CreateIndexSpec struct {
-	DocumentType string
-	DocumentName string
-	DocumentID   int64
+	DocumentType     string
+	DocumentName     string
+	DocumentID       int64
+	StringDocumentID string
+	ExpiresAt        time.Time
 }
 
 // UpdateIndexSpec is the spec of UpdateIndex function
 // OldDocumentName is the name of the old document which will be replaced by new document
 // NewDocumentName is the name of the new document
-// DocumentID is the id of the document
+// DocumentID is the id of the document when its document type uses int64 ids, StringDocumentID is its id when its document type uses string ids
+// ExpiresAt is the expiry time of the new document (zero time when it does not expire anymore)
 type UpdateIndexSpec struct {
-	DocumentType    string
-	OldDocumentName string
-	NewDocumentName string
-	DocumentID      int64
+	DocumentType     string
+	OldDocumentName  string
+	NewDocumentName  string
+	DocumentID       int64
+	StringDocumentID string
+	ExpiresAt        time.Time
 }
 
 // SearchSpec is the spec of Search function
+// ForcePrimary forces reading from the primary redis instead of the read replicas
+// IncludeDocumentName returns the stored document name of every result, and Highlight returns it with the search words wrapped in HighlightPreTag and HighlightPostTag (<em> and </em> when they are empty), the highlighted name is HTML escaped but the tags are not
 type SearchSpec struct {
-	DocumentType string
-	SearchTerm   string
+	DocumentType        string
+	SearchTerm          string
+	ForcePrimary        bool
+	IncludeDocumentName bool
+	Highlight           bool
+	HighlightPreTag     string
+	HighlightPostTag    string
 }
 
 // SearchResultRankData is the search result datum
+// DocumentID is the id with the id type of its document type (written in JSON as a number for an int64 id and as a string for a string id), ID is only filled for an int64 id
+// DocumentName and HighlightedDocumentName are only filled when they are requested and the document is stored
 type SearchResultRankData struct {
-	ID        int64
-	ShowCount int
-	Rank      int
+	ID                      int64
+	DocumentID              entity.DocumentID
+	ShowCount               int
+	Rank                    int
+	DocumentName            string
+	HighlightedDocumentName string
 }
 
 // GetKeywordSuggestionSpec is the spec of Getting Keyword Suggestion function
+// ForcePrimary forces reading from the primary redis instead of the read replicas
 type GetKeywordSuggestionSpec struct {
 	DocumentType string
 	Prefix       string
+	ForcePrimary bool
 }
 
 // SearchResult is the result of Search, it have array of search result datum
@@ -136,6 +206,7 @@ func (r RankByShowCount) Swap(i, j int) {
 }
 
 // Initialize is the function that return ElasthinkSDK
+// If the storage backend can not be initialized (or a document id type is invalid), every SDK function returns the initialization error
 func Initialize(initializeSpec InitializeSpec) ElasthinkSDK {
 
 	spec := config.RedisConfigWrap{
@@ -144,35 +215,106 @@ func Initialize(initializeSpec InitializeSpec) ElasthinkSDK {
 			MaxActive: initializeSpec.RedisConfig.MaxActive,
 			Address:   initializeSpec.RedisConfig.Address,
 			Timeout:   initializeSpec.RedisConfig.Timeout,
+
+			Username:       initializeSpec.RedisConfig.Username,
+			Password:       initializeSpec.RedisConfig.Password,
+			Database:       initializeSpec.RedisConfig.Database,
+			UseTLS:         initializeSpec.RedisConfig.UseTLS,
+			TLSSkipVerify:  initializeSpec.RedisConfig.TLSSkipVerify,
+			TLSServerName:  initializeSpec.RedisConfig.TLSServerName,
+			TLSCACertFile:  initializeSpec.RedisConfig.TLSCACertFile,
+			ConnectTimeout: initializeSpec.RedisConfig.ConnectTimeout,
+			ReadTimeout:    initializeSpec.RedisConfig.ReadTimeout,
+			WriteTimeout:   initializeSpec.RedisConfig.WriteTimeout,
+
+			Mode:               initializeSpec.RedisConfig.Mode,
+			SentinelAddress:    initializeSpec.RedisConfig.SentinelAddress,
+			SentinelMasterName: initializeSpec.RedisConfig.SentinelMasterName,
+			SentinelPassword:   initializeSpec.RedisConfig.SentinelPassword,
+			ClusterAddress:     initializeSpec.RedisConfig.ClusterAddress,
+
+			ReplicaAddress:             initializeSpec.RedisConfig.ReplicaAddress,
+			ReplicaHealthCheckInterval: initializeSpec.RedisConfig.ReplicaHealthCheckInterval,
 		},
 	}
-	newRedis := redis.InitRedis(spec)
+
+	var newRedis *redis.Redis
+	var newStorage storage.Storage
+
+	backend, err := storage.GetBackend(initializeSpec.StorageConfig.Backend)
+	if err == nil {
+		err = storage.ValidateNamespace(initializeSpec.SdkConfig.Namespace)
+	}
 
 	availableDocumentType := make(map[string]int)
 	for _, doctype := range initializeSpec.SdkConfig.AvailableDocumentType {
 		availableDocumentType[doctype] = 1
 	}
 
+	var documentIDTypes map[string]entity.DocumentIDType
+	if err == nil {
+		documentIDTypes, err = buildDocumentIDTypes(initializeSpec.SdkConfig.DocumentIDType, availableDocumentType)
+	}
+
+	if err == nil {
+		if backend == storage.BackendRedis {
+			newRedis = redis.InitRedis(spec)
+			newStorage = newRedis
+		} else {
+			storageSpec := config.StorageConfigWrap{
+				Storage: config.StorageConfig{Backend: backend},
+				BoltElasthink: config.BoltConfig{
+					Path:    initializeSpec.StorageConfig.BoltPath,
+					Timeout: initializeSpec.StorageConfig.BoltTimeout,
+				},
+			}
+			newStorage, err = storage.InitStorage(storageSpec, spec)
+		}
+	}
+
 	elasthinkSDK := ElasthinkSDK{
 		Redis:                   newRedis,
+		Storage:                 newStorage,
+		storageErr:              err,
+		keys:                    storage.NewKeyBuilder(initializeSpec.SdkConfig.Namespace, redis.GetMode(initializeSpec.RedisConfig.Mode) == redis.ModeCluster),
 		isUsingStopWordsRemoval: initializeSpec.SdkConfig.IsUsingStopWordsRemoval,
 		stopWordRemovalData:     initializeSpec.SdkConfig.StopWordRemovalData,
 		availableDocumentType:   availableDocumentType,
+		documentIDTypes:         documentIDTypes,
 	}
 	return elasthinkSDK
 }
 
+// Close releases the storage backend used by the SDK (redis connection pool or bolt database file)
+func (es *ElasthinkSDK) Close() error {
+	if es.Storage == nil {
+		return nil
+	}
+	return es.Storage.Close()
+}
+
 // CreateIndex is a function to create new index based on documentType, documentID, and document name
 // documentType is the type of the document, to categorize documents. For example: campaign
-// documentID, is the ID of document, the key of document. For example: 1
+// documentID, is the ID of document, the key of document. For example: 1 (or "kopi-susu" with StringDocumentID when the document type uses string ids)
 // documentName, is the name of documennt, the value which will be indexed. For example: "we want to eat seafood on a restaurant"
 func (es *ElasthinkSDK) CreateIndex(spec CreateIndexSpec) (bool, error) {
-	documentID := spec.DocumentID
+	return es.CreateIndexContext(context.Background(), spec)
+}
+
+// CreateIndexContext is CreateIndex which stops as soon as ctx is done (deadline exceeded or cancelled)
+// The returned error wraps ctx.Err(), check it with errors.Is(err, context.DeadlineExceeded) or errors.Is(err, context.Canceled)
+// The keys added before ctx is done are kept, so the document must be indexed again
+func (es *ElasthinkSDK) CreateIndexContext(ctx context.Context, spec CreateIndexSpec) (bool, error) {
 	documentType := spec.DocumentType
 	documentName := spec.DocumentName
 
 	// Validation
-	err := es.validateCreateIndexSpec(documentID, documentType, documentName)
+	err := es.validateStorage()
+	if err != nil {
+		return false, err
+	}
+
+	documentID, err := es.validateCreateIndexSpec(spec.DocumentID, spec.StringDocumentID, documentType, documentName)
 	if err != nil {
 		return false, err
 	}
@@ -182,7 +324,7 @@ func (es *ElasthinkSDK) CreateIndex(spec CreateIndexSpec) (bool, error) {
 	for _, k := range es.stopWordRemovalData {
 		stopword[k] = 1
 	}
-	redis := es.Redis
+	store := es.Storage
 	documentNameSet := util.Tokenize(documentName, es.isUsingStopWordsRemoval, stopword)
 
 	docType := documentType
@@ -191,18 +333,33 @@ func (es *ElasthinkSDK) CreateIndex(spec CreateIndexSpec) (bool, error) {
 
 	//add index for each tokenized items on documentNameSet
 	//if there is an error in each indexing process, construct the error keys string (to log which keys affected by the errors)
+	keys := make([]string, 0, len(documentNameSet))
 	for k := range documentNameSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, docType, k)
+		key := es.invertedIndexKey(docType, k)
+		keys = append(keys, key)
 		value := make([]interface{}, 1)
-		value[0] = fmt.Sprintf("%d", documentID)
-		_, err := redis.SAdd(key, value)
+		value[0] = documentID.String()
+		_, err := store.SAdd(ctx, key, value)
 		if err != nil {
+			if ctxErr := contextError(ctx, "create index"); ctxErr != nil {
+				return false, ctxErr
+			}
 			errorExist = true
 			errorKeys = errorKeys + " " + key + ","
 			continue
 		}
 	}
 
+	//store the document with its keys, so it can be removed once it expires
+	errorKey, err := es.storeDocument(ctx, docType, documentID, documentName, keys, spec.ExpiresAt)
+	if err != nil {
+		if ctxErr := contextError(ctx, "create index"); ctxErr != nil {
+			return false, ctxErr
+		}
+		errorExist = true
+		errorKeys = errorKeys + " " + errorKey + ","
+	}
+
 	if errorExist {
 		errorKeys = strings.TrimRight(errorKeys, ",")
 		errorKeys = strings.TrimLeft(errorKeys, " ")
@@ -214,14 +371,24 @@ func (es *ElasthinkSDK) CreateIndex(spec CreateIndexSpec) (bool, error) {
 
 //UpdateIndex is function to update previously created index
 func (es *ElasthinkSDK) UpdateIndex(spec UpdateIndexSpec) (bool, error) {
-	documentID := spec.DocumentID
+	return es.UpdateIndexContext(context.Background(), spec)
+}
+
+//UpdateIndexContext is UpdateIndex which stops as soon as ctx is done, the returned error wraps ctx.Err()
+//The keys removed or added before ctx is done are kept, so the document must be updated again
+func (es *ElasthinkSDK) UpdateIndexContext(ctx context.Context, spec UpdateIndexSpec) (bool, error) {
 	documentType := spec.DocumentType
 	oldDocumentName := spec.OldDocumentName
 	newDocumentName := spec.NewDocumentName
-	redis := es.Redis
+	store := es.Storage
 
 	// Validate
-	err := es.validateUpdateIndexSpec(documentID, documentType, spec.OldDocumentName, spec.NewDocumentName)
+	err := es.validateStorage()
+	if err != nil {
+		return false, err
+	}
+
+	documentID, err := es.validateUpdateIndexSpec(spec.DocumentID, spec.StringDocumentID, documentType, spec.OldDocumentName, spec.NewDocumentName)
 	if err != nil {
 		return false, err
 	}
@@ -241,11 +408,14 @@ func (es *ElasthinkSDK) UpdateIndex(spec UpdateIndexSpec) (bool, error) {
 	errorRemoveKeys := ""
 
 	for k := range oldDocumentNameSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, docType, k)
+		key := es.invertedIndexKey(docType, k)
 		value := make([]interface{}, 1)
-		value[0] = fmt.Sprintf("%d", documentID)
-		_, err = redis.SRem(key, value)
+		value[0] = documentID.String()
+		_, err = store.SRem(ctx, key, value)
 		if err != nil {
+			if ctxErr := contextError(ctx, "update index"); ctxErr != nil {
+				return false, ctxErr
+			}
 			isErrorRemoveExist = true
 			errorRemoveKeys = errorRemoveKeys + " " + key + ","
 			continue
@@ -256,18 +426,33 @@ func (es *ElasthinkSDK) UpdateIndex(spec UpdateIndexSpec) (bool, error) {
 	isErrorAddExist := false
 	errorAddKeys := ""
 
+	newKeys := make([]string, 0, len(newDocumentNameSet))
 	for k := range newDocumentNameSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, docType, k)
+		key := es.invertedIndexKey(docType, k)
+		newKeys = append(newKeys, key)
 		value := make([]interface{}, 1)
-		value[0] = fmt.Sprintf("%d", documentID)
-		_, err = redis.SAdd(key, value)
+		value[0] = documentID.String()
+		_, err = store.SAdd(ctx, key, value)
 		if err != nil {
+			if ctxErr := contextError(ctx, "update index"); ctxErr != nil {
+				return false, ctxErr
+			}
 			isErrorAddExist = true
 			errorAddKeys = errorAddKeys + " " + key + ","
 			continue
 		}
 	}
 
+	//replace the stored document by the new document (with its new expiry time)
+	errorKey, err := es.storeDocument(ctx, docType, documentID, newDocumentName, newKeys, spec.ExpiresAt)
+	if err != nil {
+		if ctxErr := contextError(ctx, "update index"); ctxErr != nil {
+			return false, ctxErr
+		}
+		isErrorAddExist = true
+		errorAddKeys = errorAddKeys + " " + errorKey + ","
+	}
+
 	if isErrorAddExist || isErrorRemoveExist {
 		errorRemoveKeys = strings.TrimRight(errorRemoveKeys, ",")
 		errorRemoveKeys = strings.TrimLeft(errorRemoveKeys, " ")
@@ -284,12 +469,22 @@ func (es *ElasthinkSDK) UpdateIndex(spec UpdateIndexSpec) (bool, error) {
 
 //Search is the core function of searching a document
 func (es *ElasthinkSDK) Search(spec SearchSpec) (SearchResult, error) {
+	return es.SearchContext(context.Background(), spec)
+}
+
+//SearchContext is Search which stops as soon as ctx is done, the returned error wraps ctx.Err()
+func (es *ElasthinkSDK) SearchContext(ctx context.Context, spec SearchSpec) (SearchResult, error) {
 	searchTerm := spec.SearchTerm
 	documentType := spec.DocumentType
 
 	ret := SearchResult{RankedResultList: make([]SearchResultRankData, 0)}
 
-	err := es.validateSearchSpec(documentType, searchTerm)
+	err := es.validateStorage()
+	if err != nil {
+		return ret, err
+	}
+
+	err = es.validateSearchSpec(documentType, searchTerm)
 	if err != nil {
 		return ret, err
 	}
@@ -303,20 +498,92 @@ func (es *ElasthinkSDK) Search(spec SearchSpec) (SearchResult, error) {
 		return ret, nil
 	}
 
-	wordIndexSets, err := es.fetchWordIndexSets(documentType, searchTermSet)
+	wordIndexSets, err := es.fetchWordIndexSets(ctx, documentType, searchTermSet, spec.ForcePrimary)
 	if len(wordIndexSets) == 0 || err != nil {
 		return ret, err
 	}
 
+	//expired documents are not found even before they are swept
+	expiredIDs, err := es.fetchExpiredCandidateIDs(ctx, documentType, wordIndexSets, time.Now(), spec.ForcePrimary)
+	if err != nil {
+		if ctxErr := contextError(ctx, "search"); ctxErr != nil {
+			return ret, ctxErr
+		}
+		return ret, err
+	}
+	removeDocumentIDs(wordIndexSets, expiredIDs)
+
 	rankedSearchResult := rankSearchResult(wordIndexSets)
+	if spec.IncludeDocumentName || spec.Highlight {
+		err = es.attachDocumentNames(ctx, spec, rankedSearchResult, searchTermSet)
+		if err != nil {
+			if ctxErr := contextError(ctx, "search"); ctxErr != nil {
+				return ret, ctxErr
+			}
+			return ret, err
+		}
+	}
 	ret.RankedResultList = rankedSearchResult
 
 	return ret, nil
 }
 
+//SweepExpiredDocuments removes every document of a document type that has expired from every key it is indexed in, and from the stored documents. Returns the number of removed documents
+func (es *ElasthinkSDK) SweepExpiredDocuments(documentType string) (int, error) {
+	return es.SweepExpiredDocumentsContext(context.Background(), documentType)
+}
+
+//SweepExpiredDocumentsContext is SweepExpiredDocuments which stops as soon as ctx is done, the returned error wraps ctx.Err()
+//A document that fails to be removed is swept again on the next call
+func (es *ElasthinkSDK) SweepExpiredDocumentsContext(ctx context.Context, documentType string) (int, error) {
+	err := es.validateStorage()
+	if err != nil {
+		return 0, err
+	}
+
+	err = es.isValidFromCustomDocumentType(documentType)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	documentIDs, err := es.fetchExpiredDocumentIDs(ctx, documentType, now, true)
+	if err != nil {
+		if ctxErr := contextError(ctx, "sweep expired documents"); ctxErr != nil {
+			return 0, ctxErr
+		}
+		return 0, err
+	}
+
+	removed := 0
+	for _, documentID := range documentIDs {
+		isRemoved, err := es.sweepDocument(ctx, documentType, documentID, now)
+		if err != nil {
+			if ctxErr := contextError(ctx, "sweep expired documents"); ctxErr != nil {
+				return removed, ctxErr
+			}
+			return removed, err
+		}
+		if isRemoved {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
 //GetKeywordSuggestion is the core function to get keyword suggestion from a given keyword prefix and document type
 func (es *ElasthinkSDK) GetKeywordSuggestion(spec GetKeywordSuggestionSpec) ([]string, error) {
-	err := es.validateGetKeywordSuggestionSpec(spec.DocumentType, spec.Prefix)
+	return es.GetKeywordSuggestionContext(context.Background(), spec)
+}
+
+//GetKeywordSuggestionContext is GetKeywordSuggestion which stops as soon as ctx is done, the returned error wraps ctx.Err()
+func (es *ElasthinkSDK) GetKeywordSuggestionContext(ctx context.Context, spec GetKeywordSuggestionSpec) ([]string, error) {
+	err := es.validateStorage()
+	if err != nil {
+		return []string{}, err
+	}
+
+	err = es.validateGetKeywordSuggestionSpec(spec.DocumentType, spec.Prefix)
 	if err != nil {
 		return []string{}, err
 	}
@@ -324,7 +591,7 @@ func (es *ElasthinkSDK) GetKeywordSuggestion(spec GetKeywordSuggestionSpec) ([]s
 	prefix := strings.ToLower(spec.Prefix)
 	documentType := spec.DocumentType
 
-	keywords, err := es.fetchKeywords(documentType, prefix)
+	keywords, err := es.fetchKeywords(ctx, documentType, prefix, spec.ForcePrimary)
 	if err != nil {
 		return []string{}, err
 	}
@@ -336,18 +603,244 @@ func (es *ElasthinkSDK) GetKeywordSuggestion(spec GetKeywordSuggestionSpec) ([]s
 
 /// Private Functions
 
-// validateCreateIndexSpec validates create index spec
-func (es *ElasthinkSDK) validateCreateIndexSpec(documentID int64, documentType, documentName string) error {
-	if documentID <= 0 {
-		return errors.New("Invalid Document ID")
+// invertedIndexKeyPrefix is the prefix of every word set key of a document type. Key format --> namespace:inverted:documentType: (or namespace:inverted:{documentType}: on redis cluster)
+func (es *ElasthinkSDK) invertedIndexKeyPrefix(documentType string) string {
+	return es.keys.InvertedIndexKeyPrefix(documentType)
+}
+
+// invertedIndexKey is the word set key of a word in a document type. Key format --> namespace:inverted:documentType:word
+func (es *ElasthinkSDK) invertedIndexKey(documentType, word string) string {
+	return es.keys.InvertedIndexKey(documentType, word)
+}
+
+// normalIndexKey is the key of the stored document of a document id. Key format --> namespace:normal:documentType:documentID
+func (es *ElasthinkSDK) normalIndexKey(documentType string, documentID entity.DocumentID) string {
+	return es.keys.NormalIndexKey(documentType, documentID.String())
+}
+
+// expiryKey is the sorted set key of the documents of a document type that expire. Key format --> namespace:expiry:documentType
+func (es *ElasthinkSDK) expiryKey(documentType string) string {
+	return es.keys.ExpiryKey(documentType)
+}
+
+// storeDocument keeps the document with its keys in the normal index and schedules its expiry (or cancels it when expiresAt is zero). Returns the key that fails to be written
+func (es *ElasthinkSDK) storeDocument(ctx context.Context, documentType string, documentID entity.DocumentID, documentName string, keys []string, expiresAt time.Time) (string, error) {
+	document := entity.StoredDocument{
+		Fields: map[string]string{entity.DefaultDocumentField: documentName},
+		Keys:   keys,
+	}
+	if !expiresAt.IsZero() {
+		document.ExpiresAt = expiresAt.Unix()
+	}
+
+	key := es.normalIndexKey(documentType, documentID)
+	value, err := json.Marshal(document)
+	if err != nil {
+		return key, err
+	}
+	err = es.Storage.Set(ctx, key, string(value))
+	if err != nil {
+		return key, err
+	}
+
+	key = es.expiryKey(documentType)
+	member := documentID.String()
+	if document.ExpiresAt > 0 {
+		_, err = es.Storage.ZAdd(ctx, key, float64(document.ExpiresAt), member)
+	} else {
+		_, err = es.Storage.ZRem(ctx, key, []interface{}{member})
+	}
+	if err != nil {
+		return key, err
+	}
+	return "", nil
+}
+
+// fetchExpiredDocumentIDs fetches the ids of the documents of a document type that have expired at the given time
+func (es *ElasthinkSDK) fetchExpiredDocumentIDs(ctx context.Context, documentType string, now time.Time, forcePrimary bool) ([]entity.DocumentID, error) {
+	key := es.expiryKey(documentType)
+	members, err := es.readStorage(forcePrimary).ZRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+	if err != nil {
+		return nil, err
+	}
+	return es.parseDocumentIDs(key, documentType, members)
+}
+
+// fetchExpiredCandidateIDs fetches the ids of the found documents that have expired at the given time.
+// Only the expiry of the found documents is fetched, so the cost does not grow with the documents waiting to be swept
+func (es *ElasthinkSDK) fetchExpiredCandidateIDs(ctx context.Context, documentType string, wordIndexes map[string][]entity.DocumentID, now time.Time, forcePrimary bool) ([]entity.DocumentID, error) {
+	candidates := make(map[string]entity.DocumentID)
+	members := make([]string, 0)
+	for _, ids := range wordIndexes {
+		for _, id := range ids {
+			if _, ok := candidates[id.String()]; ok {
+				continue
+			}
+			candidates[id.String()] = id
+			members = append(members, id.String())
+		}
+	}
+
+	expiresAtByMember, err := es.readStorage(forcePrimary).ZScores(ctx, es.expiryKey(documentType), members)
+	if err != nil {
+		return nil, err
+	}
+	expiredIDs := make([]entity.DocumentID, 0)
+	for member, expiresAt := range expiresAtByMember {
+		if expiresAt > float64(now.Unix()) {
+			continue
+		}
+		expiredIDs = append(expiredIDs, candidates[member])
+	}
+	return expiredIDs, nil
+}
+
+// sweepDocument removes an expired document from every key it is indexed in, then removes the stored document and its expiry
+// A document whose expiry has been moved after the given time is kept, the stored document is read from the primary storage so a replica lag cannot hide the move. Returns whether the document is removed
+func (es *ElasthinkSDK) sweepDocument(ctx context.Context, documentType string, documentID entity.DocumentID, now time.Time) (bool, error) {
+	documentKey := es.normalIndexKey(documentType, documentID)
+	value, err := es.readStorage(true).Get(ctx, documentKey)
+	if err != nil {
+		return false, err
+	}
+
+	member := []interface{}{documentID.String()}
+	if len(value) > 0 {
+		var document entity.StoredDocument
+		if err := json.Unmarshal([]byte(value), &document); err != nil {
+			return false, fmt.Errorf("Invalid stored document %s", documentKey)
+		}
+		if document.ExpiresAt == 0 || document.ExpiresAt > now.Unix() {
+			return false, nil
+		}
+
+		for _, key := range document.Keys {
+			if _, err := es.Storage.SRem(ctx, key, member); err != nil {
+				return false, err
+			}
+		}
+		for _, key := range document.RangeKeys {
+			if _, err := es.Storage.ZRem(ctx, key, member); err != nil {
+				return false, err
+			}
+		}
+		if _, err := es.Storage.Del(ctx, documentKey); err != nil {
+			return false, err
+		}
+	}
+
+	// the expiry is removed last, so a document that fails to be removed is swept again
+	if _, err := es.Storage.ZRem(ctx, es.expiryKey(documentType), member); err != nil {
+		return false, err
+	}
+	return len(value) > 0, nil
+}
+
+// attachDocumentNames puts the stored document name of every ranked document into its result, highlighted when it is requested. A document that is not stored is returned without its name
+func (es *ElasthinkSDK) attachDocumentNames(ctx context.Context, spec SearchSpec, rankedSearchResult []SearchResultRankData, searchTermSet map[string]int) error {
+	if len(rankedSearchResult) == 0 {
+		return nil
+	}
+
+	preTag, postTag := spec.HighlightPreTag, spec.HighlightPostTag
+	if preTag == "" {
+		preTag = util.DefaultHighlightPreTag
+	}
+	if postTag == "" {
+		postTag = util.DefaultHighlightPostTag
+	}
+
+	// every stored document is fetched in one call
+	documentKeys := make([]string, len(rankedSearchResult))
+	for i, rankData := range rankedSearchResult {
+		documentKeys[i] = es.normalIndexKey(spec.DocumentType, rankData.DocumentID)
+	}
+	values, err := es.readStorage(spec.ForcePrimary).MGet(ctx, documentKeys)
+	if err != nil {
+		return err
+	}
+
+	for i, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+
+		var document entity.StoredDocument
+		if err := json.Unmarshal([]byte(value), &document); err != nil {
+			return fmt.Errorf("Invalid stored document %s", documentKeys[i])
+		}
+		documentName := document.Fields[entity.DefaultDocumentField]
+		if spec.IncludeDocumentName {
+			rankedSearchResult[i].DocumentName = documentName
+		}
+		if spec.Highlight {
+			rankedSearchResult[i].HighlightedDocumentName, _ = util.Highlight(documentName, searchTermSet, preTag, postTag)
+		}
+	}
+	return nil
+}
+
+// removeDocumentIDs removes the document ids from the document ids of every word
+func removeDocumentIDs(wordIndexes map[string][]entity.DocumentID, documentIDs []entity.DocumentID) {
+	if len(documentIDs) == 0 {
+		return
+	}
+	removedIDs := make(map[entity.DocumentID]int)
+	for _, documentID := range documentIDs {
+		removedIDs[documentID] = 1
+	}
+
+	for word, ids := range wordIndexes {
+		keptIDs := make([]entity.DocumentID, 0, len(ids))
+		for _, id := range ids {
+			if _, ok := removedIDs[id]; !ok {
+				keptIDs = append(keptIDs, id)
+			}
+		}
+		wordIndexes[word] = keptIDs
+	}
+}
+
+// readStorage is the storage used for reads, the read replicas are used unless forcePrimary is true
+func (es *ElasthinkSDK) readStorage(forcePrimary bool) storage.Storage {
+	if forcePrimary {
+		return storage.Primary(es.Storage)
+	}
+	return es.Storage
+}
+
+// contextError wraps the error of ctx when it is done (nil otherwise), so the caller can check it with errors.Is
+func contextError(ctx context.Context, operation string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("elasthink %s stopped: %w", operation, err)
+	}
+	return nil
+}
+
+// validateStorage validates that the storage backend has been initialized
+func (es *ElasthinkSDK) validateStorage() error {
+	if es.storageErr != nil {
+		return es.storageErr
+	}
+	if es.Storage == nil {
+		return errors.New("Storage is not initialized")
+	}
+	return nil
+}
+
+// validateCreateIndexSpec validates create index spec, returns the document id with the id type of its document type
+func (es *ElasthinkSDK) validateCreateIndexSpec(documentID int64, stringDocumentID, documentType, documentName string) (entity.DocumentID, error) {
+	id, err := es.getDocumentID(documentType, documentID, stringDocumentID)
+	if err != nil {
+		return entity.DocumentID{}, err
 	}
 
 	if len(strings.Trim(documentName, " ")) == 0 {
-		return errors.New("Document Name must not be empty")
+		return entity.DocumentID{}, errors.New("Document Name must not be empty")
 	}
 
-	err := es.isValidFromCustomDocumentType(documentType)
-	return err
+	err = es.isValidFromCustomDocumentType(documentType)
+	return id, err
 }
 
 // Validate is the document type is valid or not
@@ -358,22 +851,72 @@ func (es *ElasthinkSDK) isValidFromCustomDocumentType(documentType string) error
 	return errors.New("Invalid Document Type")
 }
 
-// validateUpdateIndexSpec validate update index spec
-func (es *ElasthinkSDK) validateUpdateIndexSpec(documentID int64, documentType, oldDocumentName, newDocumentName string) error {
+// buildDocumentIDTypes builds the id type of every document type that has a configured id type
+func buildDocumentIDTypes(documentIDType map[string]string, availableDocumentType map[string]int) (map[string]entity.DocumentIDType, error) {
+	idTypesByDocumentType := make(map[string]entity.DocumentIDType)
+	for documentType, rawIDType := range documentIDType {
+		if _, ok := availableDocumentType[documentType]; !ok {
+			return nil, fmt.Errorf("ID type of an invalid document type %s", documentType)
+		}
+		idType, err := entity.GetDocumentIDType(rawIDType)
+		if err != nil {
+			return nil, fmt.Errorf("Document type %s has an invalid id type %s", documentType, rawIDType)
+		}
+		idTypesByDocumentType[documentType] = idType
+	}
+	return idTypesByDocumentType, nil
+}
+
+// documentIDType gets the id type of a document type, which is int64 when the document type has no configured id type
+func (es *ElasthinkSDK) documentIDType(documentType string) entity.DocumentIDType {
+	if idType, ok := es.documentIDTypes[documentType]; ok {
+		return idType
+	}
+	return entity.DocumentIDTypeInt64
+}
+
+// getDocumentID validates the document id of a spec with the id type of its document type, documentID is used for int64 ids and stringDocumentID for string ids
+func (es *ElasthinkSDK) getDocumentID(documentType string, documentID int64, stringDocumentID string) (entity.DocumentID, error) {
+	if es.documentIDType(documentType) == entity.DocumentIDTypeString {
+		return entity.ParseDocumentID(stringDocumentID, entity.DocumentIDTypeString)
+	}
 	if documentID <= 0 {
-		return errors.New("Invalid Document ID")
+		return entity.DocumentID{}, errors.New("Invalid Document ID")
+	}
+	return entity.Int64DocumentID(documentID), nil
+}
+
+// parseDocumentIDs parses the members of a key as the ids of a document type, a member that is not a valid id of the document type is not written by the SDK
+func (es *ElasthinkSDK) parseDocumentIDs(key, documentType string, members []string) ([]entity.DocumentID, error) {
+	idType := es.documentIDType(documentType)
+	documentIDs := make([]entity.DocumentID, len(members))
+	for i, member := range members {
+		documentID, err := entity.ParseDocumentID(member, idType)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid document ids in %s: %s", key, member)
+		}
+		documentIDs[i] = documentID
+	}
+	return documentIDs, nil
+}
+
+// validateUpdateIndexSpec validate update index spec, returns the document id with the id type of its document type
+func (es *ElasthinkSDK) validateUpdateIndexSpec(documentID int64, stringDocumentID, documentType, oldDocumentName, newDocumentName string) (entity.DocumentID, error) {
+	id, err := es.getDocumentID(documentType, documentID, stringDocumentID)
+	if err != nil {
+		return entity.DocumentID{}, err
 	}
 
 	if len(strings.Trim(oldDocumentName, " ")) == 0 {
-		return errors.New("Old Document Name must not be empty")
+		return entity.DocumentID{}, errors.New("Old Document Name must not be empty")
 	}
 
 	if len(strings.Trim(newDocumentName, " ")) == 0 {
-		return errors.New("Document Name must not be empty")
+		return entity.DocumentID{}, errors.New("Document Name must not be empty")
 	}
 
-	err := es.isValidFromCustomDocumentType(documentType)
-	return err
+	err = es.isValidFromCustomDocumentType(documentType)
+	return id, err
 }
 
 // validateSearchSpec validate search spec
@@ -405,37 +948,43 @@ func (es *ElasthinkSDK) validateGetKeywordSuggestionSpec(documentType, prefix st
 }
 
 // fetchWordIndexSets
-func (es *ElasthinkSDK) fetchWordIndexSets(documentType string, searchTermSet map[string]int) (map[string][]int64, error) {
-	result := make(map[string][]int64)
+func (es *ElasthinkSDK) fetchWordIndexSets(ctx context.Context, documentType string, searchTermSet map[string]int, forcePrimary bool) (map[string][]entity.DocumentID, error) {
+	result := make(map[string][]entity.DocumentID)
 
 	errorExist := false
 	errorKeys := ""
 
 	// set key format --> elasthink:inverted:documentType:word
 	for k := range searchTermSet {
-		key := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, documentType, k)
-		members, err := es.Redis.SMembers(key)
+		key := es.invertedIndexKey(documentType, k)
+		members, err := es.readStorage(forcePrimary).SMembers(ctx, key)
 		if err != nil {
+			if ctxErr := contextError(ctx, "search"); ctxErr != nil {
+				return make(map[string][]entity.DocumentID), ctxErr
+			}
 			errorExist = true
 			errorKeys = errorKeys + " " + key + ","
 			continue
 		}
-		documentIds := util.SliceStringToInt64(members)
+		documentIds, err := es.parseDocumentIDs(key, documentType, members)
+		if err != nil {
+			return make(map[string][]entity.DocumentID), err
+		}
 		result[k] = documentIds
 	}
 
 	if errorExist {
 		errorKeys = strings.TrimRight(errorKeys, ",")
 		errorKeys = strings.TrimLeft(errorKeys, " ")
-		return make(map[string][]int64), fmt.Errorf("Error on adding following keys :%s", errorKeys)
+		return make(map[string][]entity.DocumentID), fmt.Errorf("Error on adding following keys :%s", errorKeys)
 	}
 
 	return result, nil
 }
 
 //rankSearchResult ranks search result (document id by its appeareance count). word indexes is a map with word as a key and slice of ids as value. Returns ordered search result rank slice.
-func rankSearchResult(wordIndexes map[string][]int64) []SearchResultRankData {
-	counterMap := make(map[int64]int)
+func rankSearchResult(wordIndexes map[string][]entity.DocumentID) []SearchResultRankData {
+	counterMap := make(map[entity.DocumentID]int)
 	for _, ids := range wordIndexes {
 		for i := 0; i < len(ids); i++ {
 			if vcm, ok := counterMap[ids[i]]; ok {
@@ -451,8 +1000,11 @@ func rankSearchResult(wordIndexes map[string][]int64) []SearchResultRankData {
 	iterator := 0
 	for kcm, vcm := range counterMap {
 		result[iterator] = SearchResultRankData{
-			ID:        kcm,
-			ShowCount: vcm,
+			DocumentID: kcm,
+			ShowCount:  vcm,
+		}
+		if kcm.IDType == entity.DocumentIDTypeInt64 {
+			result[iterator].ID, _ = strconv.ParseInt(kcm.Value, 10, 64)
 		}
 		iterator++
 	}
@@ -471,15 +1023,18 @@ func rankSearchResult(wordIndexes map[string][]int64) []SearchResultRankData {
 }
 
 //fetchKeywords to fetch suggested keywords by prefix
-func (es *ElasthinkSDK) fetchKeywords(documentType, prefix string) ([]string, error) {
-	prefixKey := fmt.Sprintf("%s%s:%s", elasthinkInvertedIndexPrefix, documentType, prefix)
-	rawKeys, err := es.Redis.KeysPrefix(prefixKey)
+func (es *ElasthinkSDK) fetchKeywords(ctx context.Context, documentType, prefix string, forcePrimary bool) ([]string, error) {
+	prefixKey := es.invertedIndexKey(documentType, prefix)
+	rawKeys, err := es.readStorage(forcePrimary).KeysPrefix(ctx, prefixKey)
 	if err != nil {
+		if ctxErr := contextError(ctx, "get keyword suggestion"); ctxErr != nil {
+			return []string{}, ctxErr
+		}
 		return []string{}, err
 	}
 
 	finalKeywords := make([]string, len(rawKeys))
-	trimPrefix := fmt.Sprintf("%s%s:", elasthinkInvertedIndexPrefix, documentType)
+	trimPrefix := es.invertedIndexKeyPrefix(documentType)
 
 	for i := 0; i < len(rawKeys); i++ {
 		rawKey := rawKeys[i]
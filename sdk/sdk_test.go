@@ -1,11 +1,16 @@
 package sdk
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/SurgicalSteel/elasthink/entity"
 	er "github.com/SurgicalSteel/elasthink/redis"
+	"github.com/SurgicalSteel/elasthink/storage"
 	redigo "github.com/gomodule/redigo/redis"
 	"github.com/stretchr/testify/assert"
 )
@@ -65,3 +70,229 @@ func getDummyStopwords() []string {
 func getDummyDocumentType() []string {
 	return []string{"campaign", "advertisement"}
 }
+
+func TestInvertedIndexKey(t *testing.T) {
+	elasthinkSDK := getDummyInitializedSDK()
+	elasthinkSDK.keys = storage.NewKeyBuilder("", false)
+	assert.Equal(t, "elasthink:inverted:campaign:promo", elasthinkSDK.invertedIndexKey("campaign", "promo"))
+
+	elasthinkSDK.keys = storage.NewKeyBuilder("payment", true)
+	assert.Equal(t, "payment:inverted:{campaign}:promo", elasthinkSDK.invertedIndexKey("campaign", "promo"))
+	assert.Equal(t, "payment:inverted:{campaign}:", elasthinkSDK.invertedIndexKeyPrefix("campaign"))
+}
+
+func TestContextVariants(t *testing.T) {
+	elasthinkSDK := Initialize(InitializeSpec{
+		StorageConfig: StorageConfig{
+			Backend:     "bolt",
+			BoltPath:    filepath.Join(t.TempDir(), "elasthink.db"),
+			BoltTimeout: 1,
+		},
+		SdkConfig: SdkConfig{
+			AvailableDocumentType: getDummyDocumentType(),
+		},
+	})
+	defer elasthinkSDK.Close()
+
+	isSuccess, err := elasthinkSDK.CreateIndexContext(context.Background(), CreateIndexSpec{DocumentID: 1, DocumentType: "campaign", DocumentName: "promo makan murah"})
+	assert.Nil(t, err)
+	assert.True(t, isSuccess)
+
+	result, err := elasthinkSDK.SearchContext(context.Background(), SearchSpec{DocumentType: "campaign", SearchTerm: "promo murah"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.RankedResultList))
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = elasthinkSDK.CreateIndexContext(cancelledCtx, CreateIndexSpec{DocumentID: 2, DocumentType: "campaign", DocumentName: "promo"})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = elasthinkSDK.UpdateIndexContext(cancelledCtx, UpdateIndexSpec{DocumentID: 1, DocumentType: "campaign", OldDocumentName: "promo", NewDocumentName: "diskon"})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = elasthinkSDK.SearchContext(cancelledCtx, SearchSpec{DocumentType: "campaign", SearchTerm: "promo"})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = elasthinkSDK.GetKeywordSuggestionContext(cancelledCtx, GetKeywordSuggestionSpec{DocumentType: "campaign", Prefix: "pro"})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = elasthinkSDK.SweepExpiredDocumentsContext(cancelledCtx, "campaign")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestDocumentExpiry(t *testing.T) {
+	elasthinkSDK := Initialize(InitializeSpec{
+		StorageConfig: StorageConfig{
+			Backend:     "bolt",
+			BoltPath:    filepath.Join(t.TempDir(), "elasthink.db"),
+			BoltTimeout: 1,
+		},
+		SdkConfig: SdkConfig{
+			AvailableDocumentType: getDummyDocumentType(),
+		},
+	})
+	defer elasthinkSDK.Close()
+
+	_, err := elasthinkSDK.CreateIndex(CreateIndexSpec{DocumentID: 1, DocumentType: "campaign", DocumentName: "promo makan murah", ExpiresAt: time.Now().Add(-time.Minute)})
+	assert.Nil(t, err)
+	_, err = elasthinkSDK.CreateIndex(CreateIndexSpec{DocumentID: 2, DocumentType: "campaign", DocumentName: "promo minum murah", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Nil(t, err)
+	_, err = elasthinkSDK.CreateIndex(CreateIndexSpec{DocumentID: 3, DocumentType: "campaign", DocumentName: "promo murah"})
+	assert.Nil(t, err)
+
+	//an expired document is not found even before it is swept
+	result, err := elasthinkSDK.Search(SearchSpec{DocumentType: "campaign", SearchTerm: "promo"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.RankedResultList))
+
+	//updating the document without expiry cancels its expiry
+	_, err = elasthinkSDK.UpdateIndex(UpdateIndexSpec{DocumentID: 2, DocumentType: "campaign", OldDocumentName: "promo minum murah", NewDocumentName: "promo minum"})
+	assert.Nil(t, err)
+
+	removed, err := elasthinkSDK.SweepExpiredDocuments("campaign")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+
+	members, _ := elasthinkSDK.Storage.SMembers(context.Background(), elasthinkSDK.invertedIndexKey("campaign", "makan"))
+	assert.Equal(t, 0, len(members))
+	value, _ := elasthinkSDK.Storage.Get(context.Background(), elasthinkSDK.normalIndexKey("campaign", entity.Int64DocumentID(1)))
+	assert.Equal(t, "", value)
+	expiring, _ := elasthinkSDK.Storage.ZMembersWithScores(context.Background(), elasthinkSDK.expiryKey("campaign"))
+	assert.Equal(t, 0, len(expiring))
+
+	result, err = elasthinkSDK.Search(SearchSpec{DocumentType: "campaign", SearchTerm: "promo"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.RankedResultList))
+}
+
+func TestSearchWithDocumentNames(t *testing.T) {
+	elasthinkSDK := Initialize(InitializeSpec{
+		StorageConfig: StorageConfig{
+			Backend:     "bolt",
+			BoltPath:    filepath.Join(t.TempDir(), "elasthink.db"),
+			BoltTimeout: 1,
+		},
+		SdkConfig: SdkConfig{
+			AvailableDocumentType: getDummyDocumentType(),
+		},
+	})
+	defer elasthinkSDK.Close()
+
+	_, err := elasthinkSDK.CreateIndex(CreateIndexSpec{DocumentID: 1, DocumentType: "campaign", DocumentName: "Promo Makan Murah!"})
+	assert.Nil(t, err)
+
+	result, err := elasthinkSDK.Search(SearchSpec{DocumentType: "campaign", SearchTerm: "murah makan"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", result.RankedResultList[0].DocumentName)
+	assert.Equal(t, "", result.RankedResultList[0].HighlightedDocumentName)
+
+	result, err = elasthinkSDK.Search(SearchSpec{DocumentType: "campaign", SearchTerm: "murah makan", IncludeDocumentName: true, Highlight: true, HighlightPreTag: "<b>", HighlightPostTag: "</b>"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Promo Makan Murah!", result.RankedResultList[0].DocumentName)
+	assert.Equal(t, "Promo <b>Makan</b> <b>Murah</b>!", result.RankedResultList[0].HighlightedDocumentName)
+}
+
+func TestInvalidDocumentIDs(t *testing.T) {
+	elasthinkSDK := Initialize(InitializeSpec{
+		StorageConfig: StorageConfig{
+			Backend:     "bolt",
+			BoltPath:    filepath.Join(t.TempDir(), "elasthink.db"),
+			BoltTimeout: 1,
+		},
+		SdkConfig: SdkConfig{
+			AvailableDocumentType: getDummyDocumentType(),
+		},
+	})
+	defer elasthinkSDK.Close()
+
+	_, err := elasthinkSDK.CreateIndex(CreateIndexSpec{DocumentID: 1, DocumentType: "campaign", DocumentName: "promo murah"})
+	assert.Nil(t, err)
+
+	//a member written by something else than the SDK is reported instead of being left out
+	_, err = elasthinkSDK.Storage.SAdd(context.Background(), elasthinkSDK.invertedIndexKey("campaign", "promo"), []interface{}{"kopi-1"})
+	assert.Nil(t, err)
+	_, err = elasthinkSDK.Search(SearchSpec{DocumentType: "campaign", SearchTerm: "promo"})
+	assert.NotNil(t, err)
+
+	_, err = elasthinkSDK.Storage.ZAdd(context.Background(), elasthinkSDK.expiryKey("campaign"), 1, "kopi-1")
+	assert.Nil(t, err)
+	_, err = elasthinkSDK.SweepExpiredDocuments("campaign")
+	assert.NotNil(t, err)
+}
+
+func TestStringDocumentIDs(t *testing.T) {
+	elasthinkSDK := Initialize(InitializeSpec{
+		StorageConfig: StorageConfig{
+			Backend:     "bolt",
+			BoltPath:    filepath.Join(t.TempDir(), "elasthink.db"),
+			BoltTimeout: 1,
+		},
+		SdkConfig: SdkConfig{
+			AvailableDocumentType: getDummyDocumentType(),
+			DocumentIDType:        map[string]string{"advertisement": "string"},
+		},
+	})
+	defer elasthinkSDK.Close()
+
+	_, err := elasthinkSDK.CreateIndex(CreateIndexSpec{StringDocumentID: "kopi-susu", DocumentType: "advertisement", DocumentName: "kopi susu murah"})
+	assert.Nil(t, err)
+	_, err = elasthinkSDK.CreateIndex(CreateIndexSpec{StringDocumentID: "teh-manis", DocumentType: "advertisement", DocumentName: "teh manis murah", ExpiresAt: time.Now().Add(-time.Minute)})
+	assert.Nil(t, err)
+	_, err = elasthinkSDK.CreateIndex(CreateIndexSpec{StringDocumentID: "kopi susu", DocumentType: "advertisement", DocumentName: "kopi susu"})
+	assert.NotNil(t, err)
+	_, err = elasthinkSDK.CreateIndex(CreateIndexSpec{DocumentID: 1, DocumentType: "advertisement", DocumentName: "kopi susu"})
+	assert.NotNil(t, err)
+
+	//the int64 document types are not affected
+	_, err = elasthinkSDK.CreateIndex(CreateIndexSpec{DocumentID: 1, DocumentType: "campaign", DocumentName: "kopi murah"})
+	assert.Nil(t, err)
+
+	result, err := elasthinkSDK.Search(SearchSpec{DocumentType: "advertisement", SearchTerm: "kopi murah", IncludeDocumentName: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.RankedResultList))
+	assert.Equal(t, entity.StringDocumentID("kopi-susu"), result.RankedResultList[0].DocumentID)
+	assert.Equal(t, int64(0), result.RankedResultList[0].ID)
+	assert.Equal(t, "kopi susu murah", result.RankedResultList[0].DocumentName)
+
+	result, err = elasthinkSDK.Search(SearchSpec{DocumentType: "campaign", SearchTerm: "kopi"})
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Int64DocumentID(1), result.RankedResultList[0].DocumentID)
+	assert.Equal(t, int64(1), result.RankedResultList[0].ID)
+
+	_, err = elasthinkSDK.UpdateIndex(UpdateIndexSpec{StringDocumentID: "kopi-susu", DocumentType: "advertisement", OldDocumentName: "kopi susu murah", NewDocumentName: "kopi susu"})
+	assert.Nil(t, err)
+	result, err = elasthinkSDK.Search(SearchSpec{DocumentType: "advertisement", SearchTerm: "murah"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result.RankedResultList))
+
+	removed, err := elasthinkSDK.SweepExpiredDocuments("advertisement")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+	value, _ := elasthinkSDK.Storage.Get(context.Background(), elasthinkSDK.normalIndexKey("advertisement", entity.StringDocumentID("teh-manis")))
+	assert.Equal(t, "", value)
+}
+
+func TestInvalidDocumentIDType(t *testing.T) {
+	testCases := map[string]map[string]string{
+		"invalid id type":       {"advertisement": "uuid"},
+		"invalid document type": {"coupon": "string"},
+	}
+
+	for name, documentIDType := range testCases {
+		elasthinkSDK := Initialize(InitializeSpec{
+			StorageConfig: StorageConfig{
+				Backend:     "bolt",
+				BoltPath:    filepath.Join(t.TempDir(), "elasthink.db"),
+				BoltTimeout: 1,
+			},
+			SdkConfig: SdkConfig{
+				AvailableDocumentType: getDummyDocumentType(),
+				DocumentIDType:        documentIDType,
+			},
+		})
+		_, err := elasthinkSDK.CreateIndex(CreateIndexSpec{StringDocumentID: "kopi-susu", DocumentType: "advertisement", DocumentName: "kopi susu"})
+		assert.NotNil(t, err, name)
+		assert.Nil(t, elasthinkSDK.Close(), name)
+	}
+}
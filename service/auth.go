@@ -0,0 +1,380 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+)
+
+const (
+	//AuthMethodToken authenticates a request by a static bearer token in the Authorization header
+	AuthMethodToken string = "token"
+	//AuthMethodHMAC authenticates a request by its HMAC-SHA256 signature and timestamp headers
+	AuthMethodHMAC string = "hmac"
+	//AuthMethodMTLS authenticates a request by its verified TLS client certificate
+	AuthMethodMTLS string = "mtls"
+
+	//TimestampHeader is the request header that contains the unix timestamp (in second) of a signed request
+	TimestampHeader string = "X-Elasthink-Timestamp"
+	//SignatureHeader is the request header that contains the hex encoded HMAC-SHA256 signature of a signed request
+	SignatureHeader string = "X-Elasthink-Signature"
+)
+
+const defaultHMACMaxSkew time.Duration = 5 * time.Minute
+
+//AuthError is an authentication failure. StatusCode is 401 for missing or invalid credentials, and 403 for valid but not allowed credentials
+type AuthError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+//AuthErrorData is the data of an authentication failure response
+type AuthErrorData struct {
+	Code string `json:"code"`
+}
+
+//Authenticator authenticates a request, it returns nil when the request is authenticated
+type Authenticator interface {
+	Authenticate(r *http.Request) *AuthError
+}
+
+type internalAuth struct {
+	credentialAuthenticators []Authenticator
+	requiredAuthenticators   []Authenticator
+}
+
+var internalAuthObj = &internalAuth{}
+
+//InitInternalAuth initializes the authenticators of the internal endpoints from the auth config
+func InitInternalAuth(authConfig config.AuthConfigWrap) error {
+	auth := &internalAuth{}
+	internalAuthConfig := authConfig.InternalAuth
+
+	for _, method := range internalAuthConfig.Method {
+		switch strings.ToLower(strings.Trim(method, " ")) {
+		case AuthMethodToken:
+			if len(internalAuthConfig.BearerToken) == 0 {
+				return errors.New("Bearer token must be defined for token auth method")
+			}
+			if hasEmptyValue(internalAuthConfig.BearerToken) {
+				return errors.New("Bearer token must not be empty")
+			}
+			auth.credentialAuthenticators = append(auth.credentialAuthenticators, &tokenAuthenticator{tokens: internalAuthConfig.BearerToken})
+		case AuthMethodHMAC:
+			if len(internalAuthConfig.HMACSecret) == 0 {
+				return errors.New("HMAC secret must be defined for hmac auth method")
+			}
+			if hasEmptyValue(internalAuthConfig.HMACSecret) {
+				return errors.New("HMAC secret must not be empty")
+			}
+			maxSkew := time.Duration(internalAuthConfig.HMACMaxSkew) * time.Second
+			if maxSkew <= 0 {
+				maxSkew = defaultHMACMaxSkew
+			}
+			auth.credentialAuthenticators = append(auth.credentialAuthenticators, &hmacAuthenticator{
+				secrets: internalAuthConfig.HMACSecret,
+				maxSkew: maxSkew,
+				seen:    make(map[string]time.Time),
+			})
+		case AuthMethodMTLS:
+			mtls, err := newMTLSAuthenticator(internalAuthConfig.ClientCAFile, internalAuthConfig.AllowedClientName)
+			if err != nil {
+				return err
+			}
+			auth.requiredAuthenticators = append(auth.requiredAuthenticators, mtls)
+		default:
+			return fmt.Errorf("Invalid auth method %s", method)
+		}
+	}
+
+	internalAuthObj = auth
+	return nil
+}
+
+//hasEmptyValue tells whether one of the configured tokens or secrets is empty (or only whitespaces), which would accept an empty credential
+func hasEmptyValue(values []string) bool {
+	for _, value := range values {
+		if len(strings.Trim(value, " ")) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//RegisterInternalAuthenticator plugs a custom authenticator into the internal endpoints. A request must pass it besides the configured methods
+func RegisterInternalAuthenticator(authenticator Authenticator) {
+	internalAuthObj.requiredAuthenticators = append(internalAuthObj.requiredAuthenticators, authenticator)
+}
+
+//IsUsingInternalAuth tells whether the internal endpoints are authenticated
+func IsUsingInternalAuth() bool {
+	return len(internalAuthObj.credentialAuthenticators) > 0 || len(internalAuthObj.requiredAuthenticators) > 0
+}
+
+//InternalAuthMiddleware authenticates every request to the internal endpoints. Unauthenticated requests get a 401 / 403 response with the failure code.
+func InternalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authErr := internalAuthObj.authenticate(r)
+		if authErr != nil {
+			writeAuthErrorResponse(w, authErr)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//authenticate passes when one of the credential authenticators (if any) and every other authenticator pass
+func (ia *internalAuth) authenticate(r *http.Request) *AuthError {
+	for _, authenticator := range ia.requiredAuthenticators {
+		if authErr := authenticator.Authenticate(r); authErr != nil {
+			return authErr
+		}
+	}
+
+	if len(ia.credentialAuthenticators) == 0 {
+		return nil
+	}
+
+	var firstErr *AuthError
+	for _, authenticator := range ia.credentialAuthenticators {
+		authErr := authenticator.Authenticate(r)
+		if authErr == nil {
+			return nil
+		}
+		// report the failure of the method the client tried, rather than missing credentials of another method
+		if firstErr == nil || firstErr.Code == authCodeMissingCredentials {
+			firstErr = authErr
+		}
+	}
+	return firstErr
+}
+
+func writeAuthErrorResponse(w http.ResponseWriter, authErr *AuthError) {
+	responsePayloadJSON, err := json.Marshal(ResponsePayload{
+		ErrorMessage: authErr.Message,
+		Data:         AuthErrorData{Code: authErr.Code},
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if authErr.StatusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.WriteHeader(authErr.StatusCode)
+	w.Write(responsePayloadJSON)
+}
+
+const (
+	authCodeMissingCredentials   string = "missing_credentials"
+	authCodeInvalidToken         string = "invalid_token"
+	authCodeInvalidSignature     string = "invalid_signature"
+	authCodeExpiredTimestamp     string = "expired_timestamp"
+	authCodeReplayedRequest      string = "replayed_request"
+	authCodeClientCertRequired   string = "client_certificate_required"
+	authCodeClientCertInvalid    string = "client_certificate_invalid"
+	authCodeClientCertNotAllowed string = "client_certificate_not_allowed"
+)
+
+func unauthorized(code, message string) *AuthError {
+	return &AuthError{StatusCode: http.StatusUnauthorized, Code: code, Message: message}
+}
+
+//tokenAuthenticator authenticates a request by a static bearer token (Authorization: Bearer <token>)
+type tokenAuthenticator struct {
+	tokens []string
+}
+
+func (ta *tokenAuthenticator) Authenticate(r *http.Request) *AuthError {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return unauthorized(authCodeMissingCredentials, "Bearer token is required")
+	}
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if len(strings.Trim(token, " ")) == 0 {
+		return unauthorized(authCodeMissingCredentials, "Bearer token is required")
+	}
+
+	for _, validToken := range ta.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(validToken)) == 1 {
+			return nil
+		}
+	}
+	return unauthorized(authCodeInvalidToken, "Invalid bearer token")
+}
+
+//hmacAuthenticator authenticates a signed request. A signature is only accepted once within the maximum skew to stop replayed requests.
+//The seen signatures are kept per process, so a request replayed within the maximum skew can still pass on another instance behind a load balancer.
+type hmacAuthenticator struct {
+	secrets []string
+	maxSkew time.Duration
+
+	mutex     sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+//SignRequest computes the hex encoded HMAC-SHA256 signature of a request to an internal endpoint.
+//The signed message is the method, the request URI (path and query), the timestamp and the hex encoded SHA-256 of the body, joined by new lines.
+func SignRequest(secret, method, requestURI string, timestamp int64, body []byte) string {
+	bodyDigest := sha256.Sum256(body)
+	message := fmt.Sprintf("%s\n%s\n%d\n%s", strings.ToUpper(method), requestURI, timestamp, hex.EncodeToString(bodyDigest[:]))
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ha *hmacAuthenticator) Authenticate(r *http.Request) *AuthError {
+	rawTimestamp := r.Header.Get(TimestampHeader)
+	signature := strings.ToLower(r.Header.Get(SignatureHeader))
+	if len(rawTimestamp) == 0 || len(signature) == 0 {
+		return unauthorized(authCodeMissingCredentials, "Request timestamp and signature are required")
+	}
+
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return unauthorized(authCodeExpiredTimestamp, "Invalid request timestamp")
+	}
+	now := time.Now()
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > ha.maxSkew || skew < -ha.maxSkew {
+		return unauthorized(authCodeExpiredTimestamp, "Request timestamp is out of the allowed window")
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return unauthorized(authCodeInvalidSignature, "Failed to read request body")
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	isValid := false
+	for _, secret := range ha.secrets {
+		expected := SignRequest(secret, r.Method, r.URL.RequestURI(), timestamp, body)
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			isValid = true
+			break
+		}
+	}
+	if !isValid {
+		return unauthorized(authCodeInvalidSignature, "Invalid request signature")
+	}
+
+	if !ha.markSeen(signature, now) {
+		return unauthorized(authCodeReplayedRequest, "Request has already been used")
+	}
+	return nil
+}
+
+//markSeen remembers a signature until it is out of the allowed window. Returns false if the signature has been seen before
+func (ha *hmacAuthenticator) markSeen(signature string, now time.Time) bool {
+	ha.mutex.Lock()
+	defer ha.mutex.Unlock()
+
+	if now.Sub(ha.lastPrune) > time.Second {
+		for ks, vs := range ha.seen {
+			if now.Sub(vs) > 2*ha.maxSkew {
+				delete(ha.seen, ks)
+			}
+		}
+		ha.lastPrune = now
+	}
+
+	if _, ok := ha.seen[signature]; ok {
+		return false
+	}
+	ha.seen[signature] = now
+	return true
+}
+
+//mtlsAuthenticator authenticates a request by its TLS client certificate, verified against the client CA and the allowed common names
+type mtlsAuthenticator struct {
+	clientCAs    *x509.CertPool
+	allowedNames map[string]int
+}
+
+func newMTLSAuthenticator(clientCAFile string, allowedClientNames []string) (*mtlsAuthenticator, error) {
+	mtls := &mtlsAuthenticator{
+		allowedNames: make(map[string]int),
+	}
+
+	if len(clientCAFile) > 0 {
+		caCert, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		mtls.clientCAs = x509.NewCertPool()
+		if !mtls.clientCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("Failed to parse client CA certificate file")
+		}
+	}
+
+	for _, name := range allowedClientNames {
+		mtls.allowedNames[strings.Trim(name, " ")] = 1
+	}
+	return mtls, nil
+}
+
+func (ma *mtlsAuthenticator) Authenticate(r *http.Request) *AuthError {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return unauthorized(authCodeClientCertRequired, "Client certificate is required")
+	}
+	clientCert := r.TLS.PeerCertificates[0]
+
+	if ma.clientCAs != nil {
+		intermediates := x509.NewCertPool()
+		for _, cert := range r.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := clientCert.Verify(x509.VerifyOptions{
+			Roots:         ma.clientCAs,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return unauthorized(authCodeClientCertInvalid, "Invalid client certificate")
+		}
+	} else if len(r.TLS.VerifiedChains) == 0 {
+		return unauthorized(authCodeClientCertInvalid, "Client certificate is not verified")
+	}
+
+	if len(ma.allowedNames) == 0 {
+		return nil
+	}
+	if _, ok := ma.allowedNames[clientCert.Subject.CommonName]; ok {
+		return nil
+	}
+	return &AuthError{StatusCode: http.StatusForbidden, Code: authCodeClientCertNotAllowed, Message: "Client certificate is not allowed"}
+}
@@ -0,0 +1,235 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveInternal(r *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler := InternalAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func signedRequest(secret string, timestamp int64, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/internal/v1/document/create/campaign?id=1", strings.NewReader(body))
+	r.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(SignatureHeader, SignRequest(secret, http.MethodPut, "/internal/v1/document/create/campaign?id=1", timestamp, []byte(body)))
+	return r
+}
+
+func TestInitInternalAuth(t *testing.T) {
+	type tcase struct {
+		authConfig    config.AuthConfigWrap
+		expectedError bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["no auth"] = tcase{}
+
+	testCases["token without bearer token"] = tcase{
+		authConfig:    config.AuthConfigWrap{InternalAuth: config.InternalAuthConfig{Method: []string{"token"}}},
+		expectedError: true,
+	}
+
+	testCases["hmac without secret"] = tcase{
+		authConfig:    config.AuthConfigWrap{InternalAuth: config.InternalAuthConfig{Method: []string{"hmac"}}},
+		expectedError: true,
+	}
+
+	testCases["token with an empty bearer token"] = tcase{
+		authConfig:    config.AuthConfigWrap{InternalAuth: config.InternalAuthConfig{Method: []string{"token"}, BearerToken: []string{"secret-token", ""}}},
+		expectedError: true,
+	}
+
+	testCases["hmac with an empty secret"] = tcase{
+		authConfig:    config.AuthConfigWrap{InternalAuth: config.InternalAuthConfig{Method: []string{"hmac"}, HMACSecret: []string{" "}}},
+		expectedError: true,
+	}
+
+	testCases["unknown method"] = tcase{
+		authConfig:    config.AuthConfigWrap{InternalAuth: config.InternalAuthConfig{Method: []string{"basic"}}},
+		expectedError: true,
+	}
+
+	testCases["token and hmac"] = tcase{
+		authConfig: config.AuthConfigWrap{InternalAuth: config.InternalAuthConfig{
+			Method:      []string{"token", "hmac"},
+			BearerToken: []string{"secret-token"},
+			HMACSecret:  []string{"secret-key"},
+		}},
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on InitInternalAuth with test case:", ktc)
+		err := InitInternalAuth(vtc.authConfig)
+		assert.Equal(t, vtc.expectedError, err != nil)
+	}
+	InitInternalAuth(config.AuthConfigWrap{})
+}
+
+func TestInternalAuthMiddleware(t *testing.T) {
+	err := InitInternalAuth(config.AuthConfigWrap{InternalAuth: config.InternalAuthConfig{
+		Method:      []string{"token", "hmac"},
+		BearerToken: []string{"secret-token"},
+		HMACSecret:  []string{"secret-key"},
+		HMACMaxSkew: 60,
+	}})
+	assert.Nil(t, err)
+	defer InitInternalAuth(config.AuthConfigWrap{})
+
+	now := time.Now().Unix()
+	replayed := signedRequest("secret-key", now, `{"content":"replay"}`)
+	assert.Equal(t, http.StatusOK, serveInternal(replayed).Code)
+
+	type tcase struct {
+		request            *http.Request
+		expectedStatusCode int
+		expectedCode       string
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["no credentials"] = tcase{
+		request:            httptest.NewRequest(http.MethodPut, "/internal/v1/document/create/campaign", nil),
+		expectedStatusCode: http.StatusUnauthorized,
+		expectedCode:       authCodeMissingCredentials,
+	}
+
+	validToken := httptest.NewRequest(http.MethodPut, "/internal/v1/document/create/campaign", nil)
+	validToken.Header.Set("Authorization", "Bearer secret-token")
+	testCases["valid token"] = tcase{
+		request:            validToken,
+		expectedStatusCode: http.StatusOK,
+	}
+
+	invalidToken := httptest.NewRequest(http.MethodPut, "/internal/v1/document/create/campaign", nil)
+	invalidToken.Header.Set("Authorization", "Bearer wrong-token")
+	testCases["invalid token"] = tcase{
+		request:            invalidToken,
+		expectedStatusCode: http.StatusUnauthorized,
+		expectedCode:       authCodeInvalidToken,
+	}
+
+	emptyToken := httptest.NewRequest(http.MethodPut, "/internal/v1/document/create/campaign", nil)
+	emptyToken.Header.Set("Authorization", "Bearer ")
+	testCases["empty token"] = tcase{
+		request:            emptyToken,
+		expectedStatusCode: http.StatusUnauthorized,
+		expectedCode:       authCodeMissingCredentials,
+	}
+
+	testCases["valid signature"] = tcase{
+		request:            signedRequest("secret-key", now, `{"content":"hello"}`),
+		expectedStatusCode: http.StatusOK,
+	}
+
+	testCases["invalid signature"] = tcase{
+		request:            signedRequest("wrong-key", now, `{"content":"hello"}`),
+		expectedStatusCode: http.StatusUnauthorized,
+		expectedCode:       authCodeInvalidSignature,
+	}
+
+	testCases["expired timestamp"] = tcase{
+		request:            signedRequest("secret-key", now-120, `{"content":"hello"}`),
+		expectedStatusCode: http.StatusUnauthorized,
+		expectedCode:       authCodeExpiredTimestamp,
+	}
+
+	testCases["replayed request"] = tcase{
+		request:            signedRequest("secret-key", now, `{"content":"replay"}`),
+		expectedStatusCode: http.StatusUnauthorized,
+		expectedCode:       authCodeReplayedRequest,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on InternalAuthMiddleware with test case:", ktc)
+		recorder := serveInternal(vtc.request)
+		assert.Equal(t, vtc.expectedStatusCode, recorder.Code)
+		if len(vtc.expectedCode) > 0 {
+			assert.Contains(t, recorder.Body.String(), `"code":"`+vtc.expectedCode+`"`)
+		}
+	}
+}
+
+func TestMTLSAuthenticator(t *testing.T) {
+	authenticator, err := newMTLSAuthenticator("", []string{"indexer"})
+	assert.Nil(t, err)
+
+	clientCert := func(commonName string) *x509.Certificate {
+		return &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	}
+
+	type tcase struct {
+		tlsState           *tls.ConnectionState
+		expectedStatusCode int
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["no tls"] = tcase{
+		expectedStatusCode: http.StatusUnauthorized,
+	}
+
+	testCases["unverified certificate"] = tcase{
+		tlsState:           &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert("indexer")}},
+		expectedStatusCode: http.StatusUnauthorized,
+	}
+
+	testCases["allowed certificate"] = tcase{
+		tlsState: &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{clientCert("indexer")},
+			VerifiedChains:   [][]*x509.Certificate{{clientCert("indexer")}},
+		},
+	}
+
+	testCases["not allowed certificate"] = tcase{
+		tlsState: &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{clientCert("reporter")},
+			VerifiedChains:   [][]*x509.Certificate{{clientCert("reporter")}},
+		},
+		expectedStatusCode: http.StatusForbidden,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on mTLS Authenticate with test case:", ktc)
+		r := httptest.NewRequest(http.MethodPut, "/internal/v1/document/create/campaign", nil)
+		r.TLS = vtc.tlsState
+		authErr := authenticator.Authenticate(r)
+		if vtc.expectedStatusCode == 0 {
+			assert.Nil(t, authErr)
+			continue
+		}
+		assert.NotNil(t, authErr)
+		assert.Equal(t, vtc.expectedStatusCode, authErr.StatusCode)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/module"
+)
+
+//RunExpirySweeper removes the expired documents every interval until ctx is done. Each sweep is bounded by timeout
+func RunExpirySweeper(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweepExpiredDocuments(ctx, timeout)
+		}
+	}
+}
+
+//sweepExpiredDocuments runs a single sweep and logs its outcome
+func sweepExpiredDocuments(ctx context.Context, timeout time.Duration) {
+	sweepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	removed, err := module.SweepExpiredDocuments(sweepCtx, start)
+	fields := logger.Fields{"removed": removed, "duration_ms": float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		fields["error"] = err.Error()
+		serviceLogger.Error(sweepCtx, "Failed to sweep every expired document", fields)
+		return
+	}
+	if removed > 0 {
+		serviceLogger.Info(sweepCtx, "Expired documents swept", fields)
+	}
+}
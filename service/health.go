@@ -0,0 +1,166 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/storage"
+)
+
+//readinessCheckTimeout is the deadline of every readiness check
+const readinessCheckTimeout time.Duration = 2 * time.Second
+
+const (
+	//CheckStatusOK is the status of a passed check
+	CheckStatusOK string = "ok"
+	//CheckStatusFail is the status of a failed check
+	CheckStatusFail string = "fail"
+)
+
+//CheckResult is the result of a readiness check. Latency is in millisecond (storage check only)
+type CheckResult struct {
+	Status  string  `json:"status"`
+	Error   string  `json:"error,omitempty"`
+	Latency float64 `json:"latencyMs,omitempty"`
+}
+
+//ReadinessResponsePayload is the response payload of the readiness endpoint
+type ReadinessResponsePayload struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type health struct {
+	storageObject  storage.Storage
+	isShuttingDown int32
+}
+
+var healthObj = &health{}
+
+//InitHealth initializes the readiness checks with the storage to check
+func InitHealth(storageObject storage.Storage) {
+	healthObj = &health{storageObject: storageObject}
+}
+
+//SetShuttingDown makes the readiness endpoint report not ready, so the load balancer stops routing requests before the server shuts down
+func SetShuttingDown() {
+	atomic.StoreInt32(&healthObj.isShuttingDown, 1)
+}
+
+func isShuttingDown() bool {
+	return atomic.LoadInt32(&healthObj.isShuttingDown) == 1
+}
+
+//HandleLiveness is the handler for the liveness endpoint, it only tells that the process is serving requests
+func HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	responsePayloadJSON, err := json.Marshal(ResponsePayload{Data: map[string]string{"status": CheckStatusOK}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsePayloadJSON)
+}
+
+//HandleReadiness is the handler for the readiness endpoint. It checks the shutdown state, the loaded config and stopwords, and the storage connectivity (with PING and its latency).
+//Returns 503 with every failed check when elasthink is not ready to serve requests.
+func HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	readiness := ReadinessResponsePayload{
+		Ready:  true,
+		Checks: make(map[string]CheckResult),
+	}
+	addCheckResult := func(name string, result CheckResult) {
+		if result.Status != CheckStatusOK {
+			readiness.Ready = false
+			serviceLogger.Warn(ctx, "Readiness check failed", logger.Fields{"check": name, "error": result.Error})
+		}
+		readiness.Checks[name] = result
+	}
+
+	addCheckResult("shutdown", checkResult(checkShutdown()))
+	addCheckResult("config", checkResult(checkConfig()))
+	addCheckResult("stopwords", checkResult(checkStopwords()))
+	addCheckResult("storage", checkStorage(ctx, healthObj.storageObject))
+
+	statusCode := http.StatusOK
+	errorMessage := ""
+	if !readiness.Ready {
+		statusCode = http.StatusServiceUnavailable
+		errorMessage = "Not Ready"
+	}
+
+	responsePayloadJSON, err := json.Marshal(ResponsePayload{ErrorMessage: errorMessage, Data: readiness})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(statusCode)
+	w.Write(responsePayloadJSON)
+}
+
+func checkResult(err error) CheckResult {
+	if err != nil {
+		return CheckResult{Status: CheckStatusFail, Error: err.Error()}
+	}
+	return CheckResult{Status: CheckStatusOK}
+}
+
+func checkShutdown() error {
+	if isShuttingDown() {
+		return errors.New("Server is shutting down")
+	}
+	return nil
+}
+
+func checkConfig() error {
+	if config.GetRedisConfig() == nil || config.GetStorageConfig() == nil {
+		return errors.New("Config is not loaded")
+	}
+	return nil
+}
+
+func checkStopwords() error {
+	if len(entity.Entity.GetStopwordData().Words) == 0 {
+		return errors.New("Stopwords are not loaded")
+	}
+	return nil
+}
+
+func checkStorage(ctx context.Context, storageObject storage.Storage) CheckResult {
+	if storageObject == nil {
+		return checkResult(errors.New("Storage is not initialized"))
+	}
+
+	start := time.Now()
+	err := storageObject.Ping(ctx)
+	result := checkResult(err)
+	result.Latency = float64(time.Since(start).Microseconds()) / 1000
+	return result
+}
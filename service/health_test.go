@@ -0,0 +1,104 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+//pingStorage is a storage whose ping returns pingErr, other operations are not used by the health checks
+type pingStorage struct {
+	storage.Storage
+	pingErr error
+}
+
+func (ps *pingStorage) Ping(ctx context.Context) error {
+	return ps.pingErr
+}
+
+func TestHandleReadiness(t *testing.T) {
+	err := config.InitConfig("../files/config", "development")
+	assert.Nil(t, err)
+	entity.Entity.Initialize(entity.StopwordData{Words: []string{"yang"}})
+	defer InitHealth(nil)
+
+	type tcase struct {
+		storageObject      storage.Storage
+		isShuttingDown     bool
+		expectedStatusCode int
+		expectedFailed     []string
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["ready"] = tcase{
+		storageObject:      &pingStorage{},
+		expectedStatusCode: http.StatusOK,
+	}
+
+	testCases["storage unreachable"] = tcase{
+		storageObject:      &pingStorage{pingErr: errors.New("connection refused")},
+		expectedStatusCode: http.StatusServiceUnavailable,
+		expectedFailed:     []string{"storage"},
+	}
+
+	testCases["shutting down"] = tcase{
+		storageObject:      &pingStorage{},
+		isShuttingDown:     true,
+		expectedStatusCode: http.StatusServiceUnavailable,
+		expectedFailed:     []string{"shutdown"},
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on HandleReadiness with test case:", ktc)
+		InitHealth(vtc.storageObject)
+		if vtc.isShuttingDown {
+			SetShuttingDown()
+		}
+
+		recorder := httptest.NewRecorder()
+		HandleReadiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, vtc.expectedStatusCode, recorder.Code)
+
+		var responsePayload struct {
+			Data ReadinessResponsePayload `json:"data"`
+		}
+		err := json.Unmarshal(recorder.Body.Bytes(), &responsePayload)
+		assert.Nil(t, err)
+		assert.Equal(t, len(vtc.expectedFailed) == 0, responsePayload.Data.Ready)
+		for _, name := range vtc.expectedFailed {
+			assert.Equal(t, CheckStatusFail, responsePayload.Data.Checks[name].Status)
+		}
+	}
+}
+
+func TestHandleLiveness(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	HandleLiveness(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
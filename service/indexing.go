@@ -18,10 +18,8 @@ package service
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
-	"context"
 	"encoding/json"
 	"github.com/SurgicalSteel/elasthink/module"
-	"github.com/SurgicalSteel/elasthink/util"
 	"github.com/gorilla/mux"
 	"io/ioutil"
 	"net/http"
@@ -29,11 +27,10 @@ import (
 
 //HandleCreateIndex handles create index (from internal endpoint)
 func HandleCreateIndex(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	vars := mux.Vars(r)
 	documentType := vars["document_type"]
-	documentIDRaw := vars["document_id"]
-	documentID := util.StringToInt64(documentIDRaw)
+	documentID := vars["document_id"]
 
 	var requestPayload module.CreateIndexRequestPayload
 
@@ -50,6 +47,7 @@ func HandleCreateIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := module.CreateIndex(ctx, documentID, documentType, requestPayload)
+	logFailedResponse(ctx, "create_index", documentType, response)
 	responsePayload := constructResponsePayload(response)
 
 	responsePayloadJSON, err := json.Marshal(responsePayload)
@@ -64,11 +62,10 @@ func HandleCreateIndex(w http.ResponseWriter, r *http.Request) {
 
 //HandleUpdateIndex handles update index (from internal endpoint)
 func HandleUpdateIndex(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	vars := mux.Vars(r)
 	documentType := vars["document_type"]
-	documentIDRaw := vars["document_id"]
-	documentID := util.StringToInt64(documentIDRaw)
+	documentID := vars["document_id"]
 
 	var requestPayload module.UpdateIndexRequestPayload
 
@@ -85,6 +82,7 @@ func HandleUpdateIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := module.UpdateIndex(ctx, documentID, documentType, requestPayload)
+	logFailedResponse(ctx, "update_index", documentType, response)
 	responsePayload := constructResponsePayload(response)
 
 	responsePayloadJSON, err := json.Marshal(responsePayload)
@@ -0,0 +1,182 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/SurgicalSteel/elasthink/module"
+	"github.com/SurgicalSteel/elasthink/ratelimit"
+	"github.com/SurgicalSteel/elasthink/storage"
+	"github.com/gorilla/mux"
+)
+
+const (
+	//RouteSearch is the route name of the search endpoint
+	RouteSearch string = "search"
+	//RouteSuggest is the route name of the keyword suggestion endpoint
+	RouteSuggest string = "suggest"
+	//RouteExplain is the route name of the explain endpoint
+	RouteExplain string = "explain"
+)
+
+const (
+	rateLimitKindClient string = "client"
+	rateLimitKindIP     string = "ip"
+)
+
+type routeLimit struct {
+	client ratelimit.Limit
+	ip     ratelimit.Limit
+}
+
+type rateLimiter struct {
+	store             ratelimit.Store
+	keys              storage.KeyBuilder
+	trustForwardedFor bool
+	routeLimits       map[string]routeLimit
+}
+
+var rateLimiterObj *rateLimiter
+
+//InitRateLimit initializes the rate limiter of the public routes from the rate limit config.
+//The redis store needs the redis storage backend, because the buckets are kept next to the indexes.
+func InitRateLimit(rateLimitConfig config.RateLimitConfigWrap, storageObject storage.Storage, keyBuilder storage.KeyBuilder) error {
+	if len(rateLimitConfig.RateLimit) == 0 {
+		rateLimiterObj = nil
+		return nil
+	}
+
+	limiter := &rateLimiter{
+		keys:              keyBuilder,
+		trustForwardedFor: rateLimitConfig.RateLimiter.TrustForwardedFor,
+		routeLimits:       make(map[string]routeLimit),
+	}
+
+	switch strings.ToLower(strings.Trim(rateLimitConfig.RateLimiter.Store, " ")) {
+	case ratelimit.StoreRedis:
+		runner, ok := storageObject.(ratelimit.ScriptRunner)
+		if !ok {
+			return errors.New("Redis rate limit store requires the redis storage backend")
+		}
+		limiter.store = ratelimit.NewRedisStore(runner)
+	case ratelimit.StoreMemory, "":
+		limiter.store = ratelimit.NewMemoryStore()
+	default:
+		return fmt.Errorf("Invalid rate limit store %s", rateLimitConfig.RateLimiter.Store)
+	}
+
+	for route, limitConfig := range rateLimitConfig.RateLimit {
+		if route != RouteSearch && route != RouteSuggest && route != RouteExplain {
+			return fmt.Errorf("Invalid rate limit route %s", route)
+		}
+		limiter.routeLimits[route] = routeLimit{
+			client: ratelimit.Limit{Rate: limitConfig.ClientRate, Burst: limitConfig.ClientBurst},
+			ip:     ratelimit.Limit{Rate: limitConfig.IPRate, Burst: limitConfig.IPBurst},
+		}
+	}
+
+	rateLimiterObj = limiter
+	return nil
+}
+
+//RateLimitMiddleware limits the requests of every named route that has limits, per client key (valid API key) or per IP. It must run after TenantMiddleware, which resolves the API key.
+//Over limit requests get 429 with a Retry-After header. When the limit store fails, the request passes through.
+func RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := rateLimiterObj
+		route := mux.CurrentRoute(r)
+		if limiter == nil || route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limits, ok := limiter.routeLimits[route.GetName()]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		isAllowed, retryAfter := limiter.allow(route.GetName(), limits, r)
+		if !isAllowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeErrorResponse(w, http.StatusTooManyRequests, "Too Many Requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//allow takes a token from the client bucket of a route when the request has a valid API key (it has been resolved to a tenant), otherwise from the IP bucket.
+//An API key that is not resolved never gets its own bucket, so sending a new key on every request does not get past the IP limit
+func (rl *rateLimiter) allow(routeName string, limits routeLimit, r *http.Request) (bool, time.Duration) {
+	now := time.Now()
+
+	if _, ok := module.TenantFromContext(r.Context()); ok {
+		if limits.client.IsUnlimited() {
+			return true, 0
+		}
+		// the API key itself is never stored, only its digest
+		apiKeyDigest := sha256.Sum256([]byte(r.Header.Get(APIKeyHeader)))
+		key := rl.keys.RateLimitKey(routeName, rateLimitKindClient, hex.EncodeToString(apiKeyDigest[:]))
+		return rl.take(r.Context(), key, limits.client, now)
+	}
+
+	if limits.ip.IsUnlimited() {
+		return true, 0
+	}
+	key := rl.keys.RateLimitKey(routeName, rateLimitKindIP, rl.clientIP(r))
+	return rl.take(r.Context(), key, limits.ip, now)
+}
+
+func (rl *rateLimiter) take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (bool, time.Duration) {
+	isAllowed, retryAfter, err := rl.store.Take(ctx, key, limit, now)
+	if err != nil {
+		serviceLogger.Warn(ctx, "Failed to take rate limit token, request passes through", logger.Fields{"key": key, "error": err})
+		return true, 0
+	}
+	return isAllowed, retryAfter
+}
+
+//clientIP gets the IP of the client, from the first address of X-Forwarded-For header when it is trusted, otherwise from the remote address
+func (rl *rateLimiter) clientIP(r *http.Request) string {
+	if rl.trustForwardedFor {
+		forwardedFor := r.Header.Get("X-Forwarded-For")
+		if len(forwardedFor) > 0 {
+			return strings.Trim(strings.Split(forwardedFor, ",")[0], " ")
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
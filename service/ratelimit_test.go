@@ -0,0 +1,122 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/entity"
+	"github.com/SurgicalSteel/elasthink/module"
+	"github.com/SurgicalSteel/elasthink/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	err := InitRateLimit(config.RateLimitConfigWrap{
+		RateLimiter: config.RateLimiterConfig{Store: "memory"},
+		RateLimit: map[string]*config.RateLimitConfig{
+			RouteSearch: {ClientRate: 1, ClientBurst: 2, IPRate: 1, IPBurst: 3},
+		},
+	}, nil, storage.NewKeyBuilder("", false))
+	assert.Nil(t, err)
+	defer InitRateLimit(config.RateLimitConfigWrap{}, nil, storage.NewKeyBuilder("", false))
+
+	//key-a and key-b are valid API keys, every other key is not resolved to a tenant
+	validAPIKeys := map[string]int{"key-a": 1, "key-b": 1}
+	resolveTenant := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := r.Header.Get(APIKeyHeader)
+			if _, ok := validAPIKeys[apiKey]; ok {
+				r = r.WithContext(module.WithTenant(r.Context(), entity.Tenant{Name: apiKey}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	router := mux.NewRouter()
+	router.Use(resolveTenant, RateLimitMiddleware)
+	okHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	router.HandleFunc("/v1/{document_type}/_search", okHandler).Name(RouteSearch)
+	router.HandleFunc("/v1/{document_type}/{prefix}/_suggest", okHandler).Name(RouteSuggest)
+
+	serve := func(path, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.RemoteAddr = remoteAddr
+		if len(apiKey) > 0 {
+			r.Header.Set(APIKeyHeader, apiKey)
+		}
+		router.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	type tcase struct {
+		path               string
+		remoteAddr         string
+		apiKey             string
+		expectedStatusCode int
+	}
+	//steps must run in order, as they share the same buckets
+	steps := []tcase{
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.1:1111", apiKey: "key-a", expectedStatusCode: http.StatusOK},
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.1:2222", apiKey: "key-a", expectedStatusCode: http.StatusOK},
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.2:1111", apiKey: "key-a", expectedStatusCode: http.StatusTooManyRequests},
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.1:1111", apiKey: "key-b", expectedStatusCode: http.StatusOK},
+		//requests without a valid API key share the IP bucket, whatever key they send
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.1:1111", apiKey: "key-c", expectedStatusCode: http.StatusOK},
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.1:1111", apiKey: "key-d", expectedStatusCode: http.StatusOK},
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.1:1111", expectedStatusCode: http.StatusOK},
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.1:1111", apiKey: "key-e", expectedStatusCode: http.StatusTooManyRequests},
+		{path: "/v1/campaign/_search", remoteAddr: "10.0.0.2:1111", apiKey: "key-f", expectedStatusCode: http.StatusOK},
+		{path: "/v1/campaign/cam/_suggest", remoteAddr: "10.0.0.1:1111", apiKey: "key-a", expectedStatusCode: http.StatusOK},
+	}
+
+	for i, step := range steps {
+		fmt.Println("doing test on RateLimitMiddleware with step:", i)
+		recorder := serve(step.path, step.remoteAddr, step.apiKey)
+		assert.Equal(t, step.expectedStatusCode, recorder.Code)
+		if step.expectedStatusCode == http.StatusTooManyRequests {
+			assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestInitRateLimit(t *testing.T) {
+	keyBuilder := storage.NewKeyBuilder("", false)
+
+	err := InitRateLimit(config.RateLimitConfigWrap{
+		RateLimiter: config.RateLimiterConfig{Store: "redis"},
+		RateLimit:   map[string]*config.RateLimitConfig{RouteSearch: {IPRate: 1, IPBurst: 1}},
+	}, nil, keyBuilder)
+	assert.NotNil(t, err)
+
+	err = InitRateLimit(config.RateLimitConfigWrap{
+		RateLimit: map[string]*config.RateLimitConfig{"index": {IPRate: 1, IPBurst: 1}},
+	}, nil, keyBuilder)
+	assert.NotNil(t, err)
+
+	err = InitRateLimit(config.RateLimitConfigWrap{}, nil, keyBuilder)
+	assert.Nil(t, err)
+}
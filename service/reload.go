@@ -0,0 +1,85 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SurgicalSteel/elasthink/logger"
+)
+
+//ReloadFunc reloads the reloadable settings (stopwords, tenants and document fields) and swaps them in
+type ReloadFunc func() error
+
+//ReloadResponsePayload is the data of a successful reload response. ReloadedAt is in RFC 3339 format
+type ReloadResponsePayload struct {
+	ReloadedAt string `json:"reloadedAt"`
+}
+
+type reloader struct {
+	mutex  sync.Mutex
+	reload ReloadFunc
+}
+
+var reloaderObj = &reloader{}
+
+//InitReload initializes the reload (from SIGHUP or the reload endpoint) with the function that reloads the settings
+func InitReload(reload ReloadFunc) {
+	reloaderObj = &reloader{reload: reload}
+}
+
+//Reload reloads the settings, one reload at a time. Requests are not blocked, in-flight requests keep the settings they started with
+func Reload(ctx context.Context) error {
+	if reloaderObj.reload == nil {
+		return errors.New("Reload is not initialized")
+	}
+
+	reloaderObj.mutex.Lock()
+	defer reloaderObj.mutex.Unlock()
+
+	start := time.Now()
+	err := reloaderObj.reload()
+	if err != nil {
+		serviceLogger.Error(ctx, "Failed to reload settings, keeping the settings in use", logger.Fields{"error": err.Error()})
+		return err
+	}
+	serviceLogger.Info(ctx, "Settings reloaded", logger.Fields{"duration_ms": float64(time.Since(start).Microseconds()) / 1000})
+	return nil
+}
+
+//HandleReload is the handler for the reload endpoint (internal endpoint)
+func HandleReload(w http.ResponseWriter, r *http.Request) {
+	err := Reload(r.Context())
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responsePayloadJSON, err := json.Marshal(ResponsePayload{Data: ReloadResponsePayload{ReloadedAt: time.Now().Format(time.RFC3339)}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsePayloadJSON)
+}
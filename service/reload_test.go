@@ -0,0 +1,59 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleReload(t *testing.T) {
+	type tcase struct {
+		reload             ReloadFunc
+		expectedStatusCode int
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["reload not initialized"] = tcase{
+		expectedStatusCode: http.StatusInternalServerError,
+	}
+
+	testCases["reload failed"] = tcase{
+		reload:             func() error { return errors.New("Stopwords file has no word") },
+		expectedStatusCode: http.StatusInternalServerError,
+	}
+
+	testCases["reload succeeded"] = tcase{
+		reload:             func() error { return nil },
+		expectedStatusCode: http.StatusOK,
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on HandleReload with test case:", ktc)
+		InitReload(vtc.reload)
+
+		w := httptest.NewRecorder()
+		HandleReload(w, httptest.NewRequest(http.MethodPost, "/internal/v1/_reload", nil))
+		assert.Equal(t, vtc.expectedStatusCode, w.Code)
+	}
+}
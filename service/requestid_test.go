@@ -0,0 +1,75 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	type tcase struct {
+		requestID         string
+		expectedRequestID string
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["given request id"] = tcase{
+		requestID:         "trace-abc-123",
+		expectedRequestID: "trace-abc-123",
+	}
+
+	testCases["missing request id"] = tcase{}
+
+	testCases["invalid request id"] = tcase{
+		requestID: "trace abc\n123",
+	}
+
+	testCases["too long request id"] = tcase{
+		requestID: strings.Repeat("a", maxRequestIDLength+1),
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on RequestIDMiddleware with test case:", ktc)
+		contextRequestID := ""
+		handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contextRequestID = logger.RequestID(r.Context())
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if len(vtc.requestID) > 0 {
+			r.Header.Set(RequestIDHeader, vtc.requestID)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+
+		responseRequestID := recorder.Header().Get(RequestIDHeader)
+		assert.Equal(t, responseRequestID, contextRequestID)
+		if len(vtc.expectedRequestID) > 0 {
+			assert.Equal(t, vtc.expectedRequestID, responseRequestID)
+		} else {
+			assert.Equal(t, 32, len(responseRequestID))
+		}
+	}
+}
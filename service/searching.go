@@ -18,7 +18,6 @@ package service
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
-	"context"
 	"encoding/json"
 	"github.com/SurgicalSteel/elasthink/module"
 	"github.com/gorilla/mux"
@@ -28,7 +27,7 @@ import (
 
 //HandleSearch handles the search for a document id (from internal & external endpoint)
 func HandleSearch(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := readPreferenceContext(r.Context(), r)
 	vars := mux.Vars(r)
 	documentType := vars["document_type"]
 
@@ -47,6 +46,41 @@ func HandleSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := module.Search(ctx, documentType, requestPayload)
+	logFailedResponse(ctx, "search", documentType, response)
+	responsePayload := constructResponsePayload(response)
+
+	responsePayloadJSON, err := json.Marshal(responsePayload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(response.StatusCode)
+	w.Write(responsePayloadJSON)
+}
+
+//HandleExplain handles the explanation of how a document is matched by a search (from external endpoint)
+func HandleExplain(w http.ResponseWriter, r *http.Request) {
+	ctx := readPreferenceContext(r.Context(), r)
+	vars := mux.Vars(r)
+	documentType := vars["document_type"]
+	documentID := vars["document_id"]
+
+	var requestPayload module.SearchRequestPayload
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(body, &requestPayload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := module.Explain(ctx, documentType, documentID, requestPayload)
+	logFailedResponse(ctx, "explain", documentType, response)
 	responsePayload := constructResponsePayload(response)
 
 	responsePayloadJSON, err := json.Marshal(responsePayload)
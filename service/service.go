@@ -19,10 +19,16 @@ package service
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"context"
+	"encoding/json"
+	"github.com/SurgicalSteel/elasthink/logger"
 	"github.com/SurgicalSteel/elasthink/module"
 	"net/http"
+	"strconv"
 )
 
+var serviceLogger = logger.New("service")
+
 //HandlePing is the handler for a ping endpoint
 func HandlePing(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("PONG!"))
@@ -40,3 +46,36 @@ func constructResponsePayload(rawModuleResponse module.Response) ResponsePayload
 		Data:         rawModuleResponse.Data,
 	}
 }
+
+//logFailedResponse logs a module response that failed on the server side (5xx), with the request ID of ctx
+func logFailedResponse(ctx context.Context, operation, documentType string, response module.Response) {
+	if response.StatusCode < http.StatusInternalServerError {
+		return
+	}
+	serviceLogger.Error(ctx, "Request failed", logger.Fields{
+		"operation":     operation,
+		"document_type": documentType,
+		"status":        response.StatusCode,
+		"error":         response.ErrorMessage,
+	})
+}
+
+//writeErrorResponse writes an error response payload with the given status code
+func writeErrorResponse(w http.ResponseWriter, statusCode int, errorMessage string) {
+	responsePayloadJSON, err := json.Marshal(ResponsePayload{ErrorMessage: errorMessage})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(statusCode)
+	w.Write(responsePayloadJSON)
+}
+
+//readPreferenceContext forces the reads of the request to the primary storage when the request has primary=true query param
+func readPreferenceContext(ctx context.Context, r *http.Request) context.Context {
+	isForcingPrimary, err := strconv.ParseBool(r.URL.Query().Get("primary"))
+	if err == nil && isForcingPrimary {
+		return module.WithPrimaryRead(ctx)
+	}
+	return ctx
+}
@@ -0,0 +1,97 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/SurgicalSteel/elasthink/module"
+	"github.com/gorilla/mux"
+)
+
+//writeModuleResponse writes the response payload of a module response
+func writeModuleResponse(w http.ResponseWriter, response module.Response) {
+	responsePayloadJSON, err := json.Marshal(constructResponsePayload(response))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(response.StatusCode)
+	w.Write(responsePayloadJSON)
+}
+
+//readStopwordRequestPayload reads the request payload to add or remove stopwords
+func readStopwordRequestPayload(r *http.Request) (module.StopwordRequestPayload, error) {
+	var requestPayload module.StopwordRequestPayload
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return requestPayload, err
+	}
+
+	err = json.Unmarshal(body, &requestPayload)
+	return requestPayload, err
+}
+
+//HandleGetStopwords handles listing the runtime stopwords of a document type (from internal endpoint)
+func HandleGetStopwords(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	documentType := mux.Vars(r)["document_type"]
+
+	response := module.GetStopwords(ctx, documentType)
+	logFailedResponse(ctx, "get_stopwords", documentType, response)
+	writeModuleResponse(w, response)
+}
+
+//HandleAddStopwords handles adding runtime stopwords of a document type (from internal endpoint).
+//The existing index keys of the stopwords are reported when the request has reportIndexKeys=true query param
+func HandleAddStopwords(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	documentType := mux.Vars(r)["document_type"]
+
+	requestPayload, err := readStopwordRequestPayload(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	isReportingIndexKeys, _ := strconv.ParseBool(r.URL.Query().Get("reportIndexKeys"))
+
+	response := module.AddStopwords(ctx, documentType, requestPayload, isReportingIndexKeys)
+	logFailedResponse(ctx, "add_stopwords", documentType, response)
+	writeModuleResponse(w, response)
+}
+
+//HandleRemoveStopwords handles removing runtime stopwords of a document type (from internal endpoint)
+func HandleRemoveStopwords(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	documentType := mux.Vars(r)["document_type"]
+
+	requestPayload, err := readStopwordRequestPayload(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := module.RemoveStopwords(ctx, documentType, requestPayload)
+	logFailedResponse(ctx, "remove_stopwords", documentType, response)
+	writeModuleResponse(w, response)
+}
@@ -19,7 +19,6 @@ package service
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 
@@ -28,12 +27,13 @@ import (
 )
 
 func HandleKeywordSuggestion(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := readPreferenceContext(r.Context(), r)
 	vars := mux.Vars(r)
 	documentType := vars["document_type"]
 	prefix := vars["prefix"]
 
 	response := module.SuggestKeywords(ctx, documentType, prefix)
+	logFailedResponse(ctx, "suggest", documentType, response)
 	responsePayload := constructResponsePayload(response)
 
 	responsePayloadJSON, err := json.Marshal(responsePayload)
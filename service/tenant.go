@@ -0,0 +1,48 @@
+package service
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"net/http"
+
+	"github.com/SurgicalSteel/elasthink/module"
+)
+
+//APIKeyHeader is the request header that contains the API key of a tenant
+const APIKeyHeader string = "X-Api-Key"
+
+//TenantMiddleware resolves the tenant of a request from its API key and scopes the request to the tenant.
+//The request keeps the module settings in use, so a reload does not change them until it finishes.
+//Requests without a valid API key are rejected when tenants are configured, otherwise every request passes through.
+func TenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := module.WithSettings(r.Context())
+		if !module.IsUsingTenant(ctx) {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		tenant, ok := module.ResolveTenant(ctx, r.Header.Get(APIKeyHeader))
+		if !ok {
+			writeErrorResponse(w, http.StatusUnauthorized, "Invalid API Key")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(module.WithTenant(ctx, tenant)))
+	})
+}
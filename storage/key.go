@@ -0,0 +1,173 @@
+package storage
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/redis"
+)
+
+//DefaultNamespace is the default namespace (prefix) of every key elasthink reads or writes
+const DefaultNamespace string = "elasthink"
+
+//invertedIndexKeyPart is the key part for each word set in the inverted index
+const invertedIndexKeyPart string = "inverted"
+
+//fieldIndexKeyPart is the key part for each word set of a document field (followed by document type, field and word)
+const fieldIndexKeyPart string = "field"
+
+//attributeKeyPart is the key part for each document id set of an attribute value (followed by document type, attribute and value)
+const attributeKeyPart string = "attribute"
+
+//attributeValuesKeyPart is the key part for each value set of an attribute (followed by document type and attribute)
+const attributeValuesKeyPart string = "attributevalues"
+
+//rangeKeyPart is the key part for each sorted set of a numeric attribute, scored by the attribute value (followed by document type and attribute)
+const rangeKeyPart string = "range"
+
+//normalIndexKeyPart is the key part for each normal index (followed by document type and document id). The value with this key contains the original document name
+const normalIndexKeyPart string = "normal"
+
+//stopwordKeyPart is the key part for the runtime stopword set of a document type
+const stopwordKeyPart string = "stopword"
+
+//expiryKeyPart is the key part for the sorted set of the documents of a document type scored by their expiry time
+const expiryKeyPart string = "expiry"
+
+//rateLimitKeyPart is the key part for each rate limit bucket (followed by route, limit kind and client identity)
+const rateLimitKeyPart string = "ratelimit"
+
+//KeyBuilder builds every key elasthink reads or writes under a namespace, so several services can share the same redis without colliding keys
+type KeyBuilder struct {
+	namespace      string
+	isUsingHashTag bool
+}
+
+//NewKeyBuilder creates a key builder with the given namespace (default namespace if it is empty).
+//isUsingHashTag wraps the document type part of every key into a hash tag, which is required when using a redis cluster
+func NewKeyBuilder(namespace string, isUsingHashTag bool) KeyBuilder {
+	namespace = strings.Trim(namespace, " ")
+	if len(namespace) == 0 {
+		namespace = DefaultNamespace
+	}
+	return KeyBuilder{
+		namespace:      namespace,
+		isUsingHashTag: isUsingHashTag,
+	}
+}
+
+//ValidateNamespace validates a key namespace. A namespace must not contain whitespaces nor key pattern characters (*, ?, [, ])
+func ValidateNamespace(namespace string) error {
+	if strings.ContainsAny(namespace, " \t\r\n*?[]") {
+		return errors.New("Invalid Namespace")
+	}
+	return nil
+}
+
+//Namespace gets the namespace of the key builder
+func (kb KeyBuilder) Namespace() string {
+	return kb.namespace
+}
+
+//WithNamespace returns a copy of the key builder under another namespace
+func (kb KeyBuilder) WithNamespace(namespace string) KeyBuilder {
+	return NewKeyBuilder(namespace, kb.isUsingHashTag)
+}
+
+//NamespacePrefix is the prefix of every key in the namespace. Key format --> namespace:
+func (kb KeyBuilder) NamespacePrefix() string {
+	return fmt.Sprintf("%s:", kb.namespace)
+}
+
+func (kb KeyBuilder) documentTypeKeyPart(documentType string) string {
+	if kb.isUsingHashTag {
+		return redis.HashTag(documentType)
+	}
+	return documentType
+}
+
+//InvertedIndexKeyPrefix is the prefix of every word set key of a document type. Key format --> namespace:inverted:documentType: (or namespace:inverted:{documentType}: with hash tag)
+func (kb KeyBuilder) InvertedIndexKeyPrefix(documentType string) string {
+	return fmt.Sprintf("%s:%s:%s:", kb.namespace, invertedIndexKeyPart, kb.documentTypeKeyPart(documentType))
+}
+
+//InvertedIndexKey is the word set key of a word in a document type. Key format --> namespace:inverted:documentType:word
+func (kb KeyBuilder) InvertedIndexKey(documentType, word string) string {
+	return kb.InvertedIndexKeyPrefix(documentType) + word
+}
+
+//FieldIndexKey is the word set key of a word in a field of a document type. Key format --> namespace:field:documentType:field:word
+func (kb KeyBuilder) FieldIndexKey(documentType, field, word string) string {
+	return fmt.Sprintf("%s:%s:%s:%s:%s", kb.namespace, fieldIndexKeyPart, kb.documentTypeKeyPart(documentType), field, word)
+}
+
+//AttributeKeyPrefix is the prefix of every value set key of an attribute of a document type. Key format --> namespace:attribute:documentType:attribute:
+func (kb KeyBuilder) AttributeKeyPrefix(documentType, attribute string) string {
+	return fmt.Sprintf("%s:%s:%s:%s:", kb.namespace, attributeKeyPart, kb.documentTypeKeyPart(documentType), attribute)
+}
+
+//AttributeKey is the document id set key of an attribute value of a document type. Key format --> namespace:attribute:documentType:attribute:value
+func (kb KeyBuilder) AttributeKey(documentType, attribute, value string) string {
+	return kb.AttributeKeyPrefix(documentType, attribute) + value
+}
+
+//ParseAttributeKey gets the attribute and the value of a document id set key of an attribute value of a document type, ok is false when the key is not one
+func (kb KeyBuilder) ParseAttributeKey(documentType, key string) (attribute string, value string, ok bool) {
+	prefix := fmt.Sprintf("%s:%s:%s:", kb.namespace, attributeKeyPart, kb.documentTypeKeyPart(documentType))
+	if !strings.HasPrefix(key, prefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, prefix), ":", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+//AttributeValuesKey is the value set key of an attribute of a document type, it has every value that is set on a document. Key format --> namespace:attributevalues:documentType:attribute
+func (kb KeyBuilder) AttributeValuesKey(documentType, attribute string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", kb.namespace, attributeValuesKeyPart, kb.documentTypeKeyPart(documentType), attribute)
+}
+
+//RangeKey is the sorted set key of a numeric attribute of a document type, each document id is scored by its attribute value. Key format --> namespace:range:documentType:attribute
+func (kb KeyBuilder) RangeKey(documentType, attribute string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", kb.namespace, rangeKeyPart, kb.documentTypeKeyPart(documentType), attribute)
+}
+
+//NormalIndexKey is the key of the original document of a document id. Key format --> namespace:normal:documentType:documentID
+func (kb KeyBuilder) NormalIndexKey(documentType, documentID string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", kb.namespace, normalIndexKeyPart, kb.documentTypeKeyPart(documentType), documentID)
+}
+
+//StopwordKey is the key of the runtime stopword set of a document type. Key format --> namespace:stopword:documentType
+func (kb KeyBuilder) StopwordKey(documentType string) string {
+	return fmt.Sprintf("%s:%s:%s", kb.namespace, stopwordKeyPart, kb.documentTypeKeyPart(documentType))
+}
+
+//ExpiryKey is the sorted set key of the documents of a document type that expire, each document id is scored by its expiry time (in unix seconds). Key format --> namespace:expiry:documentType
+func (kb KeyBuilder) ExpiryKey(documentType string) string {
+	return fmt.Sprintf("%s:%s:%s", kb.namespace, expiryKeyPart, kb.documentTypeKeyPart(documentType))
+}
+
+//RateLimitKey is the key of the rate limit bucket of a client on a route. Key format --> namespace:ratelimit:route:kind:identity
+func (kb KeyBuilder) RateLimitKey(route, kind, identity string) string {
+	return fmt.Sprintf("%s:%s:%s:%s:%s", kb.namespace, rateLimitKeyPart, route, kind, identity)
+}
@@ -0,0 +1,89 @@
+package storage
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyBuilder(t *testing.T) {
+	defaultKeys := NewKeyBuilder("", false)
+	assert.Equal(t, DefaultNamespace, defaultKeys.Namespace())
+	assert.Equal(t, "elasthink:", defaultKeys.NamespacePrefix())
+	assert.Equal(t, "elasthink:inverted:campaign:", defaultKeys.InvertedIndexKeyPrefix("campaign"))
+	assert.Equal(t, "elasthink:inverted:campaign:promo", defaultKeys.InvertedIndexKey("campaign", "promo"))
+	assert.Equal(t, "elasthink:normal:campaign:42", defaultKeys.NormalIndexKey("campaign", "42"))
+	assert.Equal(t, "elasthink:field:campaign:title:promo", defaultKeys.FieldIndexKey("campaign", "title", "promo"))
+	assert.Equal(t, "elasthink:attribute:campaign:city:", defaultKeys.AttributeKeyPrefix("campaign", "city"))
+	assert.Equal(t, "elasthink:attribute:campaign:city:jakarta", defaultKeys.AttributeKey("campaign", "city", "jakarta"))
+	assert.Equal(t, "elasthink:attributevalues:campaign:city", defaultKeys.AttributeValuesKey("campaign", "city"))
+	assert.Equal(t, "elasthink:range:campaign:price", defaultKeys.RangeKey("campaign", "price"))
+	assert.Equal(t, "elasthink:stopword:campaign", defaultKeys.StopwordKey("campaign"))
+	assert.Equal(t, "elasthink:expiry:campaign", defaultKeys.ExpiryKey("campaign"))
+	assert.Equal(t, "elasthink:ratelimit:search:ip:10.0.0.1", defaultKeys.RateLimitKey("search", "ip", "10.0.0.1"))
+
+	namespacedKeys := NewKeyBuilder("payment", true)
+	assert.Equal(t, "payment:inverted:{campaign}:promo", namespacedKeys.InvertedIndexKey("campaign", "promo"))
+	assert.Equal(t, "payment:normal:{campaign}:42", namespacedKeys.NormalIndexKey("campaign", "42"))
+	assert.Equal(t, "payment:field:{campaign}:title:promo", namespacedKeys.FieldIndexKey("campaign", "title", "promo"))
+	assert.Equal(t, "payment:attribute:{campaign}:city:", namespacedKeys.AttributeKeyPrefix("campaign", "city"))
+	assert.Equal(t, "payment:attributevalues:{campaign}:city", namespacedKeys.AttributeValuesKey("campaign", "city"))
+	assert.Equal(t, "payment:attribute:{campaign}:city:jakarta", namespacedKeys.AttributeKey("campaign", "city", "jakarta"))
+	assert.Equal(t, "payment:range:{campaign}:price", namespacedKeys.RangeKey("campaign", "price"))
+	assert.Equal(t, "payment:stopword:{campaign}", namespacedKeys.StopwordKey("campaign"))
+	assert.Equal(t, "payment:expiry:{campaign}", namespacedKeys.ExpiryKey("campaign"))
+
+	tenantKeys := namespacedKeys.WithNamespace("payment:food")
+	assert.Equal(t, "payment:food:inverted:{campaign}:promo", tenantKeys.InvertedIndexKey("campaign", "promo"))
+}
+
+func TestValidateNamespace(t *testing.T) {
+	assert.Nil(t, ValidateNamespace("elasthink"))
+	assert.Nil(t, ValidateNamespace("team:payment"))
+	assert.NotNil(t, ValidateNamespace("team payment"))
+	assert.NotNil(t, ValidateNamespace("team*"))
+}
+
+func TestParseAttributeKey(t *testing.T) {
+	type tcase struct {
+		keys              KeyBuilder
+		key               string
+		expectedAttribute string
+		expectedValue     string
+		expectedOk        bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["attribute key"] = tcase{keys: NewKeyBuilder("", false), key: "elasthink:attribute:campaign:city:jakarta", expectedAttribute: "city", expectedValue: "jakarta", expectedOk: true}
+	testCases["hash tagged attribute key"] = tcase{keys: NewKeyBuilder("payment", true), key: "payment:attribute:{campaign}:city:jakarta", expectedAttribute: "city", expectedValue: "jakarta", expectedOk: true}
+	testCases["value with a colon"] = tcase{keys: NewKeyBuilder("", false), key: "elasthink:attribute:campaign:time:08:00", expectedAttribute: "time", expectedValue: "08:00", expectedOk: true}
+	testCases["inverted index key"] = tcase{keys: NewKeyBuilder("", false), key: "elasthink:inverted:campaign:promo"}
+	testCases["attribute key of another document type"] = tcase{keys: NewKeyBuilder("", false), key: "elasthink:attribute:banner:city:jakarta"}
+	testCases["attribute key without value"] = tcase{keys: NewKeyBuilder("", false), key: "elasthink:attribute:campaign:city:"}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on ParseAttributeKey with test case:", ktc)
+		attribute, value, ok := vtc.keys.ParseAttributeKey("campaign", vtc.key)
+		assert.Equal(t, vtc.expectedOk, ok)
+		assert.Equal(t, vtc.expectedAttribute, attribute)
+		assert.Equal(t, vtc.expectedValue, value)
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/logger"
+)
+
+var storageLogger = logger.New("storage")
+
+//Migrate copies every key with the given prefix from the source storage into the destination storage. Returns the number of migrated keys.
+//The rate limit buckets are skipped, they are short lived and the destination rebuilds them.
+func Migrate(ctx context.Context, source, destination Storage, prefix string) (int, error) {
+	keys, err := source.KeysPrefix(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, key := range keys {
+		keyType, err := source.Type(ctx, key)
+		if err != nil {
+			return migrated, err
+		}
+
+		switch keyType {
+		case KeyTypeSet:
+			err = migrateSet(ctx, source, destination, key)
+		case KeyTypeZSet:
+			err = migrateZSet(ctx, source, destination, key)
+		case KeyTypeString:
+			err = migrateString(ctx, source, destination, key)
+		case KeyTypeNone:
+			// key has been removed after we listed it
+			continue
+		case KeyTypeHash:
+			if isRateLimitKey(key) {
+				continue
+			}
+			err = fmt.Errorf("Unsupported type %s of key %s", keyType, key)
+		default:
+			err = fmt.Errorf("Unsupported type %s of key %s", keyType, key)
+		}
+		if err != nil {
+			storageLogger.Error(ctx, "Failed to migrate key", logger.Fields{"key": key, "error": err})
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
+
+//isRateLimitKey tells whether a key is a rate limit bucket (see KeyBuilder.RateLimitKey)
+func isRateLimitKey(key string) bool {
+	return strings.Contains(key, fmt.Sprintf(":%s:", rateLimitKeyPart))
+}
+
+func migrateSet(ctx context.Context, source, destination Storage, key string) error {
+	members, err := source.SMembers(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(members))
+	for i := 0; i < len(members); i++ {
+		args[i] = members[i]
+	}
+	_, err = destination.SAdd(ctx, key, args)
+	return err
+}
+
+func migrateZSet(ctx context.Context, source, destination Storage, key string) error {
+	members, err := source.ZMembersWithScores(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	for member, score := range members {
+		if _, err := destination.ZAdd(ctx, key, score, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateString(ctx context.Context, source, destination Storage, key string) error {
+	value, err := source.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return destination.Set(ctx, key, value)
+}
@@ -0,0 +1,125 @@
+package storage
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/SurgicalSteel/elasthink/bolt"
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func initTestBolt(t *testing.T, name string) *bolt.Bolt {
+	storageConfig := config.StorageConfigWrap{
+		BoltElasthink: config.BoltConfig{
+			Path:    filepath.Join(t.TempDir(), name),
+			Timeout: 1,
+		},
+	}
+	boltObject, err := bolt.InitBolt(storageConfig)
+	if err != nil {
+		t.Fatal("Expected : ok, but found error! err:", err.Error())
+	}
+	t.Cleanup(func() {
+		boltObject.Close()
+	})
+	return boltObject
+}
+
+func TestMigrate(t *testing.T) {
+	source := initTestBolt(t, "source.db")
+	destination := initTestBolt(t, "destination.db")
+
+	source.SAdd(context.Background(), "elasthink:inverted:campaign:promo", []interface{}{1, 2, 3})
+	source.SAdd(context.Background(), "elasthink:inverted:campaign:murah", []interface{}{2})
+	source.ZAdd(context.Background(), "elasthink:range:campaign:price", 15000, "1")
+	source.ZAdd(context.Background(), "elasthink:range:campaign:price", -2.5, "2")
+	source.Set(context.Background(), "elasthink:normal:campaign:1", `{"fields":{"name":"Promo Murah"}}`)
+	source.SAdd(context.Background(), "other:key", []interface{}{4})
+
+	migrated, err := Migrate(context.Background(), source, destination, "elasthink:")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, migrated)
+
+	members, _ := destination.SMembers(context.Background(), "elasthink:inverted:campaign:promo")
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, members)
+
+	scores, _ := destination.ZMembersWithScores(context.Background(), "elasthink:range:campaign:price")
+	assert.Equal(t, map[string]float64{"1": 15000, "2": -2.5}, scores)
+
+	value, _ := destination.Get(context.Background(), "elasthink:normal:campaign:1")
+	assert.Equal(t, `{"fields":{"name":"Promo Murah"}}`, value)
+
+	keys, _ := destination.KeysPrefix(context.Background(), "other:")
+	assert.Equal(t, 0, len(keys))
+}
+
+//hashSource is a source storage that also has hash keys, which bolt cannot keep
+type hashSource struct {
+	Storage
+	hashKeys []string
+}
+
+func (hs hashSource) KeysPrefix(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := hs.Storage.KeysPrefix(ctx, prefix)
+	return append(keys, hs.hashKeys...), err
+}
+
+func (hs hashSource) Type(ctx context.Context, key string) (string, error) {
+	for _, hashKey := range hs.hashKeys {
+		if key == hashKey {
+			return KeyTypeHash, nil
+		}
+	}
+	return hs.Storage.Type(ctx, key)
+}
+
+func TestMigrateHashKeys(t *testing.T) {
+	sourceBolt := initTestBolt(t, "source.db")
+	destination := initTestBolt(t, "destination.db")
+	sourceBolt.SAdd(context.Background(), "elasthink:inverted:campaign:promo", []interface{}{1})
+
+	//rate limit buckets are skipped
+	source := hashSource{Storage: sourceBolt, hashKeys: []string{NewKeyBuilder("elasthink", false).RateLimitKey("search", "ip", "10.0.0.1")}}
+	migrated, err := Migrate(context.Background(), source, destination, "elasthink:")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, migrated)
+	members, _ := destination.SMembers(context.Background(), "elasthink:inverted:campaign:promo")
+	assert.Equal(t, []string{"1"}, members)
+
+	//any other hash key is not supported
+	source = hashSource{Storage: sourceBolt, hashKeys: []string{"elasthink:custom:hash"}}
+	_, err = Migrate(context.Background(), source, destination, "elasthink:")
+	assert.NotNil(t, err)
+}
+
+func TestGetBackend(t *testing.T) {
+	backend, err := GetBackend("")
+	assert.Nil(t, err)
+	assert.Equal(t, BackendRedis, backend)
+
+	backend, err = GetBackend(" BOLT ")
+	assert.Nil(t, err)
+	assert.Equal(t, BackendBolt, backend)
+
+	_, err = GetBackend("pebble")
+	assert.NotNil(t, err)
+}
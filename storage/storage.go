@@ -0,0 +1,114 @@
+//Package storage defines the contract of an index storage backend and how to initialize the configured one
+package storage
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/SurgicalSteel/elasthink/bolt"
+	"github.com/SurgicalSteel/elasthink/config"
+	"github.com/SurgicalSteel/elasthink/redis"
+)
+
+//Storage is the contract of a storage backend that keeps elasthink indexes. Every backend follows redis semantics for each operation.
+//Every operation stops when its context is done, and returns the error of the context.
+type Storage interface {
+	SAdd(ctx context.Context, key string, args []interface{}) (int64, error)
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SRem(ctx context.Context, key string, members []interface{}) (int64, error)
+	ZAdd(ctx context.Context, key string, score float64, member string) (int64, error)
+	ZRem(ctx context.Context, key string, members []interface{}) (int64, error)
+	ZRangeByScore(ctx context.Context, key, min, max string) ([]string, error)
+	ZMembersWithScores(ctx context.Context, key string) (map[string]float64, error)
+	ZScores(ctx context.Context, key string, members []string) (map[string]float64, error)
+	Set(ctx context.Context, key, value string) error
+	Get(ctx context.Context, key string) (string, error)
+	MGet(ctx context.Context, keys []string) ([]string, error)
+	Del(ctx context.Context, key string) (int64, error)
+	KeysPrefix(ctx context.Context, prefix string) ([]string, error)
+	Type(ctx context.Context, key string) (string, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+const (
+	//BackendRedis is the storage backend that keeps indexes in redis (default)
+	BackendRedis string = "redis"
+	//BackendBolt is the storage backend that keeps indexes in an embedded bolt database file (for single node deployments)
+	BackendBolt string = "bolt"
+)
+
+//The key types are declared once in the bolt package (which cannot import storage), so the types returned by every backend cannot drift apart
+const (
+	//KeyTypeSet is the type of a set key
+	KeyTypeSet = bolt.KeyTypeSet
+	//KeyTypeZSet is the type of a sorted set key
+	KeyTypeZSet = bolt.KeyTypeZSet
+	//KeyTypeString is the type of a string key
+	KeyTypeString = bolt.KeyTypeString
+	//KeyTypeNone is the type of a non existing key
+	KeyTypeNone = bolt.KeyTypeNone
+)
+
+//KeyTypeHash is the type of a hash key. Storage has no hash operation, only the redis rate limiter keeps its buckets in hashes
+const KeyTypeHash string = "hash"
+
+var (
+	_ Storage = (*redis.Redis)(nil)
+	_ Storage = (*bolt.Bolt)(nil)
+)
+
+//Primary returns the storage that reads from the primary. Only the redis backend can read from replicas, other backends are returned as is.
+func Primary(storageObject Storage) Storage {
+	if redisObject, ok := storageObject.(*redis.Redis); ok {
+		return redisObject.Primary()
+	}
+	return storageObject
+}
+
+//GetBackend normalizes the given backend name, an empty backend name means the default (redis) backend
+func GetBackend(backend string) (string, error) {
+	backend = strings.ToLower(strings.Trim(backend, " "))
+	switch backend {
+	case "", BackendRedis:
+		return BackendRedis, nil
+	case BackendBolt:
+		return BackendBolt, nil
+	}
+	return "", errors.New("Invalid Storage Backend")
+}
+
+//InitStorage initializes the storage backend selected in the storage config
+func InitStorage(storageConfig config.StorageConfigWrap, redisConfig config.RedisConfigWrap) (Storage, error) {
+	backend, err := GetBackend(storageConfig.Storage.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	if backend == BackendBolt {
+		boltObject, err := bolt.InitBolt(storageConfig)
+		if err != nil {
+			return nil, err
+		}
+		return boltObject, nil
+	}
+	return redis.InitRedis(redisConfig), nil
+}
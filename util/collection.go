@@ -17,13 +17,36 @@ package util
 //
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
-import ()
+import (
+	"fmt"
+	"strings"
+)
 
-//SliceStringToInt64 converts a slice of string into a slice of int64
-func SliceStringToInt64(ss []string) []int64 {
-	result := make([]int64, len(ss))
+//SliceStringToInt64 converts a slice of string into a slice of int64.
+//A string that is not a number is left out of the result, and reported in the error
+func SliceStringToInt64(ss []string) ([]int64, error) {
+	result := make([]int64, 0, len(ss))
+	invalidValues := make([]string, 0)
 	for i := 0; i < len(ss); i++ {
-		result[i] = StringToInt64(ss[i])
+		value, err := StringToInt64(ss[i])
+		if err != nil {
+			invalidValues = append(invalidValues, ss[i])
+			continue
+		}
+		result = append(result, value)
+	}
+
+	if len(invalidValues) > 0 {
+		return result, fmt.Errorf("Invalid int64 values %s", strings.Join(invalidValues, ", "))
+	}
+	return result, nil
+}
+
+//SliceStringToInterface converts a slice of string into a slice of interface (e.g. members of a set command)
+func SliceStringToInterface(ss []string) []interface{} {
+	result := make([]interface{}, len(ss))
+	for i := 0; i < len(ss); i++ {
+		result[i] = ss[i]
 	}
 	return result
 }
@@ -18,6 +18,7 @@ package util
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -25,8 +26,9 @@ import (
 
 func TestSliceStringToInt64(t *testing.T) {
 	type tcase struct {
-		sourceSlice []string
-		expected    []int64
+		sourceSlice   []string
+		expected      []int64
+		expectedError error
 	}
 	testCases := make(map[string]tcase)
 
@@ -41,14 +43,21 @@ func TestSliceStringToInt64(t *testing.T) {
 	}
 
 	testCases["slice of numbers in string and some random characters"] = tcase{
-		sourceSlice: []string{"111", "222", "333", "???", "555", "???"},
-		expected:    []int64{111, 222, 333, 0, 555, 0},
+		sourceSlice:   []string{"111", "222", "333", "???", "555", "abc"},
+		expected:      []int64{111, 222, 333, 555},
+		expectedError: errors.New("Invalid int64 values ???, abc"),
 	}
 
 	for ktc, vtc := range testCases {
 		fmt.Println("doing test on SliceStringToInt64 with test case:", ktc)
-		actual := SliceStringToInt64(vtc.sourceSlice)
+		actual, actualError := SliceStringToInt64(vtc.sourceSlice)
 		assert.ElementsMatch(t, actual, vtc.expected)
+		assert.Equal(t, vtc.expectedError, actualError)
 	}
 
 }
+
+func TestSliceStringToInterface(t *testing.T) {
+	assert.Equal(t, []interface{}{}, SliceStringToInterface([]string{}))
+	assert.Equal(t, []interface{}{"promo", "diskon"}, SliceStringToInterface([]string{"promo", "diskon"}))
+}
@@ -18,14 +18,15 @@ package util
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"fmt"
 	"strconv"
 )
 
-//StringToInt64 convert a string into int64
-func StringToInt64(s string) int64 {
+//StringToInt64 convert a string into int64, it fails when the string is not a number
+func StringToInt64(s string) (int64, error) {
 	result, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
-		return int64(0)
+		return int64(0), fmt.Errorf("Invalid int64 value %s", s)
 	}
-	return result
+	return result, nil
 }
@@ -18,6 +18,7 @@ package util
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -25,14 +26,22 @@ import (
 
 func TestStringToInt64(t *testing.T) {
 	type tcase struct {
-		sourceString string
-		expected     int64
+		sourceString  string
+		expected      int64
+		expectedError error
 	}
 	testCases := make(map[string]tcase)
 
 	testCases["empty string"] = tcase{
-		sourceString: "",
-		expected:     int64(0),
+		sourceString:  "",
+		expected:      int64(0),
+		expectedError: errors.New("Invalid int64 value "),
+	}
+
+	testCases["non numeric string"] = tcase{
+		sourceString:  "promo-42",
+		expected:      int64(0),
+		expectedError: errors.New("Invalid int64 value promo-42"),
 	}
 
 	testCases["negative number in string"] = tcase{
@@ -47,7 +56,8 @@ func TestStringToInt64(t *testing.T) {
 
 	for ktc, vtc := range testCases {
 		fmt.Println("doing test on StringToInt64 with test case:", ktc)
-		actual := StringToInt64(vtc.sourceString)
+		actual, actualError := StringToInt64(vtc.sourceString)
 		assert.Equal(t, vtc.expected, actual)
+		assert.Equal(t, vtc.expectedError, actualError)
 	}
 }
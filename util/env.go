@@ -18,19 +18,30 @@ package util
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 import (
+	"fmt"
+	"regexp"
 	"strings"
 )
 
-//GetEnv gets environment from given string
-func GetEnv(env string) string {
+//envNameRegex is the allowed format of an environment name, it names the config files of the environment
+var envNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+//GetEnv gets environment from given string. Aliases of the common environments are resolved and an empty string means development.
+//Any other environment name is allowed as long as it only has letters, digits, hyphens and underscores
+func GetEnv(env string) (string, error) {
 	env = strings.Trim(env, " ")
 	env = strings.ToLower(env)
 	switch env {
+	case "", "dev", "development":
+		return "development", nil
 	case "stg", "staging":
-		return "staging"
+		return "staging", nil
 	case "prod", "production":
-		return "production"
-	default:
-		return "development"
+		return "production", nil
+	}
+
+	if !envNameRegex.MatchString(env) {
+		return "", fmt.Errorf("Invalid environment name %s", env)
 	}
+	return env, nil
 }
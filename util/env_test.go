@@ -27,6 +27,7 @@ func TestGetEnv(t *testing.T) {
 	type tcase struct {
 		sourceEnvString string
 		expected        string
+		isError         bool
 	}
 	testCases := make(map[string]tcase)
 
@@ -45,14 +46,34 @@ func TestGetEnv(t *testing.T) {
 		expected:        "development",
 	}
 
+	testCases["env prod alias"] = tcase{
+		sourceEnvString: "Prod",
+		expected:        "production",
+	}
+
+	testCases["arbitrary env"] = tcase{
+		sourceEnvString: "QA-1 ",
+		expected:        "qa-1",
+	}
+
 	testCases["invalid env"] = tcase{
 		sourceEnvString: "?B$A!J&I*N{G}A%N+",
-		expected:        "development",
+		isError:         true,
+	}
+
+	testCases["path traversal env"] = tcase{
+		sourceEnvString: "../production",
+		isError:         true,
 	}
 
 	for ktc, vtc := range testCases {
 		fmt.Println("doing test on GetEnv with test case:", ktc)
-		actual := GetEnv(vtc.sourceEnvString)
+		actual, err := GetEnv(vtc.sourceEnvString)
+		if vtc.isError {
+			assert.NotNil(t, err)
+			continue
+		}
+		assert.Nil(t, err)
 		assert.Equal(t, vtc.expected, actual)
 	}
 
@@ -0,0 +1,56 @@
+package util
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+const (
+	//DefaultHighlightPreTag is the tag put before a highlighted word when no tag is given
+	DefaultHighlightPreTag = "<em>"
+	//DefaultHighlightPostTag is the tag put after a highlighted word when no tag is given
+	DefaultHighlightPostTag = "</em>"
+)
+
+//highlightWordRegex matches a word the way Tokenize splits it (every character other than a letter or a digit is a separator)
+var highlightWordRegex = regexp.MustCompile(`[a-zA-Z0-9]+`)
+
+//Highlight wraps every word of s that is in the word set (case insensitive, like Tokenize) with the pre and post tags.
+//The text of s is HTML escaped (the tags are not), so the highlighted text is safe to render as HTML with trusted tags.
+//Returns the highlighted text and whether any word is highlighted
+func Highlight(s string, wordSet map[string]int, preTag, postTag string) (string, bool) {
+	isHighlighted := false
+	var highlighted strings.Builder
+	last := 0
+	for _, loc := range highlightWordRegex.FindAllStringIndex(s, -1) {
+		highlighted.WriteString(html.EscapeString(s[last:loc[0]]))
+		word := s[loc[0]:loc[1]]
+		if _, ok := wordSet[strings.ToLower(word)]; ok {
+			isHighlighted = true
+			highlighted.WriteString(preTag + html.EscapeString(word) + postTag)
+		} else {
+			highlighted.WriteString(html.EscapeString(word))
+		}
+		last = loc[1]
+	}
+	highlighted.WriteString(html.EscapeString(s[last:]))
+	return highlighted.String(), isHighlighted
+}
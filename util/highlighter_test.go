@@ -0,0 +1,73 @@
+package util
+
+// Elasthink, An alternative to elasticsearch engine written in Go for small set of documents that uses inverted index to build the index and utilizes redis to store the indexes.
+// Copyright (C) 2020 Yuwono Bangun Nagoro (a.k.a SurgicalSteel)
+//
+// This file is part of Elasthink
+//
+// Elasthink is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Elasthink is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	type tcase struct {
+		sourceString          string
+		wordSet               map[string]int
+		expected              string
+		expectedIsHighlighted bool
+	}
+	testCases := make(map[string]tcase)
+
+	testCases["matched words"] = tcase{
+		sourceString:          "Diskon Kopi, diskon Susu!",
+		wordSet:               map[string]int{"diskon": 1, "susu": 1},
+		expected:              "<em>Diskon</em> Kopi, <em>diskon</em> <em>Susu</em>!",
+		expectedIsHighlighted: true,
+	}
+	testCases["part of a word is not matched"] = tcase{
+		sourceString: "Kopikopi",
+		wordSet:      map[string]int{"kopi": 1},
+		expected:     "Kopikopi",
+	}
+	testCases["text is escaped"] = tcase{
+		sourceString:          `<script>alert("kopi")</script> & Kopi's`,
+		wordSet:               map[string]int{"kopi": 1, "script": 1},
+		expected:              "&lt;<em>script</em>&gt;alert(&#34;<em>kopi</em>&#34;)&lt;/<em>script</em>&gt; &amp; <em>Kopi</em>&#39;s",
+		expectedIsHighlighted: true,
+	}
+	testCases["text without matched word is escaped"] = tcase{
+		sourceString: "Kopi <b>Susu</b>",
+		wordSet:      map[string]int{"teh": 1},
+		expected:     "Kopi &lt;b&gt;Susu&lt;/b&gt;",
+	}
+	testCases["no matched word"] = tcase{
+		sourceString: "Cashback Pulsa",
+		wordSet:      map[string]int{"kopi": 1},
+		expected:     "Cashback Pulsa",
+	}
+
+	for ktc, vtc := range testCases {
+		fmt.Println("doing test on Highlight with test case:", ktc)
+		actual, isHighlighted := Highlight(vtc.sourceString, vtc.wordSet, DefaultHighlightPreTag, DefaultHighlightPostTag)
+		assert.Equal(t, vtc.expected, actual)
+		assert.Equal(t, vtc.expectedIsHighlighted, isHighlighted)
+	}
+
+	actual, _ := Highlight("Diskon Kopi", map[string]int{"kopi": 1}, "**", "**")
+	assert.Equal(t, "Diskon **Kopi**", actual)
+}